@@ -48,3 +48,124 @@ func TestPravegaOperatorTemplate(t *testing.T) {
 	g.Expect(podContainers[0].Image).To(Equal("tristan1900/pravega-operator:0.3.0"))
 	g.Expect(podContainers[0].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
 }
+
+func TestPravegaOperatorTemplateWatchNamespaces(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Path to the helm chart
+	helmChartPath := "../pravega-operator"
+
+	// Setup the args.
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"watchNamespaces": "{tenant-a,tenant-b}",
+		},
+	}
+
+	// Run "helm template" underlying and return the result as output
+	output := helm.RenderTemplate(t, options, helmChartPath, []string{"templates/operator.yaml"})
+
+	// Parse output
+	var deploy appsv1.Deployment
+	helm.UnmarshalK8SYaml(t, output, &deploy)
+
+	// Verify the output
+	env := deploy.Spec.Template.Spec.Containers[0].Env
+	g.Expect(env[0].Name).To(Equal("WATCH_NAMESPACE"))
+	g.Expect(env[0].Value).To(Equal("tenant-a,tenant-b"))
+	g.Expect(env[0].ValueFrom).To(BeNil())
+}
+
+func TestPravegaOperatorTemplateVersionMatrix(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Path to the helm chart
+	helmChartPath := "../pravega-operator"
+
+	// Setup the args.
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"versionMatrix.0\\.5\\.0": "{0.6.0}",
+		},
+	}
+
+	// Run "helm template" underlying and return the result as output
+	output := helm.RenderTemplate(t, options, helmChartPath, []string{"templates/operator.yaml"})
+
+	// Parse output
+	var deploy appsv1.Deployment
+	helm.UnmarshalK8SYaml(t, output, &deploy)
+
+	// Verify the output
+	podSpec := deploy.Spec.Template.Spec
+	g.Expect(podSpec.Containers[0].Args).To(ContainElement("-version-matrix=/etc/pravega-operator/version-matrix/matrix.yaml"))
+	g.Expect(podSpec.Volumes[0].ConfigMap.Name).To(HaveSuffix("-version-matrix"))
+
+	output = helm.RenderTemplate(t, options, helmChartPath, []string{"templates/version_matrix.yaml"})
+
+	var configMap corev1.ConfigMap
+	helm.UnmarshalK8SYaml(t, output, &configMap)
+
+	g.Expect(configMap.Data["matrix.yaml"]).To(ContainSubstring("0.5.0:"))
+}
+
+func TestPravegaOperatorTemplateWebhookCert(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Path to the helm chart
+	helmChartPath := "../pravega-operator"
+
+	// Setup the args.
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"versionMatrix.0\\.5\\.0": "{0.6.0}",
+			"webhookCert.secret":      "pravega-webhook-cert",
+		},
+	}
+
+	// Run "helm template" underlying and return the result as output
+	output := helm.RenderTemplate(t, options, helmChartPath, []string{"templates/operator.yaml"})
+
+	// Parse output
+	var deploy appsv1.Deployment
+	helm.UnmarshalK8SYaml(t, output, &deploy)
+
+	// Verify the output
+	podSpec := deploy.Spec.Template.Spec
+	g.Expect(podSpec.Containers[0].Args).To(ConsistOf(
+		"-version-matrix=/etc/pravega-operator/version-matrix/matrix.yaml",
+		"-webhook-cert-dir=/etc/pravega-operator/webhook-cert",
+	))
+	g.Expect(podSpec.Containers[0].VolumeMounts).To(HaveLen(2))
+	g.Expect(podSpec.Volumes[1].Secret.SecretName).To(Equal("pravega-webhook-cert"))
+}
+
+func TestPravegaOperatorTemplateWebhookDisabled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Path to the helm chart
+	helmChartPath := "../pravega-operator"
+
+	// Setup the args.
+	options := &helm.Options{
+		SetValues: map[string]string{
+			"webhook.enabled": "false",
+		},
+	}
+
+	// Run "helm template" underlying and return the result as output
+	output := helm.RenderTemplate(t, options, helmChartPath, []string{"templates/operator.yaml"})
+
+	// Parse output
+	var deploy appsv1.Deployment
+	helm.UnmarshalK8SYaml(t, output, &deploy)
+
+	// Verify the output
+	g.Expect(deploy.Spec.Template.Spec.Containers[0].Args).To(ConsistOf("-webhook=false"))
+	g.Expect(deploy.Spec.Template.Spec.Volumes).To(BeEmpty())
+
+	// The definition does not reference the conversion webhook
+	output = helm.RenderTemplate(t, options, helmChartPath, []string{"templates/crd.yaml"})
+	g.Expect(output).To(ContainSubstring("strategy: None"))
+	g.Expect(output).NotTo(ContainSubstring("pravega-webhook-svc"))
+}
@@ -30,13 +30,18 @@ func TestPravegaTemplate(t *testing.T) {
 	options := &helm.Options{
 		SetValues: map[string]string{
 			"version":                      "0.4.0-beta",
+			"upgradePolicy.rollback":       "true",
+			"imagePullSecrets[0].name":     "registry",
 			"zookeeperUri":                 "foo-client:2181",
 			"externalAccess.enabled":       "true",
 			"externalAccess.type":          "NodePort",
+			"bookkeeper.version":           "0.4.0",
 			"bookkeeper.image.repository":  "tristan1900/bookkeeper",
+			"bookkeeper.image.tag":         "0.4.0-cve-fix",
 			"bookkeeper.replicas":          "5",
 			"bookkeeper.autoRecovery":      "false",
 			"pravega.image.repository":     "tristan1900/pravega",
+			"pravega.image.digest":         "sha256:4b1c8bd86e3d3cb8c34c05c8bbd1c9a1e1bbbdc1a3d1ba1a3e6bd8b6f6e2c0d1",
 			"pravega.controllerReplicas":   "2",
 			"pravega.segmentStoreReplicas": "7",
 			"pravega.debugLogging":         "true",
@@ -54,13 +59,18 @@ func TestPravegaTemplate(t *testing.T) {
 	// Verify the output
 	boolFalse := false
 	g.Expect(p.Spec.Version).To(Equal("0.4.0-beta"))
+	g.Expect(p.Spec.UpgradePolicy.Rollback).To(BeTrue())
+	g.Expect(p.Spec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: "registry"}}))
 	g.Expect(p.Spec.ZookeeperUri).To(Equal("foo-client:2181"))
 	g.Expect(p.Spec.ExternalAccess.Enabled).To(BeTrue())
 	g.Expect(p.Spec.ExternalAccess.Type).To(Equal(corev1.ServiceTypeNodePort))
+	g.Expect(p.Spec.Bookkeeper.Version).To(Equal("0.4.0"))
 	g.Expect(p.Spec.Bookkeeper.Image.Repository).To(Equal("tristan1900/bookkeeper"))
+	g.Expect(p.Spec.Bookkeeper.Image.Tag).To(Equal("0.4.0-cve-fix"))
 	g.Expect(p.Spec.Bookkeeper.Replicas).To(BeEquivalentTo(5))
 	g.Expect(p.Spec.Bookkeeper.AutoRecovery).To(Equal(&boolFalse))
 	g.Expect(p.Spec.Pravega.Image.Repository).To(Equal("tristan1900/pravega"))
+	g.Expect(p.Spec.Pravega.Image.Digest).To(HavePrefix("sha256:"))
 	g.Expect(p.Spec.Pravega.ControllerReplicas).To(BeEquivalentTo(2))
 	g.Expect(p.Spec.Pravega.SegmentStoreReplicas).To(BeEquivalentTo(7))
 	g.Expect(p.Spec.Pravega.DebugLogging).To(BeTrue())
@@ -13,9 +13,11 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"github.com/pravega/pravega-operator/pkg/webhook"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
@@ -24,9 +26,11 @@ import (
 	"github.com/pravega/pravega-operator/pkg/apis"
 	"github.com/pravega/pravega-operator/pkg/controller"
 	controllerconfig "github.com/pravega/pravega-operator/pkg/controller/config"
+	"github.com/pravega/pravega-operator/pkg/util"
 	"github.com/pravega/pravega-operator/pkg/version"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
@@ -37,12 +41,42 @@ import (
 var (
 	versionFlag bool
 	webhookFlag bool
+	metricsAddr string
+	logLevel    string
+	logFormat   string
 )
 
 func init() {
 	flag.BoolVar(&versionFlag, "version", false, "Show version and quit")
 	flag.BoolVar(&controllerconfig.TestMode, "test", false, "Enable test mode. Do not use this flag in production")
 	flag.BoolVar(&webhookFlag, "webhook", true, "Enable webhook, the default is enabled.")
+	flag.StringVar(&metricsAddr, "metrics-addr", ":60000", "Address the metrics endpoint binds to. Set to 0 to disable metrics")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level, one of debug, info, warning, error")
+	flag.StringVar(&logFormat, "log-format", "text", "Log format, one of text, json")
+	flag.DurationVar(&controllerconfig.ResyncPeriod, "resync-period", controllerconfig.ResyncPeriod, "Delay between periodic reconciliations of a Pravega cluster")
+	flag.IntVar(&controllerconfig.MaxConcurrentReconciles, "max-concurrent-reconciles", controllerconfig.MaxConcurrentReconciles, "Maximum number of Pravega clusters reconciled in parallel")
+	flag.DurationVar(&controllerconfig.ZookeeperCleanupTimeout, "zookeeper-cleanup-timeout", controllerconfig.ZookeeperCleanupTimeout, "How long to retry deleting the ZooKeeper metadata of a deleted Pravega cluster")
+	flag.StringVar(&controllerconfig.VersionMatrixFile, "version-matrix", "", "File with the supported Pravega versions and upgrade paths, reloaded when it changes")
+	flag.StringVar(&controllerconfig.WebhookCertDir, "webhook-cert-dir", "", "Directory with the tls.crt, tls.key and ca.crt of the webhook, reloaded when they change. The webhook generates its own certificate if empty")
+	flag.DurationVar(&controllerconfig.WebhookCertExpiryWarning, "webhook-cert-expiry-warning", controllerconfig.WebhookCertExpiryWarning, "How long before the expiry of the webhook certificate to report it")
+}
+
+func configureLogging() error {
+	level, err := log.ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(level)
+
+	switch logFormat {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format: %s", logFormat)
+	}
+	return nil
 }
 
 func printVersion() {
@@ -56,6 +90,20 @@ func printVersion() {
 func main() {
 	flag.Parse()
 
+	// The reconciler validates the clusters when the validating webhook does not
+	controllerconfig.ValidateSpec = !webhookFlag
+
+	if err := configureLogging(); err != nil {
+		log.Fatal(err)
+	}
+
+	if flag.Arg(0) == "render" {
+		if err := render(flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	printVersion()
 
 	if versionFlag {
@@ -66,10 +114,22 @@ func main() {
 		log.Warn("----- Running in test mode. Make sure you are NOT in production -----")
 	}
 
-	namespace, err := k8sutil.GetWatchNamespace()
+	namespaces, err := getWatchNamespaces()
 	if err != nil {
 		log.Fatal(err, "failed to get watch namespace")
 	}
+	controllerconfig.WatchNamespaces = namespaces
+	if namespaces[0] == "" {
+		log.Info("watching all namespaces")
+	} else {
+		log.Infof("watching namespaces %s", strings.Join(namespaces, ", "))
+	}
+
+	if controllerconfig.VersionMatrixFile != "" {
+		if err := util.LoadVersionMatrix(controllerconfig.VersionMatrixFile); err != nil {
+			log.Errorf("%v, using the %s", err, util.VersionMatrixCompiledIn)
+		}
+	}
 
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
@@ -87,25 +147,45 @@ func main() {
 	}
 	defer r.Unset()
 
-	// Create a new Cmd to provide shared dependencies and start components
-	mgr, err := manager.New(cfg, manager.Options{Namespace: namespace})
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	log.Print("Registering Components")
 
-	// Setup Scheme for all resources
-	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
-		log.Fatal(err)
-	}
+	// Create a Cmd per watched namespace to provide shared dependencies and
+	// start components, so that the caches only hold the objects of the
+	// watched namespaces. The metrics of all Cmds share the same registry.
+	var managers []manager.Manager
+	for i, namespace := range namespaces {
+		addr := "0"
+		if i == 0 {
+			addr = metricsAddr
+		}
+		mgr, err := newManager(cfg, namespace, addr)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	// Setup all Controllers
-	if err := controller.AddToManager(mgr); err != nil {
-		log.Fatal(err)
+		// Setup all Controllers
+		if err := controller.AddToManager(mgr); err != nil {
+			log.Fatal(err)
+		}
+		managers = append(managers, mgr)
 	}
 
 	if webhookFlag {
+		mgr := managers[0]
+		if len(namespaces) > 1 {
+			// The webhook serves the clusters of all the watched namespaces,
+			// it gets its own Cmd in the namespace of the operator
+			operatorNamespace, err := k8sutil.GetOperatorNamespace()
+			if err != nil {
+				log.Fatal(err)
+			}
+			mgr, err = newManager(cfg, operatorNamespace, "0")
+			if err != nil {
+				log.Fatal(err)
+			}
+			managers = append(managers, mgr)
+		}
+
 		// Setup webhook
 		if err := webhook.AddToManager(mgr); err != nil {
 			log.Fatal(err)
@@ -114,8 +194,59 @@ func main() {
 
 	log.Print("Starting the Cmd")
 
-	// Start the Cmd
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
+	// Start the Cmds
+	stop := signals.SetupSignalHandler()
+	if controllerconfig.VersionMatrixFile != "" {
+		go util.WatchVersionMatrix(controllerconfig.VersionMatrixFile, controllerconfig.VersionMatrixPollInterval, stop)
+	}
+	errs := make(chan error, len(managers))
+	for _, mgr := range managers {
+		go func(mgr manager.Manager) {
+			errs <- mgr.Start(stop)
+		}(mgr)
+	}
+	if err := <-errs; err != nil {
 		log.Fatal(err, "manager exited non-zero")
 	}
 }
+
+// getWatchNamespaces returns the comma separated namespaces of the
+// WATCH_NAMESPACE environment variable. An empty variable stands for all
+// namespaces and is returned as a single empty namespace.
+func getWatchNamespaces() ([]string, error) {
+	value, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, namespace := range strings.Split(value, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	if len(namespaces) == 0 {
+		return []string{""}, nil
+	}
+	return namespaces, nil
+}
+
+func newManager(cfg *rest.Config, namespace, metricsAddr string) (manager.Manager, error) {
+	mgr, err := manager.New(cfg, manager.Options{
+		Namespace:          namespace,
+		MetricsBindAddress: metricsAddr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup Scheme for all resources
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
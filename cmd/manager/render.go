@@ -0,0 +1,77 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/pravega"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// render prints the objects generated for the PravegaCluster read from a
+// YAML file, without contacting an API server
+func render(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	file := flags.String("f", "-", "PravegaCluster YAML file to render, - for the standard input")
+	namespace := flags.String("namespace", "default", "Namespace of the PravegaCluster if the file does not set it")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s render [-f file] [-namespace namespace]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	var (
+		data []byte
+		err  error
+	)
+	if *file == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read PravegaCluster (%s): %v", *file, err)
+	}
+
+	p := &v1alpha1.PravegaCluster{}
+	if err = yaml.Unmarshal(data, p); err != nil {
+		return fmt.Errorf("failed to parse PravegaCluster (%s): %v", *file, err)
+	}
+	if p.Kind != "PravegaCluster" {
+		return fmt.Errorf("expected a PravegaCluster, got kind %q", p.Kind)
+	}
+	if p.Namespace == "" {
+		p.Namespace = *namespace
+	}
+	p.WithDefaults()
+
+	if ref := p.Spec.Zookeeper.ClusterRef; ref != nil {
+		log.Warnf("rendering zookeeper uri %s instead of the address of zookeeper cluster (%s/%s)",
+			p.Spec.ZookeeperUri, ref.Namespace, ref.Name)
+	}
+
+	for _, obj := range pravega.MakeClusterObjects(p) {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %v", obj.GetObjectKind().GroupVersionKind().Kind, err)
+		}
+		fmt.Fprintf(out, "---\n%s", data)
+	}
+	return nil
+}
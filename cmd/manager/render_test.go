@@ -0,0 +1,133 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/pravega"
+	"github.com/pravega/pravega-operator/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestManager(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Manager")
+}
+
+var _ = Describe("Render", func() {
+	const cluster = `apiVersion: pravega.pravega.io/v1alpha1
+kind: PravegaCluster
+metadata:
+  name: example
+spec:
+  version: 0.5.0
+  externalAccess:
+    enabled: true
+`
+
+	var file string
+
+	writeCluster := func(data string) {
+		Ω(ioutil.WriteFile(file, []byte(data), 0644)).Should(Succeed())
+	}
+
+	// renderObjects renders the cluster file and returns the rendered objects
+	renderObjects := func(args ...string) []*unstructured.Unstructured {
+		out := &bytes.Buffer{}
+		Ω(render(append([]string{"-f", file}, args...), out)).Should(Succeed())
+		var objects []*unstructured.Unstructured
+		for _, doc := range strings.Split(out.String(), "---\n") {
+			if doc == "" {
+				continue
+			}
+			obj := &unstructured.Unstructured{}
+			Ω(yaml.Unmarshal([]byte(doc), &obj.Object)).Should(Succeed())
+			objects = append(objects, obj)
+		}
+		return objects
+	}
+
+	BeforeEach(func() {
+		f, err := ioutil.TempFile("", "pravegacluster")
+		Ω(err).Should(BeNil())
+		Ω(f.Close()).Should(Succeed())
+		file = f.Name()
+		writeCluster(cluster)
+	})
+
+	AfterEach(func() {
+		os.Remove(file)
+	})
+
+	It("should print the objects generated for the cluster", func() {
+		p := &v1alpha1.PravegaCluster{}
+		Ω(yaml.Unmarshal([]byte(cluster), p)).Should(Succeed())
+		p.Namespace = "default"
+		p.WithDefaults()
+		expected := pravega.MakeClusterObjects(p)
+
+		objects := renderObjects()
+		Ω(objects).Should(HaveLen(len(expected)))
+		Ω(objects).Should(HaveLen(12 + int(p.Spec.Pravega.SegmentStoreReplicas)))
+		for i, obj := range objects {
+			gvk := expected[i].GetObjectKind().GroupVersionKind()
+			Ω(obj.GetKind()).Should(Equal(gvk.Kind))
+			Ω(obj.GetNamespace()).Should(Equal("default"))
+		}
+	})
+
+	It("should set the defaults of the cluster", func() {
+		for _, obj := range renderObjects() {
+			if obj.GetKind() != "StatefulSet" || obj.GetName() != util.StatefulSetNameForBookie("example") {
+				continue
+			}
+			spec, ok := obj.Object["spec"].(map[string]interface{})
+			Ω(ok).Should(BeTrue())
+			Ω(spec["replicas"]).Should(BeEquivalentTo(v1alpha1.MinimumBookkeeperReplicas))
+			return
+		}
+		Fail("bookie StatefulSet not rendered")
+	})
+
+	It("should render the cluster in the namespace of the flag", func() {
+		for _, obj := range renderObjects("-namespace", "pravega") {
+			Ω(obj.GetNamespace()).Should(Equal("pravega"))
+		}
+	})
+
+	It("should keep the namespace of the file", func() {
+		writeCluster(strings.Replace(cluster, "  name: example\n", "  name: example\n  namespace: pravega\n", 1))
+		for _, obj := range renderObjects("-namespace", "other") {
+			Ω(obj.GetNamespace()).Should(Equal("pravega"))
+		}
+	})
+
+	It("should reject other kinds", func() {
+		writeCluster(strings.Replace(cluster, "kind: PravegaCluster", "kind: ZookeeperCluster", 1))
+		err := render([]string{"-f", file}, &bytes.Buffer{})
+		Ω(err).Should(MatchError(ContainSubstring(`expected a PravegaCluster, got kind "ZookeeperCluster"`)))
+	})
+
+	It("should fail on a missing file", func() {
+		err := render([]string{"-f", file + ".missing"}, &bytes.Buffer{})
+		Ω(err).Should(MatchError(HavePrefix("failed to read PravegaCluster")))
+	})
+})
@@ -60,6 +60,12 @@ type BookkeeperSpec struct {
 	// By default, "pravega/bookkeeper" will be used.
 	Image *BookkeeperImageSpec `json:"image"`
 
+	// Version is the BookKeeper version, i.e. the version of the Pravega
+	// release the BookKeeper image comes from. It lets BookKeeper be upgraded
+	// independently of the Pravega components. If version is not set, the
+	// cluster version is used.
+	Version string `json:"version,omitempty"`
+
 	// Replicas defines the number of BookKeeper replicas.
 	// Minimum is 3. Defaults to 3.
 	Replicas int32 `json:"replicas"`
@@ -74,6 +80,10 @@ type BookkeeperSpec struct {
 	// ServiceAccountName configures the service account used on BookKeeper instances
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 
+	// ImagePullSecrets are the secrets used to pull the BookKeeper image, in
+	// addition to the image pull secrets of the cluster
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
 	// BookieResources specifies the request and limit of resources that bookie can have.
 	// BookieResources includes CPU and memory resources
 	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
@@ -144,8 +154,6 @@ func (s *BookkeeperImageSpec) withDefaults() (changed bool) {
 		s.Repository = DefaultBookkeeperImageRepository
 	}
 
-	s.Tag = ""
-
 	if s.PullPolicy == "" {
 		changed = true
 		s.PullPolicy = DefaultBookkeeperImagePullPolicy
@@ -170,6 +178,11 @@ type BookkeeperStorageSpec struct {
 	// This field is optional. If no PVC spec and there is no default storage class,
 	// stateful containers will use emptyDir as volume
 	IndexVolumeClaimTemplate *v1.PersistentVolumeClaimSpec `json:"indexVolumeClaimTemplate"`
+
+	// ReclaimPolicy defines whether the ledger, journal and index volumes are
+	// deleted when BookKeeper is scaled down or the cluster is deleted.
+	// Options are "Retain" and "Delete". Defaults to "Delete".
+	ReclaimPolicy ReclaimPolicy `json:"reclaimPolicy,omitempty"`
 }
 
 func (s *BookkeeperStorageSpec) withDefaults() (changed bool) {
@@ -209,5 +222,10 @@ func (s *BookkeeperStorageSpec) withDefaults() (changed bool) {
 		}
 	}
 
+	if s.ReclaimPolicy == "" {
+		changed = true
+		s.ReclaimPolicy = DefaultReclaimPolicy
+	}
+
 	return changed
 }
@@ -0,0 +1,413 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+)
+
+// ConversionDataAnnotation holds the settings of a v1beta1 cluster that
+// v1alpha1 cannot represent, i.e. the options and the external access that
+// differ between the controller and the segment store. It is set when a
+// v1beta1 cluster is converted to v1alpha1, and removed when the cluster is
+// converted back, so that the conversion is lossless. The operator reads the
+// settings of each component from it, see ControllerOptions and
+// ControllerExternalAccess.
+const ConversionDataAnnotation = "pravega.pravega.io/conversion-data"
+
+// conversionData is the content of the ConversionDataAnnotation
+type conversionData struct {
+	// ControllerOptions are the options only set on the controller, or set
+	// to another value than on the segment store
+	ControllerOptions map[string]string `json:"controllerOptions,omitempty"`
+
+	// SegmentStoreOptions are the options only set on the segment store, or
+	// set to another value than on the controller
+	SegmentStoreOptions map[string]string `json:"segmentStoreOptions,omitempty"`
+
+	// ControllerExternalAccess is the external access of the controller, if
+	// it differs from the external access of the segment store
+	ControllerExternalAccess *ExternalAccess `json:"controllerExternalAccess,omitempty"`
+}
+
+// conversionData returns the content of the ConversionDataAnnotation, or an
+// empty conversionData if the annotation is not set or cannot be parsed
+func (p *PravegaCluster) conversionData() *conversionData {
+	data := &conversionData{}
+	if value, ok := p.Annotations[ConversionDataAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), data); err != nil {
+			return &conversionData{}
+		}
+	}
+	return data
+}
+
+// ControllerOptions returns the options of the controller, i.e. the options
+// of the cluster and the controller options of the ConversionDataAnnotation
+func (p *PravegaCluster) ControllerOptions() map[string]string {
+	var options map[string]string
+	if p.Spec.Pravega != nil {
+		options = p.Spec.Pravega.Options
+	}
+	return mergeOptions(options, p.conversionData().ControllerOptions)
+}
+
+// SegmentStoreOptions returns the options of the segment store, i.e. the
+// options of the cluster and the segment store options of the
+// ConversionDataAnnotation
+func (p *PravegaCluster) SegmentStoreOptions() map[string]string {
+	var options map[string]string
+	if p.Spec.Pravega != nil {
+		options = p.Spec.Pravega.Options
+	}
+	return mergeOptions(options, p.conversionData().SegmentStoreOptions)
+}
+
+// ControllerExternalAccess returns the external access of the controller,
+// which is the external access of the cluster unless the
+// ConversionDataAnnotation sets another one
+func (p *PravegaCluster) ControllerExternalAccess() *ExternalAccess {
+	if e := p.conversionData().ControllerExternalAccess; e != nil {
+		e.withDefaults()
+		return e
+	}
+	if p.Spec.ExternalAccess == nil {
+		return &ExternalAccess{}
+	}
+	return p.Spec.ExternalAccess
+}
+
+// mergeOptions returns a copy of the options with the overrides, or nil if
+// both are nil
+func mergeOptions(options, overrides map[string]string) map[string]string {
+	if options == nil && overrides == nil {
+		return nil
+	}
+	merged := make(map[string]string, len(options)+len(overrides))
+	for k, v := range options {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ConvertTo converts the cluster to v1beta1
+func (p *PravegaCluster) ConvertTo(dst *v1beta1.PravegaCluster) error {
+	src := p.DeepCopy()
+	data := src.conversionData()
+	if _, ok := src.Annotations[ConversionDataAnnotation]; ok {
+		delete(src.Annotations, ConversionDataAnnotation)
+		if len(src.Annotations) == 0 {
+			src.Annotations = nil
+		}
+	}
+
+	dst.TypeMeta = src.TypeMeta
+	dst.APIVersion = v1beta1.SchemeGroupVersion.String()
+	dst.Kind = "PravegaCluster"
+	dst.ObjectMeta = src.ObjectMeta
+
+	s := &src.Spec
+	dst.Spec = v1beta1.ClusterSpec{
+		ZookeeperUri:     s.ZookeeperUri,
+		Version:          s.Version,
+		ImagePullSecrets: s.ImagePullSecrets,
+	}
+	if s.Zookeeper != nil {
+		dst.Spec.Zookeeper = &v1beta1.ZookeeperSpec{
+			Secret: s.Zookeeper.Secret,
+			Auth:   s.Zookeeper.Auth,
+			TLS:    s.Zookeeper.TLS,
+		}
+		if ref := s.Zookeeper.ClusterRef; ref != nil {
+			dst.Spec.Zookeeper.ClusterRef = &v1beta1.ZookeeperClusterReference{Name: ref.Name, Namespace: ref.Namespace}
+		}
+	}
+	if s.TLS != nil {
+		dst.Spec.TLS = &v1beta1.TLSPolicy{}
+		if s.TLS.Static != nil {
+			static := v1beta1.StaticTLS(*s.TLS.Static)
+			dst.Spec.TLS.Static = &static
+		}
+	}
+	if s.Authentication != nil {
+		authentication := v1beta1.AuthenticationParameters(*s.Authentication)
+		dst.Spec.Authentication = &authentication
+	}
+	if s.UpgradePolicy != nil {
+		upgradePolicy := v1beta1.UpgradePolicySpec(*s.UpgradePolicy)
+		dst.Spec.UpgradePolicy = &upgradePolicy
+	}
+	if b := s.Bookkeeper; b != nil {
+		dst.Spec.Bookkeeper = &v1beta1.BookkeeperSpec{
+			Version:            b.Version,
+			Replicas:           b.Replicas,
+			AutoRecovery:       b.AutoRecovery,
+			ServiceAccountName: b.ServiceAccountName,
+			ImagePullSecrets:   b.ImagePullSecrets,
+			Resources:          b.Resources,
+			Options:            b.Options,
+		}
+		if b.Image != nil {
+			image := v1beta1.ImageSpec(b.Image.ImageSpec)
+			dst.Spec.Bookkeeper.Image = &image
+		}
+		if b.Storage != nil {
+			dst.Spec.Bookkeeper.Storage = &v1beta1.BookkeeperStorageSpec{
+				LedgerVolumeClaimTemplate:  b.Storage.LedgerVolumeClaimTemplate,
+				JournalVolumeClaimTemplate: b.Storage.JournalVolumeClaimTemplate,
+				IndexVolumeClaimTemplate:   b.Storage.IndexVolumeClaimTemplate,
+				ReclaimPolicy:              v1beta1.ReclaimPolicy(b.Storage.ReclaimPolicy),
+			}
+		}
+	}
+	if s.Pravega != nil || s.ExternalAccess != nil || data.ControllerExternalAccess != nil {
+		dst.Spec.Pravega = convertPravegaSpecTo(s.Pravega, s.ExternalAccess, data)
+	}
+
+	return convertStatus(&src.Status, &dst.Status)
+}
+
+func convertPravegaSpecTo(s *PravegaSpec, externalAccess *ExternalAccess, data *conversionData) *v1beta1.PravegaSpec {
+	dst := &v1beta1.PravegaSpec{}
+	if s != nil {
+		dst = &v1beta1.PravegaSpec{
+			ControllerReplicas:             s.ControllerReplicas,
+			SegmentStoreReplicas:           s.SegmentStoreReplicas,
+			DebugLogging:                   s.DebugLogging,
+			ControllerOptions:              mergeOptions(s.Options, data.ControllerOptions),
+			SegmentStoreOptions:            mergeOptions(s.Options, data.SegmentStoreOptions),
+			CacheVolumeClaimTemplate:       s.CacheVolumeClaimTemplate,
+			CacheVolumeReclaimPolicy:       v1beta1.ReclaimPolicy(s.CacheVolumeReclaimPolicy),
+			ControllerServiceAccountName:   s.ControllerServiceAccountName,
+			SegmentStoreServiceAccountName: s.SegmentStoreServiceAccountName,
+			ImagePullSecrets:               s.ImagePullSecrets,
+			ControllerResources:            s.ControllerResources,
+			SegmentStoreResources:          s.SegmentStoreResources,
+		}
+		if s.Image != nil {
+			image := v1beta1.ImageSpec(s.Image.ImageSpec)
+			dst.Image = &image
+		}
+		if t := s.Tier2; t != nil {
+			dst.Tier2 = &v1beta1.Tier2Spec{}
+			if t.FileSystem != nil {
+				fs := v1beta1.FileSystemSpec(*t.FileSystem)
+				dst.Tier2.FileSystem = &fs
+			}
+			if t.Ecs != nil {
+				ecs := v1beta1.ECSSpec(*t.Ecs)
+				dst.Tier2.Ecs = &ecs
+			}
+			if t.Hdfs != nil {
+				hdfs := v1beta1.HDFSSpec(*t.Hdfs)
+				dst.Tier2.Hdfs = &hdfs
+			}
+		}
+	}
+
+	if externalAccess != nil {
+		segmentStore := v1beta1.ExternalAccess(*externalAccess)
+		dst.SegmentStoreExternalAccess = &segmentStore
+		controller := segmentStore
+		dst.ControllerExternalAccess = &controller
+	}
+	if data.ControllerExternalAccess != nil {
+		controller := v1beta1.ExternalAccess(*data.ControllerExternalAccess)
+		dst.ControllerExternalAccess = &controller
+	}
+	return dst
+}
+
+// ConvertFrom converts the cluster from v1beta1. The options and the external
+// access that differ between the controller and the segment store are kept in
+// the ConversionDataAnnotation.
+func (p *PravegaCluster) ConvertFrom(src *v1beta1.PravegaCluster) error {
+	src = src.DeepCopy()
+
+	p.TypeMeta = src.TypeMeta
+	p.APIVersion = SchemeGroupVersion.String()
+	p.Kind = "PravegaCluster"
+	p.ObjectMeta = src.ObjectMeta
+
+	s := &src.Spec
+	p.Spec = ClusterSpec{
+		ZookeeperUri:     s.ZookeeperUri,
+		Version:          s.Version,
+		ImagePullSecrets: s.ImagePullSecrets,
+	}
+	if s.Zookeeper != nil {
+		p.Spec.Zookeeper = &ZookeeperSpec{
+			Secret: s.Zookeeper.Secret,
+			Auth:   s.Zookeeper.Auth,
+			TLS:    s.Zookeeper.TLS,
+		}
+		if ref := s.Zookeeper.ClusterRef; ref != nil {
+			p.Spec.Zookeeper.ClusterRef = &ZookeeperClusterReference{Name: ref.Name, Namespace: ref.Namespace}
+		}
+	}
+	if s.TLS != nil {
+		p.Spec.TLS = &TLSPolicy{}
+		if s.TLS.Static != nil {
+			static := StaticTLS(*s.TLS.Static)
+			p.Spec.TLS.Static = &static
+		}
+	}
+	if s.Authentication != nil {
+		authentication := AuthenticationParameters(*s.Authentication)
+		p.Spec.Authentication = &authentication
+	}
+	if s.UpgradePolicy != nil {
+		upgradePolicy := UpgradePolicySpec(*s.UpgradePolicy)
+		p.Spec.UpgradePolicy = &upgradePolicy
+	}
+	if b := s.Bookkeeper; b != nil {
+		p.Spec.Bookkeeper = &BookkeeperSpec{
+			Version:            b.Version,
+			Replicas:           b.Replicas,
+			AutoRecovery:       b.AutoRecovery,
+			ServiceAccountName: b.ServiceAccountName,
+			ImagePullSecrets:   b.ImagePullSecrets,
+			Resources:          b.Resources,
+			Options:            b.Options,
+		}
+		if b.Image != nil {
+			p.Spec.Bookkeeper.Image = &BookkeeperImageSpec{ImageSpec: ImageSpec(*b.Image)}
+		}
+		if b.Storage != nil {
+			p.Spec.Bookkeeper.Storage = &BookkeeperStorageSpec{
+				LedgerVolumeClaimTemplate:  b.Storage.LedgerVolumeClaimTemplate,
+				JournalVolumeClaimTemplate: b.Storage.JournalVolumeClaimTemplate,
+				IndexVolumeClaimTemplate:   b.Storage.IndexVolumeClaimTemplate,
+				ReclaimPolicy:              ReclaimPolicy(b.Storage.ReclaimPolicy),
+			}
+		}
+	}
+
+	data := &conversionData{}
+	if s.Pravega != nil {
+		p.Spec.Pravega, p.Spec.ExternalAccess = convertPravegaSpecFrom(s.Pravega, data)
+	}
+	if data.ControllerOptions != nil || data.SegmentStoreOptions != nil || data.ControllerExternalAccess != nil {
+		value, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to convert cluster (%s): %v", p.Name, err)
+		}
+		if p.Annotations == nil {
+			p.Annotations = map[string]string{}
+		}
+		p.Annotations[ConversionDataAnnotation] = string(value)
+	}
+
+	return convertStatus(&src.Status, &p.Status)
+}
+
+func convertPravegaSpecFrom(s *v1beta1.PravegaSpec, data *conversionData) (*PravegaSpec, *ExternalAccess) {
+	dst := &PravegaSpec{
+		ControllerReplicas:             s.ControllerReplicas,
+		SegmentStoreReplicas:           s.SegmentStoreReplicas,
+		DebugLogging:                   s.DebugLogging,
+		CacheVolumeClaimTemplate:       s.CacheVolumeClaimTemplate,
+		CacheVolumeReclaimPolicy:       ReclaimPolicy(s.CacheVolumeReclaimPolicy),
+		ControllerServiceAccountName:   s.ControllerServiceAccountName,
+		SegmentStoreServiceAccountName: s.SegmentStoreServiceAccountName,
+		ImagePullSecrets:               s.ImagePullSecrets,
+		ControllerResources:            s.ControllerResources,
+		SegmentStoreResources:          s.SegmentStoreResources,
+	}
+	if s.Image != nil {
+		dst.Image = &PravegaImageSpec{ImageSpec: ImageSpec(*s.Image)}
+	}
+	if t := s.Tier2; t != nil {
+		dst.Tier2 = &Tier2Spec{}
+		if t.FileSystem != nil {
+			fs := FileSystemSpec(*t.FileSystem)
+			dst.Tier2.FileSystem = &fs
+		}
+		if t.Ecs != nil {
+			ecs := ECSSpec(*t.Ecs)
+			dst.Tier2.Ecs = &ecs
+		}
+		if t.Hdfs != nil {
+			hdfs := HDFSSpec(*t.Hdfs)
+			dst.Tier2.Hdfs = &hdfs
+		}
+	}
+
+	// The options set to the same value on both components are the options
+	// of the cluster, the others are kept in the conversion data
+	if s.ControllerOptions != nil || s.SegmentStoreOptions != nil {
+		dst.Options = map[string]string{}
+		for k, v := range s.ControllerOptions {
+			if value, ok := s.SegmentStoreOptions[k]; ok && value == v {
+				dst.Options[k] = v
+			}
+		}
+		data.ControllerOptions = optionsOverrides(s.ControllerOptions, dst.Options)
+		data.SegmentStoreOptions = optionsOverrides(s.SegmentStoreOptions, dst.Options)
+	}
+
+	// The external access of the segment store is the external access of the
+	// cluster, the one of the controller is kept in the conversion data if
+	// it differs
+	var externalAccess *ExternalAccess
+	if s.SegmentStoreExternalAccess != nil {
+		e := ExternalAccess(*s.SegmentStoreExternalAccess)
+		externalAccess = &e
+	}
+	if !reflect.DeepEqual(s.ControllerExternalAccess, s.SegmentStoreExternalAccess) {
+		e := ExternalAccess{}
+		if s.ControllerExternalAccess != nil {
+			e = ExternalAccess(*s.ControllerExternalAccess)
+		}
+		data.ControllerExternalAccess = &e
+	}
+
+	// A v1alpha1 cluster may set the external access without the Pravega
+	// configuration, which is then empty in v1beta1
+	if reflect.DeepEqual(*dst, PravegaSpec{}) && (s.ControllerExternalAccess != nil || s.SegmentStoreExternalAccess != nil) {
+		dst = nil
+	}
+	return dst, externalAccess
+}
+
+// optionsOverrides returns the options that are not in the shared options
+func optionsOverrides(options, shared map[string]string) map[string]string {
+	var overrides map[string]string
+	for k, v := range options {
+		if _, ok := shared[k]; !ok {
+			if overrides == nil {
+				overrides = map[string]string{}
+			}
+			overrides[k] = v
+		}
+	}
+	return overrides
+}
+
+// convertStatus converts the status between versions. The status has the same
+// fields in both versions.
+func convertStatus(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("failed to convert status: %v", err)
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("failed to convert status: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package v1alpha1_test
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+)
+
+var _ = Describe("PravegaCluster conversion", func() {
+
+	toJSON := func(obj interface{}) string {
+		data, err := json.Marshal(obj)
+		Ω(err).Should(BeNil())
+		return string(data)
+	}
+
+	roundTripAlpha := func(p *v1alpha1.PravegaCluster) (*v1beta1.PravegaCluster, *v1alpha1.PravegaCluster) {
+		beta := &v1beta1.PravegaCluster{}
+		Ω(p.ConvertTo(beta)).Should(Succeed())
+		alpha := &v1alpha1.PravegaCluster{}
+		Ω(alpha.ConvertFrom(beta)).Should(Succeed())
+		return beta, alpha
+	}
+
+	roundTripBeta := func(p *v1beta1.PravegaCluster) (*v1alpha1.PravegaCluster, *v1beta1.PravegaCluster) {
+		alpha := &v1alpha1.PravegaCluster{}
+		Ω(alpha.ConvertFrom(p)).Should(Succeed())
+		beta := &v1beta1.PravegaCluster{}
+		Ω(alpha.ConvertTo(beta)).Should(Succeed())
+		return alpha, beta
+	}
+
+	var p *v1alpha1.PravegaCluster
+
+	BeforeEach(func() {
+		p = &v1alpha1.PravegaCluster{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "pravega.pravega.io/v1alpha1",
+				Kind:       "PravegaCluster",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "example",
+				Namespace:   "default",
+				Annotations: map[string]string{v1alpha1.PausedAnnotation: "true"},
+			},
+		}
+	})
+
+	Context("From v1alpha1", func() {
+		It("should round trip an empty cluster", func() {
+			p.Annotations = nil
+			_, alpha := roundTripAlpha(p)
+			Ω(toJSON(alpha)).Should(MatchJSON(toJSON(p)))
+		})
+
+		It("should round trip a cluster with defaults and status", func() {
+			p.WithDefaults()
+			p.Status.InitConditions()
+			p.Status.SetPodsReadyConditionTrue()
+			p.Status.CurrentVersion = "0.4.0"
+			p.Status.Controller.Versions = map[string]string{"example-pravega-controller-0": "0.4.0"}
+			beta, alpha := roundTripAlpha(p)
+			Ω(toJSON(alpha)).Should(MatchJSON(toJSON(p)))
+			Ω(beta.Spec.Pravega.ControllerReplicas).Should(BeEquivalentTo(1))
+			Ω(beta.Status.Conditions).Should(HaveLen(len(p.Status.Conditions)))
+		})
+
+		It("should round trip a detailed cluster", func() {
+			p.Spec = v1alpha1.ClusterSpec{
+				ZookeeperUri: "zk:2181",
+				Zookeeper: &v1alpha1.ZookeeperSpec{
+					Secret:     "zk-secret",
+					Auth:       true,
+					ClusterRef: &v1alpha1.ZookeeperClusterReference{Name: "zk"},
+				},
+				ExternalAccess: &v1alpha1.ExternalAccess{Enabled: true, Type: corev1.ServiceTypeNodePort, DomainName: "example.com"},
+				TLS:            &v1alpha1.TLSPolicy{Static: &v1alpha1.StaticTLS{ControllerSecret: "controller-tls"}},
+				Version:        "0.5.0",
+				Bookkeeper: &v1alpha1.BookkeeperSpec{
+					Image:   &v1alpha1.BookkeeperImageSpec{ImageSpec: v1alpha1.ImageSpec{Repository: "registry/bookkeeper", Digest: "sha256:abc"}},
+					Version: "0.4.0",
+					Storage: &v1alpha1.BookkeeperStorageSpec{ReclaimPolicy: v1alpha1.ReclaimPolicyRetain},
+					Options: map[string]string{"journalDirectories": "/bk/journal"},
+				},
+				Pravega: &v1alpha1.PravegaSpec{
+					Image:   &v1alpha1.PravegaImageSpec{ImageSpec: v1alpha1.ImageSpec{Repository: "registry/pravega", Tag: "0.5.0-patched"}},
+					Options: map[string]string{"pravegaservice.containerCount": "8"},
+					Tier2:   &v1alpha1.Tier2Spec{Ecs: &v1alpha1.ECSSpec{Uri: "https://ecs:9021", Bucket: "pravega", Credentials: "ecs"}},
+				},
+			}
+			beta, alpha := roundTripAlpha(p)
+			Ω(toJSON(alpha)).Should(MatchJSON(toJSON(p)))
+
+			Ω(beta.APIVersion).Should(Equal("pravega.pravega.io/v1beta1"))
+			Ω(beta.Spec.Pravega.ControllerOptions).Should(Equal(p.Spec.Pravega.Options))
+			Ω(beta.Spec.Pravega.SegmentStoreOptions).Should(Equal(p.Spec.Pravega.Options))
+			Ω(*beta.Spec.Pravega.ControllerExternalAccess).Should(BeEquivalentTo(*p.Spec.ExternalAccess))
+			Ω(*beta.Spec.Pravega.SegmentStoreExternalAccess).Should(BeEquivalentTo(*p.Spec.ExternalAccess))
+			Ω(beta.Spec.Pravega.Image.Tag).Should(Equal("0.5.0-patched"))
+			Ω(beta.Spec.Bookkeeper.Image.Digest).Should(Equal("sha256:abc"))
+			Ω(beta.Annotations).Should(Equal(p.Annotations))
+		})
+
+		It("should not share the options of the components", func() {
+			p.Spec.Pravega = &v1alpha1.PravegaSpec{Options: map[string]string{"a": "1"}}
+			beta, _ := roundTripAlpha(p)
+			beta.Spec.Pravega.ControllerOptions["a"] = "2"
+			Ω(beta.Spec.Pravega.SegmentStoreOptions["a"]).Should(Equal("1"))
+			Ω(p.Spec.Pravega.Options["a"]).Should(Equal("1"))
+		})
+	})
+
+	Context("From v1beta1", func() {
+		var b *v1beta1.PravegaCluster
+
+		BeforeEach(func() {
+			b = &v1beta1.PravegaCluster{}
+			Ω(p.ConvertTo(b)).Should(Succeed())
+			b.Spec.Version = "0.5.0"
+			b.Spec.Pravega = &v1beta1.PravegaSpec{
+				ControllerOptions:          map[string]string{"shared": "1", "controller.retention": "10", "differs": "a"},
+				SegmentStoreOptions:        map[string]string{"shared": "1", "pravegaservice.containerCount": "8", "differs": "b"},
+				ControllerExternalAccess:   &v1beta1.ExternalAccess{Enabled: true, Type: corev1.ServiceTypeLoadBalancer},
+				SegmentStoreExternalAccess: &v1beta1.ExternalAccess{Enabled: true, Type: corev1.ServiceTypeNodePort, DomainName: "example.com"},
+			}
+		})
+
+		It("should round trip the settings of the components", func() {
+			_, beta := roundTripBeta(b)
+			Ω(toJSON(beta)).Should(MatchJSON(toJSON(b)))
+		})
+
+		It("should keep the shared settings in v1alpha1", func() {
+			alpha, _ := roundTripBeta(b)
+			Ω(alpha.Spec.Pravega.Options).Should(Equal(map[string]string{"shared": "1"}))
+			Ω(alpha.Spec.ExternalAccess.Type).Should(Equal(corev1.ServiceTypeNodePort))
+			Ω(alpha.Annotations).Should(HaveKey(v1alpha1.ConversionDataAnnotation))
+			Ω(alpha.Annotations).Should(HaveKeyWithValue(v1alpha1.PausedAnnotation, "true"))
+		})
+
+		It("should expose the settings of the components in v1alpha1", func() {
+			alpha, _ := roundTripBeta(b)
+			Ω(alpha.ControllerOptions()).Should(Equal(b.Spec.Pravega.ControllerOptions))
+			Ω(alpha.SegmentStoreOptions()).Should(Equal(b.Spec.Pravega.SegmentStoreOptions))
+			Ω(*alpha.ControllerExternalAccess()).Should(BeEquivalentTo(*b.Spec.Pravega.ControllerExternalAccess))
+		})
+
+		It("should not annotate clusters with the same settings on both components", func() {
+			b.Spec.Pravega.ControllerOptions = b.Spec.Pravega.SegmentStoreOptions
+			b.Spec.Pravega.ControllerExternalAccess = b.Spec.Pravega.SegmentStoreExternalAccess
+			alpha, beta := roundTripBeta(b)
+			Ω(alpha.Annotations).ShouldNot(HaveKey(v1alpha1.ConversionDataAnnotation))
+			Ω(alpha.ControllerOptions()).Should(Equal(alpha.Spec.Pravega.Options))
+			Ω(alpha.ControllerExternalAccess()).Should(Equal(alpha.Spec.ExternalAccess))
+			Ω(toJSON(beta)).Should(MatchJSON(toJSON(b)))
+		})
+
+		It("should round trip the external access without Pravega configuration", func() {
+			b.Spec.Pravega = &v1beta1.PravegaSpec{
+				ControllerExternalAccess:   &v1beta1.ExternalAccess{Enabled: true},
+				SegmentStoreExternalAccess: &v1beta1.ExternalAccess{Enabled: true},
+			}
+			alpha, beta := roundTripBeta(b)
+			Ω(alpha.Spec.Pravega).Should(BeNil())
+			Ω(toJSON(beta)).Should(MatchJSON(toJSON(b)))
+		})
+	})
+})
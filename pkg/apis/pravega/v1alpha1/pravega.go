@@ -93,6 +93,11 @@ type PravegaSpec struct {
 	// emptyDir as volume
 	CacheVolumeClaimTemplate *v1.PersistentVolumeClaimSpec `json:"cacheVolumeClaimTemplate"`
 
+	// CacheVolumeReclaimPolicy defines whether the cache volumes are deleted
+	// when the Segment Store is scaled down or the cluster is deleted.
+	// Options are "Retain" and "Delete". Defaults to "Delete".
+	CacheVolumeReclaimPolicy ReclaimPolicy `json:"cacheVolumeReclaimPolicy,omitempty"`
+
 	// Tier2 is the configuration of Pravega's tier 2 storage. If no configuration
 	// is provided, it will assume that a PersistentVolumeClaim called "pravega-tier2"
 	// is present and it will use it as Tier 2
@@ -106,6 +111,10 @@ type PravegaSpec struct {
 	// If not specified, Kubernetes will automatically assign the default service account in the namespace
 	SegmentStoreServiceAccountName string `json:"segmentStoreServiceAccountName,omitempty"`
 
+	// ImagePullSecrets are the secrets used to pull the Pravega image, in
+	// addition to the image pull secrets of the cluster
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
 	// ControllerResources specifies the request and limit of resources that controller can have.
 	// ControllerResources includes CPU and memory resources
 	ControllerResources *v1.ResourceRequirements `json:"controllerResources,omitempty"`
@@ -151,6 +160,11 @@ func (s *PravegaSpec) withDefaults() (changed bool) {
 		}
 	}
 
+	if s.CacheVolumeReclaimPolicy == "" {
+		changed = true
+		s.CacheVolumeReclaimPolicy = DefaultReclaimPolicy
+	}
+
 	if s.Tier2 == nil {
 		changed = true
 		s.Tier2 = &Tier2Spec{}
@@ -202,8 +216,6 @@ func (s *PravegaImageSpec) withDefaults() (changed bool) {
 		s.Repository = DefaultPravegaImageRepository
 	}
 
-	s.Tag = ""
-
 	if s.PullPolicy == "" {
 		changed = true
 		s.PullPolicy = DefaultPravegaImagePullPolicy
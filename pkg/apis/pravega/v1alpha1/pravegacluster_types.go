@@ -25,6 +25,33 @@ const (
 	// DefaultPravegaVersion is the default tag used for for the Pravega
 	// Docker image
 	DefaultPravegaVersion = "0.4.0"
+
+	// DefaultProgressDeadlineSeconds is the default time a component may
+	// take to make progress during an upgrade before the upgrade fails
+	DefaultProgressDeadlineSeconds = 600
+
+	// PausedAnnotation pauses the reconciliation of a cluster when set to
+	// "true". The operator then only reports the status of the cluster.
+	PausedAnnotation = "pravega.pravega.io/paused"
+
+	// UpgradeApprovedAnnotation approves the upgrade of the remaining pods of
+	// a component once its canary pods are upgraded. The value is the version
+	// the component is upgrading to. The operator removes the annotation once
+	// the approval is recorded, so that every component is approved on its own.
+	UpgradeApprovedAnnotation = "pravega.pravega.io/upgrade-approved"
+
+	// AllowImmutableChangesAnnotation lets the webhook accept changes to the
+	// fields that cannot change once the cluster is created, e.g. the volume
+	// claim templates or the tier 2 storage, when set to "true". Such changes
+	// may break the cluster. The annotation should be removed once the change
+	// is made.
+	AllowImmutableChangesAnnotation = "pravega.pravega.io/allow-immutable-changes"
+
+	// DefaultedFieldsAnnotation lists the paths of the fields of the spec
+	// set to their default value by the mutating webhook, separated by
+	// commas, e.g. "spec.externalAccess,spec.pravega.controllerReplicas".
+	// Tools comparing the stored spec with the applied one may ignore them.
+	DefaultedFieldsAnnotation = "pravega.pravega.io/defaulted-fields"
 )
 
 func init() {
@@ -55,9 +82,44 @@ type PravegaCluster struct {
 // WithDefaults set default values when not defined in the spec.
 func (p *PravegaCluster) WithDefaults() (changed bool) {
 	changed = p.Spec.withDefaults()
+
+	if ref := p.Spec.Zookeeper.ClusterRef; ref != nil && ref.Namespace == "" {
+		changed = true
+		ref.Namespace = p.Namespace
+	}
 	return changed
 }
 
+// ZookeeperAddress returns the address the cluster uses to connect to
+// ZooKeeper: the address of the referenced ZookeeperCluster once it is
+// resolved, or the ZooKeeper URI of the spec
+func (p *PravegaCluster) ZookeeperAddress() string {
+	if p.Spec.Zookeeper != nil && p.Spec.Zookeeper.ClusterRef != nil && p.Status.ZookeeperUri != "" {
+		return p.Status.ZookeeperUri
+	}
+	return p.Spec.ZookeeperUri
+}
+
+// BookkeeperVersion returns the version of BookKeeper, which is the cluster
+// version unless a BookKeeper version is set
+func (p *PravegaCluster) BookkeeperVersion() string {
+	if p.Spec.Bookkeeper != nil && p.Spec.Bookkeeper.Version != "" {
+		return p.Spec.Bookkeeper.Version
+	}
+	return p.Spec.Version
+}
+
+// IsPaused returns true if the reconciliation of the cluster is paused
+func (p *PravegaCluster) IsPaused() bool {
+	return p.Annotations[PausedAnnotation] == "true"
+}
+
+// AllowsImmutableChanges returns true if the cluster has the
+// AllowImmutableChangesAnnotation
+func (p *PravegaCluster) AllowsImmutableChanges() bool {
+	return p.Annotations[AllowImmutableChangesAnnotation] == "true"
+}
+
 // ClusterSpec defines the desired state of PravegaCluster
 type ClusterSpec struct {
 	// ZookeeperUri specifies the hostname/IP address and port in the format
@@ -67,6 +129,10 @@ type ClusterSpec struct {
 	// available at: https://github.com/pravega/zookeeper-operator
 	ZookeeperUri string `json:"zookeeperUri"`
 
+	// Zookeeper configures authentication and TLS for the connections to
+	// ZooKeeper, both from the operator and from the Pravega components
+	Zookeeper *ZookeeperSpec `json:"zookeeper,omitempty"`
+
 	// ExternalAccess specifies whether or not to allow external access
 	// to clients and the service type to use to achieve it
 	// By default, external access is not enabled
@@ -92,6 +158,13 @@ type ClusterSpec struct {
 	// If version is not set, default is "0.4.0".
 	Version string `json:"version"`
 
+	// UpgradePolicy defines how the operator handles version upgrades
+	UpgradePolicy *UpgradePolicySpec `json:"upgradePolicy,omitempty"`
+
+	// ImagePullSecrets are the secrets used to pull the images of all the
+	// components from private registries
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
 	// Bookkeeper configuration
 	Bookkeeper *BookkeeperSpec `json:"bookkeeper"`
 
@@ -105,6 +178,11 @@ func (s *ClusterSpec) withDefaults() (changed bool) {
 		s.ZookeeperUri = DefaultZookeeperUri
 	}
 
+	if s.Zookeeper == nil {
+		changed = true
+		s.Zookeeper = &ZookeeperSpec{}
+	}
+
 	if s.ExternalAccess == nil {
 		changed = true
 		s.ExternalAccess = &ExternalAccess{}
@@ -127,7 +205,21 @@ func (s *ClusterSpec) withDefaults() (changed bool) {
 	}
 
 	if s.Version == "" {
-		s.Version = DefaultPravegaVersion
+		if s.Pravega != nil && s.Pravega.Image != nil && s.Pravega.Image.Tag != "" {
+			// The tag of the Pravega image used to set the cluster version
+			s.Version = s.Pravega.Image.Tag
+			s.Pravega.Image.Tag = ""
+		} else {
+			s.Version = DefaultPravegaVersion
+		}
+		changed = true
+	}
+
+	if s.UpgradePolicy == nil {
+		changed = true
+		s.UpgradePolicy = &UpgradePolicySpec{}
+	}
+	if s.UpgradePolicy.withDefaults() {
 		changed = true
 	}
 
@@ -151,6 +243,70 @@ func (s *ClusterSpec) withDefaults() (changed bool) {
 	return changed
 }
 
+const (
+	// Keys of the ZooKeeper secret used by the operator. Username and password
+	// are used with the digest authentication scheme. The client certificate
+	// and key are optional
+	ZookeeperSecretUsernameKey = "username"
+	ZookeeperSecretPasswordKey = "password"
+	ZookeeperSecretCACertKey   = "ca.crt"
+	ZookeeperSecretCertKey     = "tls.crt"
+	ZookeeperSecretKeyKey      = "tls.key"
+
+	// Keys of the ZooKeeper secret used by the Pravega components. The JAAS
+	// configuration holds the SASL credentials in its "Client" section
+	ZookeeperSecretJaasKey               = "jaas.conf"
+	ZookeeperSecretTrustStoreKey         = "truststore.jks"
+	ZookeeperSecretTrustStorePasswordKey = "truststore.password"
+)
+
+// ZookeeperSpec defines how to connect to ZooKeeper
+type ZookeeperSpec struct {
+	// Secret is the name of the secret holding the ZooKeeper credentials and
+	// TLS material, in the namespace of the cluster. It is mounted in the
+	// Pravega components and read by the operator to clean up the cluster
+	// metadata.
+	Secret string `json:"secret,omitempty"`
+
+	// Auth specifies whether or not to authenticate to ZooKeeper with the
+	// credentials of the secret. By default, authentication is not enabled
+	Auth bool `json:"auth,omitempty"`
+
+	// TLS specifies whether or not to connect to ZooKeeper with TLS, using the
+	// certificates of the secret. By default, TLS is not enabled
+	TLS bool `json:"tls,omitempty"`
+
+	// ClusterRef references a ZookeeperCluster managed by the zookeeper
+	// operator. When set, the cluster uses the client address reported by
+	// the ZookeeperCluster instead of ZookeeperUri, and the bookies are not
+	// deployed until it is ready. The address is reported in the status
+	ClusterRef *ZookeeperClusterReference `json:"clusterRef,omitempty"`
+}
+
+// ZookeeperClusterReference references a ZookeeperCluster object
+type ZookeeperClusterReference struct {
+	// Name of the ZookeeperCluster
+	Name string `json:"name"`
+
+	// Namespace of the ZookeeperCluster. Defaults to the namespace of the
+	// Pravega cluster
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (z *ZookeeperSpec) IsAuthEnabled() bool {
+	if z == nil {
+		return false
+	}
+	return z.Auth && z.Secret != ""
+}
+
+func (z *ZookeeperSpec) IsTLSEnabled() bool {
+	if z == nil {
+		return false
+	}
+	return z.TLS && z.Secret != ""
+}
+
 // ExternalAccess defines the configuration of the external access
 type ExternalAccess struct {
 	// Enabled specifies whether or not external access is enabled
@@ -222,12 +378,126 @@ func (ap *AuthenticationParameters) IsEnabled() bool {
 	return ap.Enabled
 }
 
+// UpgradePolicySpec defines how the operator handles version upgrades
+type UpgradePolicySpec struct {
+	// Rollback specifies whether a failed upgrade is rolled back to the
+	// previous version. The components already upgraded are rolled back in
+	// the reverse order of the upgrade, one pod at a time. If disabled, a
+	// failed upgrade is left as is and needs manual intervention.
+	// By default, failed upgrades are not rolled back
+	Rollback bool `json:"rollback,omitempty"`
+
+	// ControllerProgressDeadlineSeconds is the time in seconds the controller
+	// deployment may take to make progress during an upgrade or a config
+	// rollout before it is considered failed. It is the progress deadline of
+	// the deployment.
+	// Default is 600
+	ControllerProgressDeadlineSeconds int32 `json:"controllerProgressDeadlineSeconds,omitempty"`
+
+	// SegmentStoreProgressDeadlineSeconds is the time in seconds the segment
+	// stores may take to make progress, i.e. to get one more pod updated,
+	// during an upgrade or a config rollout before it is considered failed.
+	// Segment stores recovering many containers from tier 2 may need longer.
+	// Default is 600
+	SegmentStoreProgressDeadlineSeconds int32 `json:"segmentStoreProgressDeadlineSeconds,omitempty"`
+
+	// BookkeeperProgressDeadlineSeconds is the time in seconds the bookies may
+	// take to make progress, i.e. to get one more pod updated, during an
+	// upgrade or a config rollout before it is considered failed.
+	// Default is 600
+	BookkeeperProgressDeadlineSeconds int32 `json:"bookkeeperProgressDeadlineSeconds,omitempty"`
+
+	// PodReadyTimeoutSeconds is the time in seconds an updated pod may take
+	// to become ready before the upgrade or the config rollout is considered
+	// failed. By default, pods have no readiness timeout and only the
+	// progress deadlines apply
+	PodReadyTimeoutSeconds int32 `json:"podReadyTimeoutSeconds,omitempty"`
+
+	// SegmentStoreCanaryPods is the number of segment store pods upgraded
+	// first. Once they are ready, the upgrade waits for an approval before
+	// upgrading the remaining pods, see UpgradeApprovedAnnotation.
+	// By default, there are no canary pods
+	SegmentStoreCanaryPods int32 `json:"segmentStoreCanaryPods,omitempty"`
+
+	// BookkeeperCanaryPods is the number of bookie pods upgraded first. Once
+	// they are ready, the upgrade waits for an approval before upgrading the
+	// remaining pods, see UpgradeApprovedAnnotation.
+	// By default, there are no canary pods
+	BookkeeperCanaryPods int32 `json:"bookkeeperCanaryPods,omitempty"`
+}
+
+func (u *UpgradePolicySpec) withDefaults() (changed bool) {
+	if u.ControllerProgressDeadlineSeconds <= 0 {
+		changed = true
+		u.ControllerProgressDeadlineSeconds = DefaultProgressDeadlineSeconds
+	}
+
+	if u.SegmentStoreProgressDeadlineSeconds <= 0 {
+		changed = true
+		u.SegmentStoreProgressDeadlineSeconds = DefaultProgressDeadlineSeconds
+	}
+
+	if u.BookkeeperProgressDeadlineSeconds <= 0 {
+		changed = true
+		u.BookkeeperProgressDeadlineSeconds = DefaultProgressDeadlineSeconds
+	}
+
+	if u.PodReadyTimeoutSeconds < 0 {
+		changed = true
+		u.PodReadyTimeoutSeconds = 0
+	}
+
+	if u.SegmentStoreCanaryPods < 0 {
+		changed = true
+		u.SegmentStoreCanaryPods = 0
+	}
+
+	if u.BookkeeperCanaryPods < 0 {
+		changed = true
+		u.BookkeeperCanaryPods = 0
+	}
+
+	return changed
+}
+
+func (u *UpgradePolicySpec) IsRollbackEnabled() bool {
+	if u == nil {
+		return false
+	}
+	return u.Rollback
+}
+
+// ReclaimPolicy defines what happens to the persistent volume claims of a
+// component when the component is scaled down or the cluster is deleted
+type ReclaimPolicy string
+
+const (
+	// ReclaimPolicyRetain keeps the persistent volume claims. They have to be
+	// deleted manually once they are no longer needed
+	ReclaimPolicyRetain ReclaimPolicy = "Retain"
+
+	// ReclaimPolicyDelete deletes the persistent volume claims of the pods
+	// removed by a scale-down, and of all pods when the cluster is deleted
+	ReclaimPolicyDelete ReclaimPolicy = "Delete"
+
+	// DefaultReclaimPolicy is the reclaim policy used when none is specified
+	DefaultReclaimPolicy = ReclaimPolicyDelete
+)
+
 // ImageSpec defines the fields needed for a Docker repository image
 type ImageSpec struct {
 	Repository string `json:"repository"`
 
-	// Deprecated: Use `spec.Version` instead
+	// Tag is the tag of the image. By default, the version of the component
+	// is used as tag. Set it to run another build of the same version, e.g.
+	// an image patched for a CVE.
+	// If the cluster version is not set, the tag of the Pravega image is
+	// used as cluster version instead, as in previous releases.
 	Tag string `json:"tag,omitempty"`
 
+	// Digest pins the image to a digest, e.g. "sha256:4b1c...". The digest
+	// takes precedence over the tag.
+	Digest string `json:"digest,omitempty"`
+
 	PullPolicy v1.PullPolicy `json:"pullPolicy"`
 }
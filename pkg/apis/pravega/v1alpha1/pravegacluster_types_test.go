@@ -67,5 +67,75 @@ var _ = Describe("PravegaCluster Types Spec", func() {
 		It("should set bookkeeper spec", func() {
 			Ω(p.Spec.Bookkeeper).ShouldNot(BeNil())
 		})
+
+		It("should not roll back failed upgrades", func() {
+			Ω(p.Spec.UpgradePolicy).ShouldNot(BeNil())
+			Ω(p.Spec.UpgradePolicy.IsRollbackEnabled()).Should(BeFalse())
+		})
+
+		It("should set the upgrade progress deadlines to 600 seconds", func() {
+			Ω(p.Spec.UpgradePolicy.ControllerProgressDeadlineSeconds).Should(BeEquivalentTo(600))
+			Ω(p.Spec.UpgradePolicy.SegmentStoreProgressDeadlineSeconds).Should(BeEquivalentTo(600))
+			Ω(p.Spec.UpgradePolicy.BookkeeperProgressDeadlineSeconds).Should(BeEquivalentTo(600))
+			Ω(p.Spec.UpgradePolicy.PodReadyTimeoutSeconds).Should(BeZero())
+		})
+
+		It("should set the volume reclaim policies to Delete", func() {
+			Ω(p.Spec.Bookkeeper.Storage.ReclaimPolicy).Should(Equal(v1alpha1.ReclaimPolicyDelete))
+			Ω(p.Spec.Pravega.CacheVolumeReclaimPolicy).Should(Equal(v1alpha1.ReclaimPolicyDelete))
+		})
+	})
+
+	Context("#WithDefaults with an image tag", func() {
+		BeforeEach(func() {
+			p.Spec.Pravega = &v1alpha1.PravegaSpec{
+				Image: &v1alpha1.PravegaImageSpec{
+					ImageSpec: v1alpha1.ImageSpec{Tag: "0.5.0-2"},
+				},
+			}
+		})
+
+		It("should use the tag as version if the version is not set", func() {
+			p.WithDefaults()
+			Ω(p.Spec.Version).Should(Equal("0.5.0-2"))
+			Ω(p.Spec.Pravega.Image.Tag).Should(BeEmpty())
+		})
+
+		It("should keep the tag if the version is set", func() {
+			p.Spec.Version = "0.5.0"
+			p.WithDefaults()
+			Ω(p.Spec.Version).Should(Equal("0.5.0"))
+			Ω(p.Spec.Pravega.Image.Tag).Should(Equal("0.5.0-2"))
+		})
+	})
+
+	Context("#BookkeeperVersion", func() {
+		BeforeEach(func() {
+			p.Spec.Version = "0.5.0"
+			p.WithDefaults()
+		})
+
+		It("should be the cluster version by default", func() {
+			Ω(p.BookkeeperVersion()).Should(Equal("0.5.0"))
+		})
+
+		It("should be the bookkeeper version if set", func() {
+			p.Spec.Bookkeeper.Version = "0.6.0"
+			Ω(p.BookkeeperVersion()).Should(Equal("0.6.0"))
+		})
+	})
+
+	Context("#WithDefaults with a zookeeper cluster reference", func() {
+		BeforeEach(func() {
+			p.Namespace = "pravega"
+			p.Spec.Zookeeper = &v1alpha1.ZookeeperSpec{
+				ClusterRef: &v1alpha1.ZookeeperClusterReference{Name: "zookeeper"},
+			}
+			p.WithDefaults()
+		})
+
+		It("should default the namespace of the reference", func() {
+			Ω(p.Spec.Zookeeper.ClusterRef.Namespace).Should(Equal("pravega"))
+		})
 	})
 })
@@ -22,11 +22,44 @@ const (
 	ClusterConditionPodsReady ClusterConditionType = "PodsReady"
 	ClusterConditionUpgrading                      = "Upgrading"
 	ClusterConditionError                          = "Error"
+	ClusterConditionPaused                         = "Paused"
 
 	// Reasons for cluster upgrading condition
 	UpgradingControllerReason   = "UpgradingController"
 	UpgradingSegmentstoreReason = "UpgradingSegmentstore"
 	UpgradingBookkeeperReason   = "UpgradingBookkeeper"
+
+	// Reasons for cluster upgrading condition when a failed upgrade is
+	// rolled back to the previous version
+	RollingBackControllerReason   = "RollingBackController"
+	RollingBackSegmentstoreReason = "RollingBackSegmentstore"
+	RollingBackBookkeeperReason   = "RollingBackBookkeeper"
+
+	// Reasons for cluster upgrading condition when the canary pods of a
+	// component are upgraded and the upgrade waits for an approval
+	WaitingForSegmentstoreApprovalReason = "WaitingForSegmentstoreApproval"
+	WaitingForBookkeeperApprovalReason   = "WaitingForBookkeeperApproval"
+
+	// Reasons for cluster upgrading condition when pods are restarted to
+	// pick up configuration changes without a version change
+	UpdatingControllerConfigReason   = "UpdatingControllerConfig"
+	UpdatingSegmentstoreConfigReason = "UpdatingSegmentstoreConfig"
+	UpdatingBookkeeperConfigReason   = "UpdatingBookkeeperConfig"
+
+	// Phases of the ZooKeeper metadata cleanup of a deleted cluster
+	ZookeeperCleanupTerminatingPods = "TerminatingPods"
+	ZookeeperCleanupDeletingZnodes  = "DeletingZnodes"
+	ZookeeperCleanupFailed          = "Failed"
+
+	// Phases of the rollback of a failed upgrade
+	RollbackInProgress = "InProgress"
+	RollbackCompleted  = "Completed"
+	RollbackFailed     = "Failed"
+
+	// Phases of the canary pods of a component during an upgrade
+	CanaryUpgrading          = "Upgrading"
+	CanaryWaitingForApproval = "WaitingForApproval"
+	CanaryApproved           = "Approved"
 )
 
 // ClusterStatus defines the observed state of PravegaCluster
@@ -41,6 +74,23 @@ type ClusterStatus struct {
 	// If the cluster is not upgrading, TargetVersion is empty.
 	TargetVersion string `json:"targetVersion,omitempty"`
 
+	// CurrentBookkeeperVersion is the current BookKeeper version
+	CurrentBookkeeperVersion string `json:"currentBookkeeperVersion,omitempty"`
+
+	// TargetBookkeeperVersion is the version BookKeeper is upgrading to.
+	// If the cluster is not upgrading, TargetBookkeeperVersion is empty.
+	TargetBookkeeperVersion string `json:"targetBookkeeperVersion,omitempty"`
+
+	// CurrentPravegaImage is the Pravega image of the spec when the cluster
+	// last ran the current version. It is restored if an upgrade is rolled
+	// back.
+	CurrentPravegaImage *ImageSpec `json:"currentPravegaImage,omitempty"`
+
+	// CurrentBookkeeperImage is the BookKeeper image of the spec when the
+	// cluster last ran the current BookKeeper version. It is restored if an
+	// upgrade is rolled back.
+	CurrentBookkeeperImage *ImageSpec `json:"currentBookkeeperImage,omitempty"`
+
 	// Replicas is the number of desired replicas in the cluster
 	Replicas int32 `json:"replicas"`
 
@@ -52,6 +102,139 @@ type ClusterStatus struct {
 
 	// Members is the Pravega members in the cluster
 	Members MembersStatus `json:"members"`
+
+	// ObservedGeneration is the most recent generation of the cluster spec
+	// reflected by this status
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ZookeeperUri is the client address reported by the ZookeeperCluster
+	// referenced in the spec, which the cluster uses instead of the ZooKeeper
+	// URI of the spec
+	ZookeeperUri string `json:"zookeeperUri,omitempty"`
+
+	// Controller is the status of the Pravega controllers
+	Controller ComponentStatus `json:"controller"`
+
+	// SegmentStore is the status of the Pravega segment stores
+	SegmentStore ComponentStatus `json:"segmentStore"`
+
+	// Bookkeeper is the status of the bookies
+	Bookkeeper ComponentStatus `json:"bookkeeper"`
+
+	// ZookeeperCleanup is the progress of the deletion of the cluster
+	// metadata from ZooKeeper. It is only set once the cluster is deleted.
+	ZookeeperCleanup *ZookeeperCleanupStatus `json:"zookeeperCleanup,omitempty"`
+
+	// Rollback is the progress of the rollback of the last failed upgrade.
+	// It is only set if the upgrade policy enables rollbacks, and it is
+	// replaced when another upgrade fails.
+	Rollback *RollbackStatus `json:"rollback,omitempty"`
+
+	// Canary is the progress of the canary pods of the component being
+	// upgraded. It is only set during an upgrade with canary pods.
+	Canary *CanaryStatus `json:"canary,omitempty"`
+}
+
+// CanaryStatus is the progress of the canary pods of a component during an
+// upgrade
+type CanaryStatus struct {
+	// Component is the component being upgraded, i.e. bookie or
+	// pravega-segmentstore
+	Component string `json:"component"`
+
+	// Version is the version the component is upgraded to
+	Version string `json:"version"`
+
+	// Phase is the current phase of the canary pods, one of Upgrading,
+	// WaitingForApproval or Approved
+	Phase string `json:"phase"`
+
+	// Pods is the list of pods of the component running the new version
+	Pods []string `json:"pods,omitempty"`
+
+	// WaitingSince is the time the upgrade started waiting for an approval
+	WaitingSince string `json:"waitingSince,omitempty"`
+}
+
+// RollbackStatus is the progress of the rollback of a failed upgrade
+type RollbackStatus struct {
+	// Phase is the current phase of the rollback, one of InProgress,
+	// Completed or Failed. A Failed rollback needs manual intervention.
+	Phase string `json:"phase"`
+
+	// FromVersion is the version of the failed upgrade
+	FromVersion string `json:"fromVersion"`
+
+	// ToVersion is the version the cluster is rolled back to
+	ToVersion string `json:"toVersion"`
+
+	// FromBookkeeperVersion is the BookKeeper version of the failed upgrade
+	FromBookkeeperVersion string `json:"fromBookkeeperVersion,omitempty"`
+
+	// ToBookkeeperVersion is the version BookKeeper is rolled back to
+	ToBookkeeperVersion string `json:"toBookkeeperVersion,omitempty"`
+
+	// Reason is the error that failed the upgrade
+	Reason string `json:"reason,omitempty"`
+
+	// A human readable message indicating why the rollback failed
+	Message string `json:"message,omitempty"`
+
+	// StartTime is the time the rollback started
+	StartTime string `json:"startTime,omitempty"`
+
+	// CompletionTime is the time the rollback completed or failed
+	CompletionTime string `json:"completionTime,omitempty"`
+}
+
+// IsRollingBack returns true if a failed upgrade is being rolled back
+func (ps *ClusterStatus) IsRollingBack() bool {
+	return ps.Rollback != nil && ps.Rollback.Phase == RollbackInProgress
+}
+
+// ZookeeperCleanupStatus is the progress of the deletion of the cluster
+// metadata from ZooKeeper
+type ZookeeperCleanupStatus struct {
+	// Phase is the current phase of the cleanup, one of TerminatingPods,
+	// DeletingZnodes or Failed. The cleanup is Failed if the metadata could
+	// not be deleted before the timeout. It is still retried in that case.
+	Phase string `json:"phase"`
+
+	// A human readable message indicating details about the current phase
+	Message string `json:"message,omitempty"`
+
+	// StartTime is the time the cleanup started
+	StartTime string `json:"startTime,omitempty"`
+
+	// Attempts is the number of attempts to delete the metadata
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// LastAttemptTime is the time of the last attempt to delete the metadata
+	LastAttemptTime string `json:"lastAttemptTime,omitempty"`
+}
+
+// ComponentStatus is the observed state of one of the components of the
+// cluster, i.e. controller, segment store or bookkeeper
+type ComponentStatus struct {
+	// Replicas is the number of desired replicas of the component
+	Replicas int32 `json:"replicas"`
+
+	// CurrentReplicas is the number of current replicas of the component
+	CurrentReplicas int32 `json:"currentReplicas"`
+
+	// ReadyReplicas is the number of ready replicas of the component
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// UpdatedReplicas is the number of replicas running the latest pod
+	// template of the component
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+
+	// Versions is the Pravega version each pod of the component is running,
+	// indexed by pod name
+	Versions map[string]string `json:"versions,omitempty"`
+
+	// Members is the members of the component
+	Members MembersStatus `json:"members"`
 }
 
 // MembersStatus is the status of the members of the cluster with both
@@ -88,6 +271,7 @@ func (ps *ClusterStatus) InitConditions() {
 		ClusterConditionPodsReady,
 		ClusterConditionUpgrading,
 		ClusterConditionError,
+		ClusterConditionPaused,
 	}
 	for _, conditionType := range conditionTypes {
 		if _, condition := ps.GetClusterCondition(conditionType); condition == nil {
@@ -112,6 +296,17 @@ func (ps *ClusterStatus) SetUpgradingConditionTrue(reason, message string) {
 	ps.setClusterCondition(*c)
 }
 
+// RestartUpgradingCondition sets the last update time of the Upgrading
+// condition to now if an upgrade or a rollout is in progress, so that the
+// progress deadline is counted from now
+func (ps *ClusterStatus) RestartUpgradingCondition() {
+	position, condition := ps.GetClusterCondition(ClusterConditionUpgrading)
+	if condition == nil || condition.Status != corev1.ConditionTrue {
+		return
+	}
+	ps.Conditions[position].LastUpdateTime = time.Now().Format(time.RFC3339)
+}
+
 func (ps *ClusterStatus) SetUpgradingConditionFalse() {
 	c := newClusterCondition(ClusterConditionUpgrading, corev1.ConditionFalse, "", "")
 	ps.setClusterCondition(*c)
@@ -127,6 +322,26 @@ func (ps *ClusterStatus) SetErrorConditionFalse() {
 	ps.setClusterCondition(*c)
 }
 
+func (ps *ClusterStatus) SetPausedConditionTrue(reason, message string) {
+	c := newClusterCondition(ClusterConditionPaused, corev1.ConditionTrue, reason, message)
+	ps.setClusterCondition(*c)
+}
+
+func (ps *ClusterStatus) SetPausedConditionFalse() {
+	c := newClusterCondition(ClusterConditionPaused, corev1.ConditionFalse, "", "")
+	ps.setClusterCondition(*c)
+}
+
+// IsConfigUpdateReason returns true if the reason of the upgrading condition
+// corresponds to a configuration rollout rather than a version upgrade
+func IsConfigUpdateReason(reason string) bool {
+	switch reason {
+	case UpdatingControllerConfigReason, UpdatingSegmentstoreConfigReason, UpdatingBookkeeperConfigReason:
+		return true
+	}
+	return false
+}
+
 func newClusterCondition(condType ClusterConditionType, status corev1.ConditionStatus, reason, message string) *ClusterCondition {
 	return &ClusterCondition{
 		Type:               condType,
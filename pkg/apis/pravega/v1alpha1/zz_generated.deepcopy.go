@@ -72,6 +72,11 @@ func (in *BookkeeperSpec) DeepCopyInto(out *BookkeeperSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -116,6 +121,27 @@ func (in *BookkeeperStorageSpec) DeepCopy() *BookkeeperStorageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryStatus) DeepCopyInto(out *CanaryStatus) {
+	*out = *in
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryStatus.
+func (in *CanaryStatus) DeepCopy() *CanaryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterCondition) DeepCopyInto(out *ClusterCondition) {
 	*out = *in
@@ -135,11 +161,26 @@ func (in *ClusterCondition) DeepCopy() *ClusterCondition {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	*out = *in
+	if in.Zookeeper != nil {
+		in, out := &in.Zookeeper, &out.Zookeeper
+		*out = new(ZookeeperSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ExternalAccess != nil {
 		in, out := &in.ExternalAccess, &out.ExternalAccess
 		*out = new(ExternalAccess)
 		**out = **in
 	}
+	if in.UpgradePolicy != nil {
+		in, out := &in.UpgradePolicy, &out.UpgradePolicy
+		*out = new(UpgradePolicySpec)
+		**out = **in
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.Bookkeeper != nil {
 		in, out := &in.Bookkeeper, &out.Bookkeeper
 		*out = new(BookkeeperSpec)
@@ -171,7 +212,35 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 		*out = make([]ClusterCondition, len(*in))
 		copy(*out, *in)
 	}
+	if in.CurrentPravegaImage != nil {
+		in, out := &in.CurrentPravegaImage, &out.CurrentPravegaImage
+		*out = new(ImageSpec)
+		**out = **in
+	}
+	if in.CurrentBookkeeperImage != nil {
+		in, out := &in.CurrentBookkeeperImage, &out.CurrentBookkeeperImage
+		*out = new(ImageSpec)
+		**out = **in
+	}
 	in.Members.DeepCopyInto(&out.Members)
+	in.Controller.DeepCopyInto(&out.Controller)
+	in.SegmentStore.DeepCopyInto(&out.SegmentStore)
+	in.Bookkeeper.DeepCopyInto(&out.Bookkeeper)
+	if in.ZookeeperCleanup != nil {
+		in, out := &in.ZookeeperCleanup, &out.ZookeeperCleanup
+		*out = new(ZookeeperCleanupStatus)
+		**out = **in
+	}
+	if in.Rollback != nil {
+		in, out := &in.Rollback, &out.Rollback
+		*out = new(RollbackStatus)
+		**out = **in
+	}
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(CanaryStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -185,6 +254,30 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
+	*out = *in
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Members.DeepCopyInto(&out.Members)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
+func (in *ComponentStatus) DeepCopy() *ComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ECSSpec) DeepCopyInto(out *ECSSpec) {
 	*out = *in
@@ -409,6 +502,11 @@ func (in *PravegaSpec) DeepCopyInto(out *PravegaSpec) {
 		*out = new(v1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -422,6 +520,22 @@ func (in *PravegaSpec) DeepCopy() *PravegaSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollbackStatus) DeepCopyInto(out *RollbackStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollbackStatus.
+func (in *RollbackStatus) DeepCopy() *RollbackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RollbackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Tier2Spec) DeepCopyInto(out *Tier2Spec) {
 	*out = *in
@@ -452,3 +566,72 @@ func (in *Tier2Spec) DeepCopy() *Tier2Spec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradePolicySpec) DeepCopyInto(out *UpgradePolicySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradePolicySpec.
+func (in *UpgradePolicySpec) DeepCopy() *UpgradePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZookeeperCleanupStatus) DeepCopyInto(out *ZookeeperCleanupStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperCleanupStatus.
+func (in *ZookeeperCleanupStatus) DeepCopy() *ZookeeperCleanupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ZookeeperCleanupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZookeeperClusterReference) DeepCopyInto(out *ZookeeperClusterReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperClusterReference.
+func (in *ZookeeperClusterReference) DeepCopy() *ZookeeperClusterReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ZookeeperClusterReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZookeeperSpec) DeepCopyInto(out *ZookeeperSpec) {
+	*out = *in
+	if in.ClusterRef != nil {
+		in, out := &in.ClusterRef, &out.ClusterRef
+		*out = new(ZookeeperClusterReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperSpec.
+func (in *ZookeeperSpec) DeepCopy() *ZookeeperSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZookeeperSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,71 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// BookkeeperSpec defines the configuration of BookKeeper
+type BookkeeperSpec struct {
+	// Image defines the BookKeeper Docker image to use.
+	// By default, "pravega/bookkeeper" will be used.
+	Image *ImageSpec `json:"image,omitempty"`
+
+	// Version is the BookKeeper version, i.e. the version of the Pravega
+	// release the BookKeeper image comes from. If version is not set, the
+	// cluster version is used.
+	Version string `json:"version,omitempty"`
+
+	// Replicas defines the number of BookKeeper replicas.
+	// Minimum is 3. Defaults to 3.
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Storage configures the storage for BookKeeper
+	Storage *BookkeeperStorageSpec `json:"storage,omitempty"`
+
+	// AutoRecovery indicates whether or not BookKeeper auto recovery is enabled.
+	// Defaults to true.
+	AutoRecovery *bool `json:"autoRecovery,omitempty"`
+
+	// ServiceAccountName configures the service account used on BookKeeper instances
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// ImagePullSecrets are the secrets used to pull the BookKeeper image, in
+	// addition to the image pull secrets of the cluster
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Resources specifies the request and limit of resources that bookie can have.
+	// Resources includes CPU and memory resources
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Options is the Bookkeeper configuration that is to override the bk_server.conf
+	// in bookkeeper. Some examples can be found here
+	// https://github.com/apache/bookkeeper/blob/master/docker/README.md
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// BookkeeperStorageSpec is the configuration of the volumes used in BookKeeper
+type BookkeeperStorageSpec struct {
+	// LedgerVolumeClaimTemplate is the spec to describe PVC for the BookKeeper ledger
+	LedgerVolumeClaimTemplate *v1.PersistentVolumeClaimSpec `json:"ledgerVolumeClaimTemplate,omitempty"`
+
+	// JournalVolumeClaimTemplate is the spec to describe PVC for the BookKeeper journal
+	JournalVolumeClaimTemplate *v1.PersistentVolumeClaimSpec `json:"journalVolumeClaimTemplate,omitempty"`
+
+	// IndexVolumeClaimTemplate is the spec to describe PVC for the BookKeeper index
+	IndexVolumeClaimTemplate *v1.PersistentVolumeClaimSpec `json:"indexVolumeClaimTemplate,omitempty"`
+
+	// ReclaimPolicy defines whether the ledger, journal and index volumes are
+	// deleted when BookKeeper is scaled down or the cluster is deleted.
+	// Options are "Retain" and "Delete". Defaults to "Delete".
+	ReclaimPolicy ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+}
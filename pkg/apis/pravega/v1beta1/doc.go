@@ -0,0 +1,14 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+// Package v1beta1 contains API Schema definitions for the pravega v1beta1 API group
+// +k8s:deepcopy-gen=package,register
+// +groupName=pravega.pravega.io
+package v1beta1
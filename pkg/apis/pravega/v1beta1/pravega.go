@@ -0,0 +1,127 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// PravegaSpec defines the configuration of Pravega. Unlike in v1alpha1, the
+// options and the external access are set for each component.
+type PravegaSpec struct {
+	// ControllerReplicas defines the number of Controller replicas.
+	// Defaults to 1.
+	ControllerReplicas int32 `json:"controllerReplicas,omitempty"`
+
+	// SegmentStoreReplicas defines the number of Segment Store replicas.
+	// Defaults to 1.
+	SegmentStoreReplicas int32 `json:"segmentStoreReplicas,omitempty"`
+
+	// DebugLogging indicates whether or not debug level logging is enabled.
+	// Defaults to false.
+	DebugLogging bool `json:"debugLogging,omitempty"`
+
+	// Image defines the Pravega Docker image to use.
+	// By default, "pravega/pravega" will be used.
+	Image *ImageSpec `json:"image,omitempty"`
+
+	// ControllerOptions is the Pravega configuration that is passed to the
+	// controllers as JAVA_OPTS. See the following file for a complete list of
+	// options:
+	// https://github.com/pravega/pravega/blob/master/config/config.properties
+	ControllerOptions map[string]string `json:"controllerOptions,omitempty"`
+
+	// SegmentStoreOptions is the Pravega configuration that is passed to the
+	// segment stores as JAVA_OPTS
+	SegmentStoreOptions map[string]string `json:"segmentStoreOptions,omitempty"`
+
+	// ControllerExternalAccess specifies whether or not to allow external
+	// access to the controllers and the service type to use to achieve it.
+	// By default, external access is not enabled
+	ControllerExternalAccess *ExternalAccess `json:"controllerExternalAccess,omitempty"`
+
+	// SegmentStoreExternalAccess specifies whether or not to allow external
+	// access to the segment stores and the service type to use to achieve
+	// it. By default, external access is not enabled
+	SegmentStoreExternalAccess *ExternalAccess `json:"segmentStoreExternalAccess,omitempty"`
+
+	// CacheVolumeClaimTemplate is the spec to describe PVC for the Pravega cache.
+	// This field is optional. If no PVC spec, stateful containers will use
+	// emptyDir as volume
+	CacheVolumeClaimTemplate *v1.PersistentVolumeClaimSpec `json:"cacheVolumeClaimTemplate,omitempty"`
+
+	// CacheVolumeReclaimPolicy defines whether the cache volumes are deleted
+	// when the Segment Store is scaled down or the cluster is deleted.
+	// Options are "Retain" and "Delete". Defaults to "Delete".
+	CacheVolumeReclaimPolicy ReclaimPolicy `json:"cacheVolumeReclaimPolicy,omitempty"`
+
+	// Tier2 is the configuration of Pravega's tier 2 storage. If no configuration
+	// is provided, it will assume that a PersistentVolumeClaim called "pravega-tier2"
+	// is present and it will use it as Tier 2
+	Tier2 *Tier2Spec `json:"tier2,omitempty"`
+
+	// ControllerServiceAccountName configures the service account used on controller instances.
+	// If not specified, Kubernetes will automatically assign the default service account in the namespace
+	ControllerServiceAccountName string `json:"controllerServiceAccountName,omitempty"`
+
+	// SegmentStoreServiceAccountName configures the service account used on segment store instances.
+	// If not specified, Kubernetes will automatically assign the default service account in the namespace
+	SegmentStoreServiceAccountName string `json:"segmentStoreServiceAccountName,omitempty"`
+
+	// ImagePullSecrets are the secrets used to pull the Pravega image, in
+	// addition to the image pull secrets of the cluster
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// ControllerResources specifies the request and limit of resources that controller can have.
+	// ControllerResources includes CPU and memory resources
+	ControllerResources *v1.ResourceRequirements `json:"controllerResources,omitempty"`
+
+	// SegmentStoreResources specifies the request and limit of resources that segmentStore can have.
+	// SegmentStoreResources includes CPU and memory resources
+	SegmentStoreResources *v1.ResourceRequirements `json:"segmentStoreResources,omitempty"`
+}
+
+// Tier2Spec configures the Tier 2 storage type to use with Pravega.
+// If not specified, Tier 2 will be configured in filesystem mode and will try
+// to use a PersistentVolumeClaim with the name "pravega-tier2"
+type Tier2Spec struct {
+	// FileSystem is used to configure a pre-created Persistent Volume Claim
+	// as Tier 2 backend.
+	// It is default Tier 2 mode.
+	FileSystem *FileSystemSpec `json:"filesystem,omitempty"`
+
+	// Ecs is used to configure a Dell EMC ECS system as a Tier 2 backend
+	Ecs *ECSSpec `json:"ecs,omitempty"`
+
+	// Hdfs is used to configure an HDFS system as a Tier 2 backend
+	Hdfs *HDFSSpec `json:"hdfs,omitempty"`
+}
+
+// FileSystemSpec contains the reference to a PVC.
+type FileSystemSpec struct {
+	PersistentVolumeClaim *v1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim"`
+}
+
+// ECSSpec contains the connection details to a Dell EMC ECS system
+type ECSSpec struct {
+	Uri         string `json:"uri"`
+	Bucket      string `json:"bucket"`
+	Root        string `json:"root"`
+	Namespace   string `json:"namespace"`
+	Credentials string `json:"credentials"`
+}
+
+// HDFSSpec contains the connection details to an HDFS system
+type HDFSSpec struct {
+	Uri               string `json:"uri"`
+	Root              string `json:"root"`
+	ReplicationFactor int32  `json:"replicationFactor"`
+}
@@ -0,0 +1,215 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package v1beta1
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func init() {
+	SchemeBuilder.Register(&PravegaCluster{}, &PravegaClusterList{})
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PravegaClusterList contains a list of PravegaCluster
+type PravegaClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PravegaCluster `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PravegaCluster is the Schema for the pravegaclusters API
+// +k8s:openapi-gen=true
+type PravegaCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterSpec   `json:"spec,omitempty"`
+	Status ClusterStatus `json:"status,omitempty"`
+}
+
+// ClusterSpec defines the desired state of PravegaCluster. It has the same
+// fields as in v1alpha1, except for the external access, which is configured
+// for each component in PravegaSpec.
+type ClusterSpec struct {
+	// ZookeeperUri specifies the hostname/IP address and port in the format
+	// "hostname:port".
+	// By default, the value "zk-client:2181" is used, that corresponds to the
+	// default Zookeeper service created by the Pravega Zookkeeper operator
+	// available at: https://github.com/pravega/zookeeper-operator
+	ZookeeperUri string `json:"zookeeperUri,omitempty"`
+
+	// Zookeeper configures authentication and TLS for the connections to
+	// ZooKeeper, both from the operator and from the Pravega components
+	Zookeeper *ZookeeperSpec `json:"zookeeper,omitempty"`
+
+	// TLS is the Pravega security configuration that is passed to the Pravega processes.
+	// See the following file for a complete list of options:
+	// https://github.com/pravega/pravega/blob/master/documentation/src/docs/security/pravega-security-configurations.md
+	TLS *TLSPolicy `json:"tls,omitempty"`
+
+	// Authentication can be enabled for authorizing all communication from clients to controller and segment store
+	// See the following file for a complete list of options:
+	// https://github.com/pravega/pravega/blob/master/documentation/src/docs/security/pravega-security-configurations.md
+	Authentication *AuthenticationParameters `json:"authentication,omitempty"`
+
+	// Version is the expected version of the Pravega cluster.
+	// The pravega-operator will eventually make the Pravega cluster version
+	// equal to the expected version.
+	//
+	// The version must follow the [semver]( http://semver.org) format, for example "3.2.13".
+	// Only Pravega released versions are supported: https://github.com/pravega/pravega/releases
+	//
+	// If version is not set, default is "0.4.0".
+	Version string `json:"version,omitempty"`
+
+	// UpgradePolicy defines how the operator handles version upgrades
+	UpgradePolicy *UpgradePolicySpec `json:"upgradePolicy,omitempty"`
+
+	// ImagePullSecrets are the secrets used to pull the images of all the
+	// components from private registries
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Bookkeeper configuration
+	Bookkeeper *BookkeeperSpec `json:"bookkeeper,omitempty"`
+
+	// Pravega configuration
+	Pravega *PravegaSpec `json:"pravega,omitempty"`
+}
+
+// ZookeeperSpec defines how to connect to ZooKeeper
+type ZookeeperSpec struct {
+	// Secret is the name of the secret holding the ZooKeeper credentials and
+	// TLS material, in the namespace of the cluster
+	Secret string `json:"secret,omitempty"`
+
+	// Auth specifies whether or not to authenticate to ZooKeeper with the
+	// credentials of the secret. By default, authentication is not enabled
+	Auth bool `json:"auth,omitempty"`
+
+	// TLS specifies whether or not to connect to ZooKeeper with TLS, using the
+	// certificates of the secret. By default, TLS is not enabled
+	TLS bool `json:"tls,omitempty"`
+
+	// ClusterRef references a ZookeeperCluster managed by the zookeeper
+	// operator. When set, the cluster uses the client address reported by
+	// the ZookeeperCluster instead of ZookeeperUri
+	ClusterRef *ZookeeperClusterReference `json:"clusterRef,omitempty"`
+}
+
+// ZookeeperClusterReference references a ZookeeperCluster object
+type ZookeeperClusterReference struct {
+	// Name of the ZookeeperCluster
+	Name string `json:"name"`
+
+	// Namespace of the ZookeeperCluster. Defaults to the namespace of the
+	// Pravega cluster
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ExternalAccess defines the configuration of the external access to a
+// component
+type ExternalAccess struct {
+	// Enabled specifies whether or not external access is enabled
+	// By default, external access is not enabled
+	Enabled bool `json:"enabled"`
+
+	// Type specifies the service type to achieve external access.
+	// Options are "LoadBalancer" and "NodePort".
+	// By default, if external access is enabled, it will use "LoadBalancer"
+	Type v1.ServiceType `json:"type,omitempty"`
+
+	// Domain Name to be used for External Access
+	// This value is ignored if External Access is disabled
+	DomainName string `json:"domainName,omitempty"`
+}
+
+type TLSPolicy struct {
+	// Static TLS means keys/certs are generated by the user and passed to an operator.
+	Static *StaticTLS `json:"static,omitempty"`
+}
+
+type StaticTLS struct {
+	ControllerSecret   string `json:"controllerSecret,omitempty"`
+	SegmentStoreSecret string `json:"segmentStoreSecret,omitempty"`
+}
+
+type AuthenticationParameters struct {
+	// Enabled specifies whether or not authentication is enabled
+	// By default, authentication is not enabled
+	Enabled bool `json:"enabled"`
+
+	// name of Secret containing Password based Authentication Parameters like username, password and acl
+	// optional - used only by PasswordAuthHandler for authentication
+	PasswordAuthSecret string `json:"passwordAuthSecret,omitempty"`
+}
+
+// UpgradePolicySpec defines how the operator handles version upgrades
+type UpgradePolicySpec struct {
+	// Rollback specifies whether a failed upgrade is rolled back to the
+	// previous version. By default, failed upgrades are not rolled back
+	Rollback bool `json:"rollback,omitempty"`
+
+	// ControllerProgressDeadlineSeconds is the time in seconds the controller
+	// deployment may take to make progress during an upgrade or a config
+	// rollout before it is considered failed.
+	// Default is 600
+	ControllerProgressDeadlineSeconds int32 `json:"controllerProgressDeadlineSeconds,omitempty"`
+
+	// SegmentStoreProgressDeadlineSeconds is the time in seconds the segment
+	// stores may take to make progress during an upgrade or a config rollout
+	// before it is considered failed.
+	// Default is 600
+	SegmentStoreProgressDeadlineSeconds int32 `json:"segmentStoreProgressDeadlineSeconds,omitempty"`
+
+	// BookkeeperProgressDeadlineSeconds is the time in seconds the bookies may
+	// take to make progress during an upgrade or a config rollout before it
+	// is considered failed.
+	// Default is 600
+	BookkeeperProgressDeadlineSeconds int32 `json:"bookkeeperProgressDeadlineSeconds,omitempty"`
+
+	// PodReadyTimeoutSeconds is the time in seconds an updated pod may take
+	// to become ready before the upgrade or the config rollout is considered
+	// failed. By default, pods have no readiness timeout
+	PodReadyTimeoutSeconds int32 `json:"podReadyTimeoutSeconds,omitempty"`
+
+	// SegmentStoreCanaryPods is the number of segment store pods upgraded
+	// first. By default, there are no canary pods
+	SegmentStoreCanaryPods int32 `json:"segmentStoreCanaryPods,omitempty"`
+
+	// BookkeeperCanaryPods is the number of bookie pods upgraded first.
+	// By default, there are no canary pods
+	BookkeeperCanaryPods int32 `json:"bookkeeperCanaryPods,omitempty"`
+}
+
+// ReclaimPolicy defines what happens to the persistent volume claims of a
+// component when the component is scaled down or the cluster is deleted
+type ReclaimPolicy string
+
+// ImageSpec defines the fields needed for a Docker repository image
+type ImageSpec struct {
+	Repository string `json:"repository,omitempty"`
+
+	// Tag is the tag of the image. By default, the version of the component
+	// is used as tag. Unlike in v1alpha1, the tag is never used as cluster
+	// version.
+	Tag string `json:"tag,omitempty"`
+
+	// Digest pins the image to a digest, e.g. "sha256:4b1c...". The digest
+	// takes precedence over the tag.
+	Digest string `json:"digest,omitempty"`
+
+	PullPolicy v1.PullPolicy `json:"pullPolicy,omitempty"`
+}
@@ -0,0 +1,206 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+type ClusterConditionType string
+
+// ClusterStatus defines the observed state of PravegaCluster
+type ClusterStatus struct {
+	// Conditions list all the applied conditions
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+
+	// CurrentVersion is the current cluster version
+	CurrentVersion string `json:"currentVersion,omitempty"`
+
+	// TargetVersion is the version the cluster upgrading to.
+	// If the cluster is not upgrading, TargetVersion is empty.
+	TargetVersion string `json:"targetVersion,omitempty"`
+
+	// CurrentBookkeeperVersion is the current BookKeeper version
+	CurrentBookkeeperVersion string `json:"currentBookkeeperVersion,omitempty"`
+
+	// TargetBookkeeperVersion is the version BookKeeper is upgrading to.
+	// If the cluster is not upgrading, TargetBookkeeperVersion is empty.
+	TargetBookkeeperVersion string `json:"targetBookkeeperVersion,omitempty"`
+
+	// Replicas is the number of desired replicas in the cluster
+	Replicas int32 `json:"replicas"`
+
+	// CurrentReplicas is the number of current replicas in the cluster
+	CurrentReplicas int32 `json:"currentReplicas"`
+
+	// ReadyReplicas is the number of ready replicas in the cluster
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// Members is the Pravega members in the cluster
+	Members MembersStatus `json:"members"`
+
+	// ObservedGeneration is the most recent generation of the cluster spec
+	// reflected by this status
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ZookeeperUri is the client address reported by the ZookeeperCluster
+	// referenced in the spec, which the cluster uses instead of the ZooKeeper
+	// URI of the spec
+	ZookeeperUri string `json:"zookeeperUri,omitempty"`
+
+	// Controller is the status of the Pravega controllers
+	Controller ComponentStatus `json:"controller"`
+
+	// SegmentStore is the status of the Pravega segment stores
+	SegmentStore ComponentStatus `json:"segmentStore"`
+
+	// Bookkeeper is the status of the bookies
+	Bookkeeper ComponentStatus `json:"bookkeeper"`
+
+	// ZookeeperCleanup is the progress of the deletion of the cluster
+	// metadata from ZooKeeper. It is only set once the cluster is deleted.
+	ZookeeperCleanup *ZookeeperCleanupStatus `json:"zookeeperCleanup,omitempty"`
+
+	// Rollback is the progress of the rollback of the last failed upgrade.
+	// It is only set if the upgrade policy enables rollbacks, and it is
+	// replaced when another upgrade fails.
+	Rollback *RollbackStatus `json:"rollback,omitempty"`
+
+	// Canary is the progress of the canary pods of the component being
+	// upgraded. It is only set during an upgrade with canary pods.
+	Canary *CanaryStatus `json:"canary,omitempty"`
+}
+
+// CanaryStatus is the progress of the canary pods of a component during an
+// upgrade
+type CanaryStatus struct {
+	// Component is the component being upgraded, i.e. bookie or
+	// pravega-segmentstore
+	Component string `json:"component"`
+
+	// Version is the version the component is upgraded to
+	Version string `json:"version"`
+
+	// Phase is the current phase of the canary pods, one of Upgrading,
+	// WaitingForApproval or Approved
+	Phase string `json:"phase"`
+
+	// Pods is the list of pods of the component running the new version
+	Pods []string `json:"pods,omitempty"`
+
+	// WaitingSince is the time the upgrade started waiting for an approval
+	WaitingSince string `json:"waitingSince,omitempty"`
+}
+
+// RollbackStatus is the progress of the rollback of a failed upgrade
+type RollbackStatus struct {
+	// Phase is the current phase of the rollback, one of InProgress,
+	// Completed or Failed. A Failed rollback needs manual intervention.
+	Phase string `json:"phase"`
+
+	// FromVersion is the version of the failed upgrade
+	FromVersion string `json:"fromVersion"`
+
+	// ToVersion is the version the cluster is rolled back to
+	ToVersion string `json:"toVersion"`
+
+	// FromBookkeeperVersion is the BookKeeper version of the failed upgrade
+	FromBookkeeperVersion string `json:"fromBookkeeperVersion,omitempty"`
+
+	// ToBookkeeperVersion is the version BookKeeper is rolled back to
+	ToBookkeeperVersion string `json:"toBookkeeperVersion,omitempty"`
+
+	// Reason is the error that failed the upgrade
+	Reason string `json:"reason,omitempty"`
+
+	// A human readable message indicating why the rollback failed
+	Message string `json:"message,omitempty"`
+
+	// StartTime is the time the rollback started
+	StartTime string `json:"startTime,omitempty"`
+
+	// CompletionTime is the time the rollback completed or failed
+	CompletionTime string `json:"completionTime,omitempty"`
+}
+
+// ZookeeperCleanupStatus is the progress of the deletion of the cluster
+// metadata from ZooKeeper
+type ZookeeperCleanupStatus struct {
+	// Phase is the current phase of the cleanup, one of TerminatingPods,
+	// DeletingZnodes or Failed. The cleanup is Failed if the metadata could
+	// not be deleted before the timeout. It is still retried in that case.
+	Phase string `json:"phase"`
+
+	// A human readable message indicating details about the current phase
+	Message string `json:"message,omitempty"`
+
+	// StartTime is the time the cleanup started
+	StartTime string `json:"startTime,omitempty"`
+
+	// Attempts is the number of attempts to delete the metadata
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// LastAttemptTime is the time of the last attempt to delete the metadata
+	LastAttemptTime string `json:"lastAttemptTime,omitempty"`
+}
+
+// ComponentStatus is the observed state of one of the components of the
+// cluster, i.e. controller, segment store or bookkeeper
+type ComponentStatus struct {
+	// Replicas is the number of desired replicas of the component
+	Replicas int32 `json:"replicas"`
+
+	// CurrentReplicas is the number of current replicas of the component
+	CurrentReplicas int32 `json:"currentReplicas"`
+
+	// ReadyReplicas is the number of ready replicas of the component
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// UpdatedReplicas is the number of replicas running the latest pod
+	// template of the component
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+
+	// Versions is the Pravega version each pod of the component is running,
+	// indexed by pod name
+	Versions map[string]string `json:"versions,omitempty"`
+
+	// Members is the members of the component
+	Members MembersStatus `json:"members"`
+}
+
+// MembersStatus is the status of the members of the cluster with both
+// ready and unready node membership lists
+type MembersStatus struct {
+	Ready   []string `json:"ready"`
+	Unready []string `json:"unready"`
+}
+
+// ClusterCondition shows the current condition of a Pravega cluster.
+// Comply with k8s API conventions
+type ClusterCondition struct {
+	// Type of Pravega cluster condition.
+	Type ClusterConditionType `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+
+	// A human readable message indicating details about the transition.
+	Message string `json:"message,omitempty"`
+
+	// The last time this condition was updated.
+	LastUpdateTime string `json:"lastUpdateTime,omitempty"`
+
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+}
@@ -10,9 +10,58 @@
 
 package config
 
+import "time"
+
 // TestMode enables test mode in the operator and applies
 // the following changes:
 // - Disables BookKeeper minimum number of replicas
 // - Disables Pravega Controller minimum number of replicas
 // - Disables Segment Store minimum number of replicas
 var TestMode bool
+
+// ResyncPeriod is the delay between periodic reconciliations of a Pravega
+// cluster. Changes to the cluster and to the resources it owns are reconciled
+// as soon as they happen, so the periodic resync is only a safety net.
+var ResyncPeriod = 30 * time.Second
+
+// ZookeeperCleanupTimeout is how long the operator keeps retrying to delete
+// the metadata of a deleted Pravega cluster from ZooKeeper before giving up.
+var ZookeeperCleanupTimeout = 10 * time.Minute
+
+// MaxConcurrentReconciles is the maximum number of Pravega clusters that are
+// reconciled in parallel.
+var MaxConcurrentReconciles = 1
+
+// WatchNamespaces are the namespaces watched by the operator. A single empty
+// namespace stands for all namespaces.
+var WatchNamespaces []string
+
+// ValidateSpec makes the reconciler validate the spec of the Pravega
+// clusters, as the validating webhook does. A cluster that is not valid is not
+// reconciled until its spec is fixed. It is enabled when the webhook is
+// disabled.
+var ValidateSpec bool
+
+// VersionMatrixFile is the file the supported versions and upgrade paths are
+// loaded from. The file is reloaded when it changes. The compiled-in version
+// matrix is used if it is empty or cannot be loaded.
+var VersionMatrixFile string
+
+// VersionMatrixPollInterval is the delay between checks for changes to the
+// version matrix file.
+var VersionMatrixPollInterval = 10 * time.Second
+
+// WebhookCertDir is the directory the certificate of the webhook is loaded
+// from, e.g. a mounted secret issued by cert-manager. The certificate is
+// reloaded when it changes, and its CA certificate is set as CA bundle of the
+// webhook configurations. The webhook generates its own certificate if it is
+// empty.
+var WebhookCertDir string
+
+// WebhookCertPollInterval is the delay between checks for changes to the
+// certificate of the webhook.
+var WebhookCertPollInterval = 10 * time.Second
+
+// WebhookCertExpiryWarning is how long before the expiry of the certificate
+// of the webhook the operator starts reporting it.
+var WebhookCertExpiryWarning = 7 * 24 * time.Hour
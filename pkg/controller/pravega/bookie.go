@@ -81,13 +81,14 @@ func MakeBookieStatefulSet(pravegaCluster *v1alpha1.PravegaCluster) *appsv1.Stat
 }
 
 func MakeBookiePodTemplate(p *v1alpha1.PravegaCluster) corev1.PodTemplateSpec {
-	return corev1.PodTemplateSpec{
+	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      util.LabelsForBookie(p),
-			Annotations: map[string]string{"pravega.version": p.Spec.Version},
+			Annotations: map[string]string{"pravega.version": p.BookkeeperVersion()},
 		},
 		Spec: *makeBookiePodSpec(p),
 	}
+	return withConfigHash(template, MakeBookieConfigMap(p).Data)
 }
 
 func makeBookiePodSpec(p *v1alpha1.PravegaCluster) *corev1.PodSpec {
@@ -173,6 +174,11 @@ func makeBookiePodSpec(p *v1alpha1.PravegaCluster) *corev1.PodSpec {
 		podSpec.ServiceAccountName = p.Spec.Bookkeeper.ServiceAccountName
 	}
 
+	podSpec.ImagePullSecrets = util.ImagePullSecrets(p, p.Spec.Bookkeeper.ImagePullSecrets)
+
+	podSpec.Containers[0].Env = bookieZookeeperEnv(p)
+	configureZookeeperSecret(podSpec, p)
+
 	return podSpec
 }
 
@@ -209,7 +215,7 @@ func MakeBookieConfigMap(pravegaCluster *v1alpha1.PravegaCluster) *corev1.Config
 		"-XX:HeapDumpPath=" + heapDumpDir,
 	}
 
-	if match, _ := util.CompareVersions(pravegaCluster.Spec.Version, "0.4.0", ">="); match {
+	if match, _ := util.CompareVersions(pravegaCluster.BookkeeperVersion(), "0.4.0", ">="); match {
 		// Pravega < 0.4 uses a Java version that does not support the options below
 		memoryOpts = append(memoryOpts,
 			"-XX:+UnlockExperimentalVMOptions",
@@ -244,18 +250,22 @@ func MakeBookieConfigMap(pravegaCluster *v1alpha1.PravegaCluster) *corev1.Config
 		"BOOKIE_MEM_OPTS":          strings.Join(memoryOpts, " "),
 		"BOOKIE_GC_OPTS":           strings.Join(gcOpts, " "),
 		"BOOKIE_GC_LOGGING_OPTS":   strings.Join(gcLoggingOpts, " "),
-		"ZK_URL":                   pravegaCluster.Spec.ZookeeperUri,
+		"ZK_URL":                   pravegaCluster.ZookeeperAddress(),
 		"BK_useHostNameAsBookieID": "true",
 		"PRAVEGA_CLUSTER_NAME":     pravegaCluster.ObjectMeta.Name,
-		"WAIT_FOR":                 pravegaCluster.Spec.ZookeeperUri,
+		"WAIT_FOR":                 pravegaCluster.ZookeeperAddress(),
 	}
 
-	if match, _ := util.CompareVersions(pravegaCluster.Spec.Version, "0.5.0", "<"); match {
+	if match, _ := util.CompareVersions(pravegaCluster.BookkeeperVersion(), "0.5.0", "<"); match {
 		// Pravega < 0.5 uses BookKeeper 4.5, which does not play well
 		// with hostnames that resolve to different IP addresses over time
 		configData["BK_useHostNameAsBookieID"] = "false"
 	}
 
+	if opts := bookieZookeeperOpts(pravegaCluster); len(opts) > 0 {
+		configData[bookieExtraOptsEnv] = strings.Join(opts, " ")
+	}
+
 	if *pravegaCluster.Spec.Bookkeeper.AutoRecovery {
 		configData["BK_AUTORECOVERY"] = "true"
 		// Wait one minute before starting autorecovery. This will give
@@ -22,5 +22,7 @@ const (
 	heapDumpDir            = "/tmp/dumpfile/heap"
 	authVolumeName         = "auth-passwd-secret"
 	authMountDir           = "/etc/auth-passwd-volume"
+	zkSecretVolumeName     = "zookeeper-secret"
+	zkSecretMountDir       = "/etc/zookeeper-secret"
 	defaultTokenSigningKey = "secret"
 )
@@ -0,0 +1,43 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravega
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/pravega/pravega-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// withConfigHash stamps the pod template with a hash of the rendered config
+// map data and of the pod template itself. Pods whose hash differs from the
+// one in the template of their owner need to be restarted to pick up changes.
+func withConfigHash(template corev1.PodTemplateSpec, configData map[string]string) corev1.PodTemplateSpec {
+	template.Annotations[util.ConfigHashAnnotation] = configHash(template, configData)
+	return template
+}
+
+func configHash(template corev1.PodTemplateSpec, configData map[string]string) string {
+	template = *template.DeepCopy()
+	delete(template.Annotations, util.ConfigHashAnnotation)
+
+	data, _ := json.Marshal(struct {
+		Config   map[string]string      `json:"config"`
+		Template corev1.PodTemplateSpec `json:"template"`
+	}{
+		Config:   configData,
+		Template: template,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
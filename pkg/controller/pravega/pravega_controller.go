@@ -25,7 +25,6 @@ import (
 
 func MakeControllerDeployment(p *api.PravegaCluster) *appsv1.Deployment {
 	zero := int32(0)
-	timeout := int32(600)
 	return &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Deployment",
@@ -37,9 +36,10 @@ func MakeControllerDeployment(p *api.PravegaCluster) *appsv1.Deployment {
 			Labels:    util.LabelsForController(p),
 		},
 		Spec: appsv1.DeploymentSpec{
-			ProgressDeadlineSeconds: &timeout,
+			ProgressDeadlineSeconds: &p.Spec.UpgradePolicy.ControllerProgressDeadlineSeconds,
 			Replicas:                &p.Spec.Pravega.ControllerReplicas,
 			RevisionHistoryLimit:    &zero,
+			Strategy:                makeControllerDeploymentStrategy(),
 			Template:                MakeControllerPodTemplate(p),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: util.LabelsForController(p),
@@ -48,14 +48,30 @@ func MakeControllerDeployment(p *api.PravegaCluster) *appsv1.Deployment {
 	}
 }
 
+// makeControllerDeploymentStrategy replaces the controller pods one at a time
+// when the pod template changes, e.g. to apply a config change, and starts
+// each new pod before stopping an old one
+func makeControllerDeploymentStrategy() appsv1.DeploymentStrategy {
+	maxUnavailable := intstr.FromInt(0)
+	maxSurge := intstr.FromInt(1)
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: &maxUnavailable,
+			MaxSurge:       &maxSurge,
+		},
+	}
+}
+
 func MakeControllerPodTemplate(p *api.PravegaCluster) corev1.PodTemplateSpec {
-	return corev1.PodTemplateSpec{
+	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      util.LabelsForController(p),
 			Annotations: map[string]string{"pravega.version": p.Spec.Version},
 		},
 		Spec: *makeControllerPodSpec(p),
 	}
+	return withConfigHash(template, MakeControllerConfigMap(p).Data)
 }
 
 func makeControllerPodSpec(p *api.PravegaCluster) *corev1.PodSpec {
@@ -135,8 +151,11 @@ func makeControllerPodSpec(p *api.PravegaCluster) *corev1.PodSpec {
 		podSpec.ServiceAccountName = p.Spec.Pravega.ControllerServiceAccountName
 	}
 
+	podSpec.ImagePullSecrets = util.ImagePullSecrets(p, p.Spec.Pravega.ImagePullSecrets)
+
 	configureControllerTLSSecrets(podSpec, p)
 	configureAuthSecrets(podSpec, p)
+	configureZookeeperSecret(podSpec, p)
 	return podSpec
 }
 
@@ -191,21 +210,23 @@ func MakeControllerConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
 		)
 	}
 
-	for name, value := range p.Spec.Pravega.Options {
+	javaOpts = append(javaOpts, controllerZookeeperOpts(p)...)
+
+	for name, value := range p.ControllerOptions() {
 		javaOpts = append(javaOpts, fmt.Sprintf("-D%v=%v", name, value))
 	}
 
 	authEnabledStr := fmt.Sprint(p.Spec.Authentication.IsEnabled())
 	configData := map[string]string{
 		"CLUSTER_NAME":           p.Name,
-		"ZK_URL":                 p.Spec.ZookeeperUri,
+		"ZK_URL":                 p.ZookeeperAddress(),
 		"JAVA_OPTS":              strings.Join(javaOpts, " "),
 		"REST_SERVER_PORT":       "10080",
 		"CONTROLLER_SERVER_PORT": "9090",
 		"AUTHORIZATION_ENABLED":  authEnabledStr,
 		"TOKEN_SIGNING_KEY":      defaultTokenSigningKey,
 		"TLS_ENABLED":            "false",
-		"WAIT_FOR":               p.Spec.ZookeeperUri,
+		"WAIT_FOR":               p.ZookeeperAddress(),
 	}
 
 	configMap := &corev1.ConfigMap{
@@ -225,8 +246,8 @@ func MakeControllerConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
 
 func MakeControllerService(p *api.PravegaCluster) *corev1.Service {
 	serviceType := corev1.ServiceTypeClusterIP
-	if p.Spec.ExternalAccess.Enabled {
-		serviceType = p.Spec.ExternalAccess.Type
+	if externalAccess := p.ControllerExternalAccess(); externalAccess.Enabled {
+		serviceType = externalAccess.Type
 	}
 	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
@@ -56,13 +56,14 @@ func MakeSegmentStoreStatefulSet(pravegaCluster *api.PravegaCluster) *appsv1.Sta
 }
 
 func MakeSegmentStorePodTemplate(p *api.PravegaCluster) corev1.PodTemplateSpec {
-	return corev1.PodTemplateSpec{
+	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:      util.LabelsForSegmentStore(p),
 			Annotations: map[string]string{"pravega.version": p.Spec.Version},
 		},
 		Spec: makeSegmentstorePodSpec(p),
 	}
+	return withConfigHash(template, MakeSegmentstoreConfigMap(p).Data)
 }
 
 func makeSegmentstorePodSpec(p *api.PravegaCluster) corev1.PodSpec {
@@ -151,8 +152,12 @@ func makeSegmentstorePodSpec(p *api.PravegaCluster) corev1.PodSpec {
 		podSpec.ServiceAccountName = p.Spec.Pravega.SegmentStoreServiceAccountName
 	}
 
+	podSpec.ImagePullSecrets = util.ImagePullSecrets(p, p.Spec.Pravega.ImagePullSecrets)
+
 	configureSegmentstoreTLSSecret(&podSpec, p)
 
+	configureZookeeperSecret(&podSpec, p)
+
 	configureTier2Filesystem(&podSpec, p.Spec.Pravega)
 
 	return podSpec
@@ -177,7 +182,9 @@ func MakeSegmentstoreConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
 		)
 	}
 
-	for name, value := range p.Spec.Pravega.Options {
+	javaOpts = append(javaOpts, segmentStoreZookeeperOpts(p)...)
+
+	for name, value := range p.SegmentStoreOptions() {
 		javaOpts = append(javaOpts, fmt.Sprintf("-D%v=%v", name, value))
 	}
 
@@ -185,7 +192,7 @@ func MakeSegmentstoreConfigMap(p *api.PravegaCluster) *corev1.ConfigMap {
 	configData := map[string]string{
 		"AUTHORIZATION_ENABLED": authEnabledStr,
 		"CLUSTER_NAME":          p.Name,
-		"ZK_URL":                p.Spec.ZookeeperUri,
+		"ZK_URL":                p.ZookeeperAddress(),
 		"JAVA_OPTS":             strings.Join(javaOpts, " "),
 		"CONTROLLER_URL":        util.PravegaControllerServiceURL(*p),
 	}
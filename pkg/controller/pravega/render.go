@@ -0,0 +1,43 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravega
+
+import (
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// MakeClusterObjects returns all the objects generated for a Pravega cluster,
+// in the order they are deployed by the operator. The cluster is expected to
+// be defaulted.
+func MakeClusterObjects(p *api.PravegaCluster) []runtime.Object {
+	objects := []runtime.Object{
+		MakeBookieHeadlessService(p),
+		MakeBookiePodDisruptionBudget(p),
+		MakeBookieConfigMap(p),
+		MakeBookieStatefulSet(p),
+		MakeControllerPodDisruptionBudget(p),
+		MakeControllerConfigMap(p),
+		MakeControllerDeployment(p),
+		MakeControllerService(p),
+		MakeSegmentStoreHeadlessService(p),
+	}
+	if p.Spec.ExternalAccess.Enabled {
+		for _, service := range MakeSegmentStoreExternalServices(p) {
+			objects = append(objects, service)
+		}
+	}
+	return append(objects,
+		MakeSegmentstorePodDisruptionBudget(p),
+		MakeSegmentstoreConfigMap(p),
+		MakeSegmentStoreStatefulSet(p),
+	)
+}
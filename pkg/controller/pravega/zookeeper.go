@@ -0,0 +1,115 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravega
+
+import (
+	"fmt"
+	"path"
+
+	api "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// zkTrustStorePasswordEnv is the environment variable holding the password
+	// of the ZooKeeper trust store in the bookie container
+	zkTrustStorePasswordEnv = "ZK_TRUSTSTORE_PASSWORD"
+
+	// bookieExtraOptsEnv is the environment variable with additional JVM
+	// options of the bookies
+	bookieExtraOptsEnv = "BOOKIE_EXTRA_OPTS"
+)
+
+// configureZookeeperSecret mounts the ZooKeeper secret in the pod
+func configureZookeeperSecret(podSpec *corev1.PodSpec, p *api.PravegaCluster) {
+	if p.Spec.Zookeeper == nil || p.Spec.Zookeeper.Secret == "" {
+		return
+	}
+	addSecretVolumeWithMount(podSpec, p, zkSecretVolumeName, p.Spec.Zookeeper.Secret, zkSecretVolumeName, zkSecretMountDir)
+}
+
+// zookeeperJaasOpts returns the JVM options to authenticate to ZooKeeper with
+// the SASL credentials of the secret
+func zookeeperJaasOpts(p *api.PravegaCluster) []string {
+	if !p.Spec.Zookeeper.IsAuthEnabled() {
+		return nil
+	}
+	return []string{
+		"-Djava.security.auth.login.config=" + path.Join(zkSecretMountDir, api.ZookeeperSecretJaasKey),
+		"-Dzookeeper.sasl.client=true",
+	}
+}
+
+func controllerZookeeperOpts(p *api.PravegaCluster) []string {
+	opts := zookeeperJaasOpts(p)
+	if p.Spec.Zookeeper.IsTLSEnabled() {
+		opts = append(opts,
+			"-Dcontroller.zk.secureConnection=true",
+			"-Dcontroller.zk.trustStorePath="+path.Join(zkSecretMountDir, api.ZookeeperSecretTrustStoreKey),
+			"-Dcontroller.zk.trustStorePasswordPath="+path.Join(zkSecretMountDir, api.ZookeeperSecretTrustStorePasswordKey),
+		)
+	}
+	return opts
+}
+
+func segmentStoreZookeeperOpts(p *api.PravegaCluster) []string {
+	opts := zookeeperJaasOpts(p)
+	if p.Spec.Zookeeper.IsTLSEnabled() {
+		opts = append(opts,
+			"-Dpravegaservice.secureZK=true",
+			"-Dpravegaservice.zkTrustStore="+path.Join(zkSecretMountDir, api.ZookeeperSecretTrustStoreKey),
+			"-Dpravegaservice.zkTrustStorePasswordPath="+path.Join(zkSecretMountDir, api.ZookeeperSecretTrustStorePasswordKey),
+		)
+	}
+	return opts
+}
+
+// bookieZookeeperOpts returns the JVM options of the ZooKeeper client of the
+// bookies, passed in BOOKIE_EXTRA_OPTS
+func bookieZookeeperOpts(p *api.PravegaCluster) []string {
+	opts := zookeeperJaasOpts(p)
+	if p.Spec.Zookeeper.IsTLSEnabled() {
+		opts = append(opts,
+			"-Dzookeeper.client.secure=true",
+			"-Dzookeeper.clientCnxnSocket=org.apache.zookeeper.ClientCnxnSocketNetty",
+			"-Dzookeeper.ssl.trustStore.location="+path.Join(zkSecretMountDir, api.ZookeeperSecretTrustStoreKey),
+		)
+	}
+	return opts
+}
+
+// bookieZookeeperEnv returns the environment variables that add the password
+// of the ZooKeeper trust store to the JVM options of the bookies. The password
+// is read from the secret, so that it does not end up in the config map.
+func bookieZookeeperEnv(p *api.PravegaCluster) []corev1.EnvVar {
+	if !p.Spec.Zookeeper.IsTLSEnabled() {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name: zkTrustStorePasswordEnv,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: p.Spec.Zookeeper.Secret,
+					},
+					Key: api.ZookeeperSecretTrustStorePasswordKey,
+				},
+			},
+		},
+		{
+			// Kubernetes expands the variables defined by the config map
+			// and the previous variables
+			Name:  bookieExtraOptsEnv,
+			Value: fmt.Sprintf("$(%s) -Dzookeeper.ssl.trustStore.password=$(%s)", bookieExtraOptsEnv, zkTrustStorePasswordEnv),
+		},
+	}
+}
@@ -0,0 +1,118 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// syncCanaryApproval holds the upgrade of a stateful set component once its
+// canary pods run the target version and are ready, until the upgrade is
+// approved with the UpgradeApprovedAnnotation. It returns true while the
+// upgrade waits for the approval. Rollbacks are never held.
+func (r *ReconcilePravegaCluster) syncCanaryApproval(p *pravegav1alpha1.PravegaCluster, component string,
+	updated []*corev1.Pod, ready bool) (waiting bool, err error) {
+	canaries := canaryPods(p, component)
+	if canaries == 0 || p.Status.IsRollingBack() {
+		return false, nil
+	}
+
+	version := targetVersion(p, component)
+	canary := p.Status.Canary
+	if canary == nil || canary.Component != component || canary.Version != version {
+		canary = &pravegav1alpha1.CanaryStatus{
+			Component: component,
+			Version:   version,
+			Phase:     pravegav1alpha1.CanaryUpgrading,
+		}
+		p.Status.Canary = canary
+	}
+	canary.Pods = podNames(updated)
+
+	if canary.Phase == pravegav1alpha1.CanaryApproved {
+		return false, nil
+	}
+	if !ready || int32(len(updated)) < canaries {
+		// The canary pods are still being upgraded
+		return false, nil
+	}
+
+	logger := r.componentLogger(p, component)
+
+	if p.Annotations[pravegav1alpha1.UpgradeApprovedAnnotation] == version {
+		logger.Infof("upgrade of canary pods %v approved", canary.Pods)
+		r.recorder.Eventf(p, corev1.EventTypeNormal, UpgradeApprovedReason,
+			"Upgrade of the remaining %s pods to version %s approved", component, version)
+		canary.Phase = pravegav1alpha1.CanaryApproved
+		return false, r.removeApproval(p)
+	}
+
+	if canary.Phase != pravegav1alpha1.CanaryWaitingForApproval {
+		logger.Infof("canary pods %v upgraded, waiting for approval", canary.Pods)
+		r.recorder.Eventf(p, corev1.EventTypeNormal, WaitingForApprovalReason,
+			"Upgraded %d %s pods to version %s, set the annotation %s=%s to upgrade the remaining pods",
+			len(updated), component, version, pravegav1alpha1.UpgradeApprovedAnnotation, version)
+		canary.Phase = pravegav1alpha1.CanaryWaitingForApproval
+		canary.WaitingSince = time.Now().Format(time.RFC3339)
+	}
+	p.Status.SetUpgradingConditionTrue(waitingForApprovalReason(component), fmt.Sprint(len(updated)))
+	return true, nil
+}
+
+// removeApproval removes the approval annotation once it is recorded in the
+// canary status, so that the next component needs its own approval
+func (r *ReconcilePravegaCluster) removeApproval(p *pravegav1alpha1.PravegaCluster) error {
+	// need to deep copy the status struct, otherwise it will be overridden
+	// when updating the CR below
+	status := p.Status.DeepCopy()
+
+	delete(p.Annotations, pravegav1alpha1.UpgradeApprovedAnnotation)
+	if err := r.client.Update(context.TODO(), p); err != nil {
+		return fmt.Errorf("failed to remove the approval annotation (%s): %v", p.Name, err)
+	}
+
+	p.Status = *status
+	return nil
+}
+
+// canaryPods returns the number of canary pods of a component, as set in the
+// upgrade policy
+func canaryPods(p *pravegav1alpha1.PravegaCluster, component string) int32 {
+	switch component {
+	case segmentStoreComponent:
+		return p.Spec.UpgradePolicy.SegmentStoreCanaryPods
+	case bookieComponent:
+		return p.Spec.UpgradePolicy.BookkeeperCanaryPods
+	}
+	return 0
+}
+
+func waitingForApprovalReason(component string) string {
+	if component == segmentStoreComponent {
+		return pravegav1alpha1.WaitingForSegmentstoreApprovalReason
+	}
+	return pravegav1alpha1.WaitingForBookkeeperApprovalReason
+}
+
+func podNames(pods []*corev1.Pod) []string {
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,110 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Upgrade canary pods", func() {
+	var c *testCluster
+
+	approve := func(version string) {
+		foundPravega := c.get()
+		foundPravega.Annotations = map[string]string{v1alpha1.UpgradeApprovedAnnotation: version}
+		Ω(c.client.Update(context.TODO(), foundPravega)).Should(Succeed())
+		c.reconcile(1)
+	}
+
+	BeforeEach(func() {
+		p := newTestCluster()
+		p.Spec.UpgradePolicy.BookkeeperCanaryPods = 1
+		c = deployTestCluster(p)
+		c.changeSpec(func(spec *v1alpha1.ClusterSpec) {
+			spec.Version = "0.6.0"
+		})
+		c.reconcile(2)
+
+		// The first bookie has been upgraded and is ready
+		for i, version := range []string{"0.6.0", "0.5.0", "0.5.0"} {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        fmt.Sprintf("example-bookie-%d", i),
+					Namespace:   p.Namespace,
+					Labels:      util.LabelsForBookie(p),
+					Annotations: map[string]string{"pravega.version": version},
+				},
+				Status: corev1.PodStatus{
+					Conditions:        []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+					ContainerStatuses: []corev1.ContainerStatus{{Ready: true}},
+				},
+			}
+			Ω(c.client.Create(context.TODO(), pod)).Should(Succeed())
+		}
+		c.setStatefulSetStatus(util.StatefulSetNameForBookie(p.Name), 3, 1, 3)
+		c.events()
+
+		c.reconcile(2)
+	})
+
+	It("should wait for an approval", func() {
+		Ω(c.podExists("example-bookie-1")).Should(BeTrue())
+		Ω(c.podExists("example-bookie-2")).Should(BeTrue())
+		Ω(c.upgradeCondition().Reason).Should(Equal(v1alpha1.WaitingForBookkeeperApprovalReason))
+		Ω(c.events()).Should(ContainElement(
+			"Normal WaitingForApproval Upgraded 1 bookie pods to version 0.6.0, set the annotation pravega.pravega.io/upgrade-approved=0.6.0 to upgrade the remaining pods"))
+	})
+
+	It("should show the pods running the new version", func() {
+		canary := c.get().Status.Canary
+		Ω(canary).ShouldNot(BeNil())
+		Ω(canary.Component).Should(Equal(bookieComponent))
+		Ω(canary.Version).Should(Equal("0.6.0"))
+		Ω(canary.Phase).Should(Equal(v1alpha1.CanaryWaitingForApproval))
+		Ω(canary.Pods).Should(Equal([]string{"example-bookie-0"}))
+		Ω(canary.WaitingSince).ShouldNot(BeEmpty())
+	})
+
+	It("should ignore the approval of another version", func() {
+		approve("0.7.0")
+		Ω(c.podExists("example-bookie-1")).Should(BeTrue())
+		Ω(c.get().Status.Canary.Phase).Should(Equal(v1alpha1.CanaryWaitingForApproval))
+	})
+
+	Context("When the upgrade is approved", func() {
+		BeforeEach(func() {
+			c.events()
+			approve("0.6.0")
+		})
+
+		It("should upgrade the remaining pods", func() {
+			Ω(c.podExists("example-bookie-1")).Should(BeFalse())
+			Ω(c.upgradeCondition().Reason).Should(Equal(v1alpha1.UpgradingBookkeeperReason))
+			Ω(c.events()).Should(ContainElement("Normal UpgradeApproved Upgrade of the remaining bookie pods to version 0.6.0 approved"))
+		})
+
+		It("should record the approval", func() {
+			foundPravega := c.get()
+			Ω(foundPravega.Status.Canary.Phase).Should(Equal(v1alpha1.CanaryApproved))
+			Ω(foundPravega.Annotations).ShouldNot(HaveKey(v1alpha1.UpgradeApprovedAnnotation))
+		})
+	})
+})
@@ -0,0 +1,297 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
+	"github.com/pravega/pravega-operator/pkg/metrics"
+	"github.com/pravega/pravega-operator/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// zookeeperCleanupPollInterval is the delay between checks for the
+	// termination of the cluster pods
+	zookeeperCleanupPollInterval = 5 * time.Second
+
+	// zookeeperCleanupMinBackoff and zookeeperCleanupMaxBackoff bound the delay
+	// between failed attempts to delete the cluster metadata
+	zookeeperCleanupMinBackoff = 5 * time.Second
+	zookeeperCleanupMaxBackoff = time.Minute
+
+	// zookeeperCleanupAttemptTimeout bounds the time an attempt to delete the
+	// cluster metadata may take, e.g. while ZooKeeper is unavailable
+	zookeeperCleanupAttemptTimeout = 30 * time.Second
+)
+
+// reconcileDeletion deletes the cluster metadata from ZooKeeper once a Pravega
+// cluster is deleted, and removes the finalizer once the metadata is gone.
+// Every step returns right away and requeues the request, so that a cluster
+// being deleted does not block the reconcile worker: the metadata is deleted
+// in the background and the result is picked up by a later reconciliation.
+// The progress is reported in the ZookeeperCleanup status.
+func (r *ReconcilePravegaCluster) reconcileDeletion(p *pravegav1alpha1.PravegaCluster) (reconcile.Result, error) {
+	key := types.NamespacedName{Name: p.Name, Namespace: p.Namespace}
+	if !util.ContainsString(p.ObjectMeta.Finalizers, util.ZkFinalizer) {
+		r.zookeeperCleanups.Delete(key)
+		return reconcile.Result{}, nil
+	}
+
+	// Fill in the reclaim policies of clusters deleted before being defaulted.
+	// The defaults are set on a copy, as the cluster is updated to remove the
+	// finalizer and its spec must not change.
+	cluster := p.DeepCopy()
+	cluster.WithDefaults()
+
+	status := p.Status.ZookeeperCleanup
+	if status == nil {
+		status = &pravegav1alpha1.ZookeeperCleanupStatus{
+			Phase:     pravegav1alpha1.ZookeeperCleanupTerminatingPods,
+			StartTime: time.Now().Format(time.RFC3339),
+		}
+		p.Status.ZookeeperCleanup = status
+		r.logger(p).Infof("cleaning up metadata from zookeeper %s", cluster.ZookeeperAddress())
+		r.recorder.Eventf(p, corev1.EventTypeNormal, ZookeeperCleanupReason,
+			"Waiting for pods to terminate before deleting the cluster metadata from zookeeper %s", cluster.ZookeeperAddress())
+	}
+
+	// Make sure that the garbage collector only deletes the volumes whose
+	// reclaim policy is Delete once the cluster is gone. The volumes created
+	// from the claim templates of stateful sets created by older versions of
+	// the operator are owned by the cluster, whatever their policy. They are
+	// released on every pass, and the finalizer is not removed until they are.
+	err := r.syncPvcReclaimPolicy(cluster, util.LabelsForBookie(cluster), cluster.Spec.Bookkeeper.Storage.ReclaimPolicy)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	err = r.syncPvcReclaimPolicy(cluster, util.LabelsForSegmentStore(cluster), cluster.Spec.Pravega.CacheVolumeReclaimPolicy)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if status.Phase == pravegav1alpha1.ZookeeperCleanupTerminatingPods {
+		remaining, err := r.terminateClusterPods(cluster)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if remaining > 0 {
+			status.Message = fmt.Sprintf("waiting for %d pods to terminate", remaining)
+			if err = r.client.Status().Update(context.TODO(), p); err != nil {
+				return reconcile.Result{}, fmt.Errorf("failed to update cluster status: %v", err)
+			}
+			return reconcile.Result{RequeueAfter: zookeeperCleanupPollInterval}, nil
+		}
+		status.Phase = pravegav1alpha1.ZookeeperCleanupDeletingZnodes
+		status.Message = ""
+		if err = r.client.Status().Update(context.TODO(), p); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to update cluster status: %v", err)
+		}
+	}
+
+	if value, ok := r.zookeeperCleanups.Load(key); ok {
+		attempt := value.(*zookeeperCleanupAttempt)
+		select {
+		case <-attempt.done:
+			r.zookeeperCleanups.Delete(key)
+			return r.completeZookeeperCleanup(p, attempt.address, attempt.err)
+		default:
+			// The status is not updated while the attempt is running
+			return reconcile.Result{RequeueAfter: zookeeperCleanupPollInterval}, nil
+		}
+	}
+
+	// The cluster may be requeued by other events before the end of the
+	// backoff, the next attempt still waits for it
+	if wait := zookeeperCleanupWait(status); wait > 0 {
+		return reconcile.Result{RequeueAfter: wait}, nil
+	}
+
+	secret, err := r.getZookeeperSecret(cluster)
+	if err == nil {
+		err = r.resolveZookeeperUri(cluster)
+	}
+	if err != nil {
+		return r.completeZookeeperCleanup(p, cluster.ZookeeperAddress(), err)
+	}
+
+	attempt := &zookeeperCleanupAttempt{
+		address: cluster.ZookeeperAddress(),
+		done:    make(chan struct{}),
+	}
+	r.zookeeperCleanups.Store(key, attempt)
+	go func() {
+		defer close(attempt.done)
+		attempt.err = util.DeleteAllZnodes(cluster, secret, zookeeperCleanupAttemptTimeout)
+	}()
+	return reconcile.Result{RequeueAfter: zookeeperCleanupPollInterval}, nil
+}
+
+// zookeeperCleanupAttempt is an attempt to delete the metadata of a cluster
+// from ZooKeeper, running in the background. err is set once done is closed.
+type zookeeperCleanupAttempt struct {
+	address string
+	done    chan struct{}
+	err     error
+}
+
+// completeZookeeperCleanup records the result of an attempt to delete the
+// cluster metadata, and removes the finalizer if the attempt succeeded
+func (r *ReconcilePravegaCluster) completeZookeeperCleanup(p *pravegav1alpha1.PravegaCluster, address string, err error) (reconcile.Result, error) {
+	status := p.Status.ZookeeperCleanup
+	status.Attempts++
+	status.LastAttemptTime = time.Now().Format(time.RFC3339)
+
+	if err == nil {
+		metrics.ZookeeperCleanups.WithLabelValues(metrics.ZookeeperCleanupSucceeded).Inc()
+		r.logger(p).Info("deleted metadata from zookeeper")
+		r.recorder.Eventf(p, corev1.EventTypeNormal, ZookeeperCleanedUpReason,
+			"Deleted the cluster metadata from zookeeper %s", address)
+		p.ObjectMeta.Finalizers = util.RemoveString(p.ObjectMeta.Finalizers, util.ZkFinalizer)
+		if err = r.client.Update(context.TODO(), p); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to remove the finalizer (%s): %v", p.Name, err)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	metrics.ZookeeperCleanups.WithLabelValues(metrics.ZookeeperCleanupFailed).Inc()
+	r.logger(p).Errorf("failed to delete metadata from zookeeper (attempt %d): %v", status.Attempts, err)
+	r.recorder.Eventf(p, corev1.EventTypeWarning, ZookeeperCleanupFailedReason,
+		"Failed to delete the cluster metadata from zookeeper %s: %v", address, err)
+	status.Message = err.Error()
+
+	if status.Phase != pravegav1alpha1.ZookeeperCleanupFailed && zookeeperCleanupTimedOut(status) {
+		status.Phase = pravegav1alpha1.ZookeeperCleanupFailed
+		r.recorder.Eventf(p, corev1.EventTypeWarning, ZookeeperCleanupFailedReason,
+			"Gave up deleting the cluster metadata after %v, remove the finalizer %s to delete the cluster anyway",
+			config.ZookeeperCleanupTimeout, util.ZkFinalizer)
+	}
+
+	if err = r.client.Status().Update(context.TODO(), p); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to update cluster status: %v", err)
+	}
+	return reconcile.Result{RequeueAfter: zookeeperCleanupRetryDelay(status)}, nil
+}
+
+// getZookeeperSecret returns the data of the secret holding the credentials
+// and certificates to connect to ZooKeeper, if any
+func (r *ReconcilePravegaCluster) getZookeeperSecret(p *pravegav1alpha1.PravegaCluster) (map[string][]byte, error) {
+	if p.Spec.Zookeeper == nil || p.Spec.Zookeeper.Secret == "" {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: p.Spec.Zookeeper.Secret, Namespace: p.Namespace}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zookeeper secret (%s): %v", p.Spec.Zookeeper.Secret, err)
+	}
+	return secret.Data, nil
+}
+
+// resolveZookeeperUri sets the ZooKeeper URI in the status of a cluster that
+// references a ZookeeperCluster, regardless of whether the ZookeeperCluster
+// is ready
+func (r *ReconcilePravegaCluster) resolveZookeeperUri(p *pravegav1alpha1.PravegaCluster) error {
+	ref := p.Spec.Zookeeper.ClusterRef
+	if ref == nil {
+		return nil
+	}
+	address, _, err := r.getZookeeperCluster(ref)
+	if err != nil {
+		return err
+	}
+	if address == "" {
+		return fmt.Errorf("zookeeper cluster (%s/%s) has no client address", ref.Namespace, ref.Name)
+	}
+	p.Status.ZookeeperUri = address
+	return nil
+}
+
+// terminateClusterPods deletes the workloads of the cluster and returns the
+// number of cluster pods that are not terminated yet. The workloads are
+// deleted explicitly because the garbage collector does not delete them while
+// the cluster still has a finalizer.
+func (r *ReconcilePravegaCluster) terminateClusterPods(p *pravegav1alpha1.PravegaCluster) (int, error) {
+	for _, obj := range []runtime.Object{
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: util.StatefulSetNameForBookie(p.Name), Namespace: p.Namespace}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: util.StatefulSetNameForSegmentstore(p.Name), Namespace: p.Namespace}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: util.DeploymentNameForController(p.Name), Namespace: p.Namespace}},
+	} {
+		err := r.client.Delete(context.TODO(), obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			return 0, fmt.Errorf("failed to delete cluster workload: %v", err)
+		}
+	}
+
+	podList := &corev1.PodList{}
+	listOptions := &client.ListOptions{
+		Namespace:     p.Namespace,
+		LabelSelector: labels.SelectorFromSet(util.LabelsForPravegaCluster(p)),
+	}
+	if err := r.client.List(context.TODO(), listOptions, podList); err != nil {
+		return 0, fmt.Errorf("failed to list cluster pods: %v", err)
+	}
+	return len(podList.Items), nil
+}
+
+// zookeeperCleanupBackoff returns the delay before the next attempt to delete
+// the cluster metadata, doubling with every failed attempt
+func zookeeperCleanupBackoff(attempts int32) time.Duration {
+	backoff := zookeeperCleanupMinBackoff
+	for i := int32(1); i < attempts && backoff < zookeeperCleanupMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > zookeeperCleanupMaxBackoff {
+		backoff = zookeeperCleanupMaxBackoff
+	}
+	return backoff
+}
+
+// zookeeperCleanupRetryDelay returns the delay between the last failed attempt
+// to delete the cluster metadata and the next one
+func zookeeperCleanupRetryDelay(status *pravegav1alpha1.ZookeeperCleanupStatus) time.Duration {
+	if status.Phase == pravegav1alpha1.ZookeeperCleanupFailed {
+		return config.ResyncPeriod
+	}
+	return zookeeperCleanupBackoff(status.Attempts)
+}
+
+// zookeeperCleanupWait returns the time left before the next attempt to delete
+// the cluster metadata
+func zookeeperCleanupWait(status *pravegav1alpha1.ZookeeperCleanupStatus) time.Duration {
+	if status.Attempts == 0 {
+		return 0
+	}
+	last, err := time.Parse(time.RFC3339, status.LastAttemptTime)
+	if err != nil {
+		return 0
+	}
+	return time.Until(last.Add(zookeeperCleanupRetryDelay(status)))
+}
+
+func zookeeperCleanupTimedOut(status *pravegav1alpha1.ZookeeperCleanupStatus) bool {
+	started, err := time.Parse(time.RFC3339, status.StartTime)
+	if err != nil {
+		return true
+	}
+	return time.Since(started) > config.ZookeeperCleanupTimeout
+}
@@ -0,0 +1,217 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Zookeeper cleanup", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s      = scheme.Scheme
+		r      *ReconcilePravegaCluster
+		client client.Client
+		req    reconcile.Request
+		pod    *corev1.Pod
+		res    reconcile.Result
+		err    error
+	)
+
+	get := func() *v1alpha1.PravegaCluster {
+		p := &v1alpha1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), req.NamespacedName, p)).Should(Succeed())
+		return p
+	}
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p := &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		// Fail fast instead of waiting for an unavailable zookeeper
+		p.Spec.ZookeeperUri = "zookeeper.invalid:2181"
+		p.WithDefaults()
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+		client = fake.NewFakeClient(p)
+		r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
+		r.Reconcile(req)
+		r.Reconcile(req)
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      util.StatefulSetNameForBookie(Name) + "-0",
+				Namespace: Namespace,
+				Labels:    util.LabelsForBookie(p),
+			},
+		}
+		Ω(client.Create(context.TODO(), pod)).Should(Succeed())
+
+		// Simulate the deletion of a cluster that was not defaulted
+		p = get()
+		Ω(p.Finalizers).Should(ContainElement(util.ZkFinalizer))
+		p.Spec.Bookkeeper.Storage.ReclaimPolicy = ""
+		now := metav1.Now()
+		p.DeletionTimestamp = &now
+		Ω(client.Update(context.TODO(), p)).Should(Succeed())
+
+		res, err = r.Reconcile(req)
+	})
+
+	Context("While the cluster pods are terminating", func() {
+		It("should requeue without error", func() {
+			Ω(err).Should(BeNil())
+			Ω(res.RequeueAfter).Should(Equal(zookeeperCleanupPollInterval))
+		})
+
+		It("should report the progress", func() {
+			status := get().Status.ZookeeperCleanup
+			Ω(status).ShouldNot(BeNil())
+			Ω(status.Phase).Should(Equal(v1alpha1.ZookeeperCleanupTerminatingPods))
+			Ω(status.Message).Should(Equal("waiting for 1 pods to terminate"))
+			Ω(status.StartTime).ShouldNot(BeEmpty())
+			Ω(status.Attempts).Should(BeZero())
+		})
+
+		It("should keep the finalizer", func() {
+			Ω(get().Finalizers).Should(ContainElement(util.ZkFinalizer))
+		})
+
+		It("should delete the cluster workloads", func() {
+			for _, name := range []string{util.StatefulSetNameForBookie(Name), util.StatefulSetNameForSegmentstore(Name)} {
+				err := client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: Namespace}, &appsv1.StatefulSet{})
+				Ω(errors.IsNotFound(err)).Should(BeTrue())
+			}
+			err := client.Get(context.TODO(), types.NamespacedName{Name: util.DeploymentNameForController(Name), Namespace: Namespace}, &appsv1.Deployment{})
+			Ω(errors.IsNotFound(err)).Should(BeTrue())
+		})
+	})
+
+	Context("When zookeeper is unavailable", func() {
+		// attempt starts an attempt to delete the metadata, waits for it to
+		// complete and reconciles the cluster to record its result
+		attempt := func() {
+			res, err = r.Reconcile(req)
+			Ω(err).Should(BeNil())
+			Ω(res.RequeueAfter).Should(Equal(zookeeperCleanupPollInterval))
+			value, ok := r.zookeeperCleanups.Load(req.NamespacedName)
+			Ω(ok).Should(BeTrue())
+			Eventually(value.(*zookeeperCleanupAttempt).done, zookeeperCleanupAttemptTimeout).Should(BeClosed())
+			res, err = r.Reconcile(req)
+		}
+
+		BeforeEach(func() {
+			Ω(client.Delete(context.TODO(), pod)).Should(Succeed())
+			attempt()
+		})
+
+		It("should retry with a backoff", func() {
+			Ω(err).Should(BeNil())
+			Ω(res.RequeueAfter).Should(Equal(zookeeperCleanupMinBackoff))
+			status := get().Status.ZookeeperCleanup
+			Ω(status.Phase).Should(Equal(v1alpha1.ZookeeperCleanupDeletingZnodes))
+			Ω(status.Attempts).Should(BeEquivalentTo(1))
+			Ω(status.Message).ShouldNot(BeEmpty())
+			Ω(get().Finalizers).Should(ContainElement(util.ZkFinalizer))
+
+			// End the backoff of the first attempt
+			p := get()
+			p.Status.ZookeeperCleanup.LastAttemptTime = time.Now().Add(-time.Hour).Format(time.RFC3339)
+			Ω(client.Status().Update(context.TODO(), p)).Should(Succeed())
+
+			attempt()
+			Ω(err).Should(BeNil())
+			Ω(res.RequeueAfter).Should(Equal(2 * zookeeperCleanupMinBackoff))
+			Ω(get().Status.ZookeeperCleanup.Attempts).Should(BeEquivalentTo(2))
+		})
+
+		It("should not retry before the end of the backoff", func() {
+			res, err = r.Reconcile(req)
+			Ω(err).Should(BeNil())
+			Ω(res.RequeueAfter).Should(BeNumerically(">", 0))
+			Ω(res.RequeueAfter).Should(BeNumerically("<=", zookeeperCleanupMinBackoff))
+			_, ok := r.zookeeperCleanups.Load(req.NamespacedName)
+			Ω(ok).Should(BeFalse())
+			Ω(get().Status.ZookeeperCleanup.Attempts).Should(BeEquivalentTo(1))
+		})
+
+		It("should not set the defaults in the spec", func() {
+			Ω(get().Spec.Bookkeeper.Storage.ReclaimPolicy).Should(BeEmpty())
+		})
+
+		Context("After the timeout", func() {
+			var timeout time.Duration
+
+			BeforeEach(func() {
+				timeout = config.ZookeeperCleanupTimeout
+				config.ZookeeperCleanupTimeout = 0
+				p := get()
+				p.Status.ZookeeperCleanup.LastAttemptTime = time.Now().Add(-time.Hour).Format(time.RFC3339)
+				Ω(client.Status().Update(context.TODO(), p)).Should(Succeed())
+				attempt()
+			})
+
+			AfterEach(func() {
+				config.ZookeeperCleanupTimeout = timeout
+			})
+
+			It("should fail and keep the finalizer", func() {
+				Ω(err).Should(BeNil())
+				Ω(res.RequeueAfter).Should(Equal(config.ResyncPeriod))
+				p := get()
+				Ω(p.Status.ZookeeperCleanup.Phase).Should(Equal(v1alpha1.ZookeeperCleanupFailed))
+				Ω(p.Finalizers).Should(ContainElement(util.ZkFinalizer))
+			})
+		})
+	})
+})
+
+var _ = Describe("Zookeeper cleanup backoff", func() {
+	It("should double with every attempt up to the maximum", func() {
+		Ω(zookeeperCleanupBackoff(1)).Should(Equal(5 * time.Second))
+		Ω(zookeeperCleanupBackoff(2)).Should(Equal(10 * time.Second))
+		Ω(zookeeperCleanupBackoff(4)).Should(Equal(40 * time.Second))
+		Ω(zookeeperCleanupBackoff(5)).Should(Equal(time.Minute))
+		Ω(zookeeperCleanupBackoff(100)).Should(Equal(time.Minute))
+	})
+})
@@ -0,0 +1,42 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+// Reasons of the events recorded on PravegaCluster objects
+const (
+	// Normal events
+	CreatedReason             = "Created"
+	ScaledReason              = "Scaled"
+	DeletedOrphanPvcReason    = "DeletedOrphanPVC"
+	RetainedOrphanPvcReason   = "RetainedOrphanPVC"
+	UpgradeStartedReason      = "UpgradeStarted"
+	UpgradeCompletedReason    = "UpgradeCompleted"
+	UpgradingPodReason        = "UpgradingPod"
+	RollbackStartedReason     = "RollbackStarted"
+	RollbackCompletedReason   = "RollbackCompleted"
+	RollingBackPodReason      = "RollingBackPod"
+	WaitingForApprovalReason  = "WaitingForApproval"
+	UpgradeApprovedReason     = "UpgradeApproved"
+	RestartingPodReason       = "RestartingPod"
+	ZookeeperCleanupReason    = "ZookeeperCleanup"
+	ZookeeperCleanedUpReason  = "ZookeeperCleanedUp"
+	WaitingForZookeeperReason = "WaitingForZookeeper"
+	PausedReason              = "Paused"
+	ResumedReason             = "Resumed"
+
+	// Warning events
+	ProgressDeadlineExceededReason = "ProgressDeadlineExceeded"
+	UpgradeFailedReason            = "UpgradeFailed"
+	RollbackFailedReason           = "RollbackFailed"
+	ConfigUpdateFailedReason       = "ConfigUpdateFailed"
+	ZookeeperCleanupFailedReason   = "ZookeeperCleanupFailed"
+	InvalidSpecReason              = "InvalidSpec"
+)
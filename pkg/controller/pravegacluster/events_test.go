@@ -0,0 +1,123 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s        = scheme.Scheme
+		r        *ReconcilePravegaCluster
+		client   client.Client
+		recorder *record.FakeRecorder
+		req      reconcile.Request
+	)
+
+	// drain returns the events recorded so far
+	drain := func() (events []string) {
+		for {
+			select {
+			case e := <-recorder.Events:
+				events = append(events, e)
+			default:
+				return events
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p := &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		p.WithDefaults()
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+		client = fake.NewFakeClient(p)
+		recorder = record.NewFakeRecorder(100)
+		r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: recorder}
+		r.Reconcile(req)
+	})
+
+	It("should record the creation of the components", func() {
+		Ω(drain()).Should(ConsistOf(
+			"Normal Created Created stateful-set example-bookie with 3 replicas",
+			"Normal Created Created deployment example-pravega-controller with 1 replicas",
+			"Normal Created Created stateful-set example-pravega-segmentstore with 1 replicas",
+		))
+	})
+
+	It("should record the scaling of the bookies", func() {
+		drain()
+		foundPravega := &v1alpha1.PravegaCluster{}
+		_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+		foundPravega.Spec.Bookkeeper.Replicas = 4
+		client.Update(context.TODO(), foundPravega)
+		r.Reconcile(req)
+		Ω(drain()).Should(ContainElement("Normal Scaled Scaled stateful-set example-bookie from 3 to 4 replicas"))
+	})
+
+	It("should record the deletion of orphan pvcs", func() {
+		foundPravega := &v1alpha1.PravegaCluster{}
+		_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+		foundPravega.Spec.Bookkeeper.Replicas = 4
+		client.Update(context.TODO(), foundPravega)
+		r.Reconcile(req)
+		drain()
+
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ledger-example-bookie-3",
+				Namespace: Namespace,
+				Labels:    util.LabelsForBookie(foundPravega),
+			},
+		}
+		Ω(client.Create(context.TODO(), pvc)).Should(Succeed())
+		_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+		foundPravega.Spec.Bookkeeper.Replicas = 3
+		client.Update(context.TODO(), foundPravega)
+		r.Reconcile(req)
+		Ω(drain()).Should(ConsistOf(
+			"Normal Scaled Scaled stateful-set example-bookie from 4 to 3 replicas",
+			"Normal DeletedOrphanPVC Deleted pvc ledger-example-bookie-3 of stateful-set example-bookie scaled down to 3 replicas",
+		))
+	})
+})
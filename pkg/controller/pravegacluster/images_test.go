@@ -0,0 +1,69 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/pravega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Images", func() {
+	const digest = "sha256:4b1c8bd86e3d3cb8c34c05c8bbd1c9a1e1bbbdc1a3d1ba1a3e6bd8b6f6e2c0d1"
+
+	var p *v1alpha1.PravegaCluster
+
+	BeforeEach(func() {
+		p = &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example",
+				Namespace: "default",
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		p.WithDefaults()
+	})
+
+	Context("Image pull secrets", func() {
+		BeforeEach(func() {
+			p.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+			p.Spec.Bookkeeper.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "bookkeeper-registry"}, {Name: "registry"}}
+		})
+
+		It("should set the secrets of the cluster and of the component", func() {
+			podSpec := pravega.MakeBookiePodTemplate(p).Spec
+			Ω(podSpec.ImagePullSecrets).Should(Equal([]corev1.LocalObjectReference{{Name: "registry"}, {Name: "bookkeeper-registry"}}))
+		})
+
+		It("should set the secrets of the cluster on the Pravega pods", func() {
+			expected := []corev1.LocalObjectReference{{Name: "registry"}}
+			Ω(pravega.MakeControllerPodTemplate(p).Spec.ImagePullSecrets).Should(Equal(expected))
+			Ω(pravega.MakeSegmentStorePodTemplate(p).Spec.ImagePullSecrets).Should(Equal(expected))
+		})
+	})
+
+	Context("Digest pinned images", func() {
+		It("should use a repository pinned to a digest as is", func() {
+			p.Spec.Pravega.Image.Repository = "registry.example.com/pravega@" + digest
+			Ω(pravega.MakeSegmentStorePodTemplate(p).Spec.Containers[0].Image).Should(Equal("registry.example.com/pravega@" + digest))
+		})
+
+		It("should pin the image to the digest", func() {
+			p.Spec.Bookkeeper.Image.Digest = digest
+			Ω(pravega.MakeBookiePodTemplate(p).Spec.Containers[0].Image).Should(Equal("pravega/bookkeeper@" + digest))
+		})
+	})
+})
@@ -0,0 +1,75 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Component names used in log fields. They match the component label of
+// the pods.
+const (
+	bookieComponent       = "bookie"
+	controllerComponent   = "pravega-controller"
+	segmentStoreComponent = "pravega-segmentstore"
+)
+
+// startReconcile assigns a new reconcile ID to the cluster. The workqueue
+// never hands the same cluster to two workers at the same time, so the ID
+// does not change until the reconciliation ends.
+func (r *ReconcilePravegaCluster) startReconcile(nn types.NamespacedName) {
+	r.reconcileIDs.Store(nn, string(uuid.NewUUID()))
+}
+
+// endReconcile forgets the reconcile ID of the cluster
+func (r *ReconcilePravegaCluster) endReconcile(nn types.NamespacedName) {
+	r.reconcileIDs.Delete(nn)
+}
+
+// logger returns a log entry with the namespace, the name, the reconcile ID
+// and the upgrade target version of the cluster
+func (r *ReconcilePravegaCluster) logger(p *pravegav1alpha1.PravegaCluster) *log.Entry {
+	return r.requestLogger(types.NamespacedName{Namespace: p.Namespace, Name: p.Name}).
+		WithFields(targetVersionFields(p))
+}
+
+// componentLogger returns the logger of the cluster with the component field
+func (r *ReconcilePravegaCluster) componentLogger(p *pravegav1alpha1.PravegaCluster, component string) *log.Entry {
+	entry := r.logger(p)
+	if component != "" {
+		entry = entry.WithField("component", component)
+	}
+	return entry
+}
+
+// requestLogger returns a log entry with the namespace, the name and the
+// reconcile ID of the cluster
+func (r *ReconcilePravegaCluster) requestLogger(nn types.NamespacedName) *log.Entry {
+	fields := log.Fields{
+		"namespace": nn.Namespace,
+		"cluster":   nn.Name,
+	}
+	if id, ok := r.reconcileIDs.Load(nn); ok {
+		fields["reconcileID"] = id
+	}
+	return log.WithFields(fields)
+}
+
+func targetVersionFields(p *pravegav1alpha1.PravegaCluster) log.Fields {
+	if p.Status.TargetVersion == "" {
+		return log.Fields{}
+	}
+	return log.Fields{"targetVersion": p.Status.TargetVersion}
+}
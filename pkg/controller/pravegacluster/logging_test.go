@@ -0,0 +1,67 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logging", func() {
+	var (
+		r  *ReconcilePravegaCluster
+		p  *v1alpha1.PravegaCluster
+		nn types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		r = &ReconcilePravegaCluster{}
+		p = &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example",
+				Namespace: "default",
+			},
+		}
+		nn = types.NamespacedName{Name: "example", Namespace: "default"}
+	})
+
+	It("should log the cluster fields", func() {
+		data := r.componentLogger(p, bookieComponent).Data
+		Ω(data).Should(HaveKeyWithValue("namespace", "default"))
+		Ω(data).Should(HaveKeyWithValue("cluster", "example"))
+		Ω(data).Should(HaveKeyWithValue("component", "bookie"))
+		Ω(data).ShouldNot(HaveKey("reconcileID"))
+		Ω(data).ShouldNot(HaveKey("targetVersion"))
+	})
+
+	It("should log the target version during an upgrade", func() {
+		p.Status.TargetVersion = "0.5.0"
+		Ω(r.logger(p).Data).Should(HaveKeyWithValue("targetVersion", "0.5.0"))
+	})
+
+	It("should log the same reconcile ID until the reconciliation ends", func() {
+		r.startReconcile(nn)
+		id := r.logger(p).Data["reconcileID"]
+		Ω(id).ShouldNot(BeEmpty())
+		Ω(r.requestLogger(nn).Data).Should(HaveKeyWithValue("reconcileID", id))
+
+		r.endReconcile(nn)
+		Ω(r.logger(p).Data).ShouldNot(HaveKey("reconcileID"))
+
+		r.startReconcile(nn)
+		Ω(r.logger(p).Data["reconcileID"]).ShouldNot(Equal(id))
+	})
+})
@@ -0,0 +1,69 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"fmt"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reconcilePaused only reports the status of a paused cluster. The defaults
+// are applied in memory to compute the status, but neither the cluster nor
+// its resources are updated, so that they can be changed by hand.
+func (r *ReconcilePravegaCluster) reconcilePaused(p *pravegav1alpha1.PravegaCluster) error {
+	p.WithDefaults()
+
+	// The upgrade is stopped while the cluster is paused
+	p.Status.RestartUpgradingCondition()
+
+	err := r.reconcileClusterStatus(p)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile cluster status: %v", err)
+	}
+	return nil
+}
+
+// syncPausedCondition sets the Paused condition of the cluster and records an
+// event when the cluster is paused or resumed
+func (r *ReconcilePravegaCluster) syncPausedCondition(p *pravegav1alpha1.PravegaCluster) {
+	_, condition := p.Status.GetClusterCondition(pravegav1alpha1.ClusterConditionPaused)
+	paused := condition != nil && condition.Status == corev1.ConditionTrue
+
+	if p.IsPaused() {
+		if !paused {
+			r.logger(p).Info("reconciliation paused")
+			r.recorder.Eventf(p, corev1.EventTypeNormal, PausedReason,
+				"Paused the reconciliation of the cluster, remove the %s annotation to resume it", pravegav1alpha1.PausedAnnotation)
+		}
+		p.Status.SetPausedConditionTrue("Annotation",
+			fmt.Sprintf("the cluster has the annotation %s=true", pravegav1alpha1.PausedAnnotation))
+		return
+	}
+
+	if paused {
+		r.logger(p).Info("reconciliation resumed")
+		r.recorder.Event(p, corev1.EventTypeNormal, ResumedReason, "Resumed the reconciliation of the cluster")
+	}
+	p.Status.SetPausedConditionFalse()
+}
+
+// restartUpgradeOnResume restarts the progress deadline of an upgrade when a
+// paused cluster is resumed, so that the time the cluster was paused since
+// its last reconciliation does not count against the deadline. The Paused
+// condition is only cleared with the status at the end of the reconciliation.
+func restartUpgradeOnResume(p *pravegav1alpha1.PravegaCluster) {
+	_, condition := p.Status.GetClusterCondition(pravegav1alpha1.ClusterConditionPaused)
+	if condition != nil && condition.Status == corev1.ConditionTrue {
+		p.Status.RestartUpgradingCondition()
+	}
+}
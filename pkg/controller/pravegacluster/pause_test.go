@@ -0,0 +1,267 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Paused cluster", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s        = scheme.Scheme
+		r        *ReconcilePravegaCluster
+		client   client.Client
+		recorder *record.FakeRecorder
+		req      reconcile.Request
+		p        *v1alpha1.PravegaCluster
+	)
+
+	get := func() *v1alpha1.PravegaCluster {
+		p := &v1alpha1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), req.NamespacedName, p)).Should(Succeed())
+		return p
+	}
+
+	getBookies := func() *appsv1.StatefulSet {
+		sts := &appsv1.StatefulSet{}
+		nn := types.NamespacedName{Name: util.StatefulSetNameForBookie(Name), Namespace: Namespace}
+		Ω(client.Get(context.TODO(), nn, sts)).Should(Succeed())
+		return sts
+	}
+
+	pausedCondition := func() *v1alpha1.ClusterCondition {
+		_, condition := get().Status.GetClusterCondition(v1alpha1.ClusterConditionPaused)
+		Ω(condition).ShouldNot(BeNil())
+		return condition
+	}
+
+	events := func() []string {
+		var events []string
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		return events
+	}
+
+	setPaused := func(paused bool) {
+		p := get()
+		if paused {
+			p.Annotations = map[string]string{v1alpha1.PausedAnnotation: "true"}
+		} else {
+			delete(p.Annotations, v1alpha1.PausedAnnotation)
+		}
+		Ω(client.Update(context.TODO(), p)).Should(Succeed())
+	}
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p = &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+	})
+
+	Context("When a new cluster is paused", func() {
+		BeforeEach(func() {
+			p.Annotations = map[string]string{v1alpha1.PausedAnnotation: "true"}
+			client = fake.NewFakeClient(p)
+			recorder = record.NewFakeRecorder(100)
+			r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: recorder}
+			_, err := r.Reconcile(req)
+			Ω(err).Should(BeNil())
+		})
+
+		It("should not deploy the cluster", func() {
+			nn := types.NamespacedName{Name: util.StatefulSetNameForBookie(Name), Namespace: Namespace}
+			err := client.Get(context.TODO(), nn, &appsv1.StatefulSet{})
+			Ω(errors.IsNotFound(err)).Should(BeTrue())
+		})
+
+		It("should set the paused condition", func() {
+			Ω(pausedCondition().Status).Should(Equal(corev1.ConditionTrue))
+		})
+	})
+
+	Context("When a running cluster is paused", func() {
+		BeforeEach(func() {
+			client = fake.NewFakeClient(p)
+			recorder = record.NewFakeRecorder(100)
+			r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: recorder}
+			r.Reconcile(req)
+			r.Reconcile(req)
+			Ω(pausedCondition().Status).Should(Equal(corev1.ConditionFalse))
+			events()
+
+			setPaused(true)
+			r.Reconcile(req)
+
+			// Change the cluster and its resources by hand
+			sts := getBookies()
+			replicas := int32(1)
+			sts.Spec.Replicas = &replicas
+			Ω(client.Update(context.TODO(), sts)).Should(Succeed())
+			foundPravega := get()
+			foundPravega.Spec.Version = "0.6.0"
+			foundPravega.Spec.Bookkeeper.Replicas = 5
+			Ω(client.Update(context.TODO(), foundPravega)).Should(Succeed())
+			r.Reconcile(req)
+		})
+
+		It("should set the paused condition", func() {
+			Ω(pausedCondition().Status).Should(Equal(corev1.ConditionTrue))
+			Ω(events()).Should(ContainElement(ContainSubstring("Normal Paused")))
+		})
+
+		It("should not touch the resources", func() {
+			Ω(*getBookies().Spec.Replicas).Should(BeEquivalentTo(1))
+		})
+
+		It("should not revert the version", func() {
+			Ω(get().Spec.Version).Should(Equal("0.6.0"))
+			Ω(get().Status.TargetVersion).Should(BeEmpty())
+		})
+
+		It("should keep reporting the status", func() {
+			Ω(get().Status.Bookkeeper.Replicas).Should(BeEquivalentTo(5))
+		})
+
+		Context("When the cluster is resumed", func() {
+			BeforeEach(func() {
+				events()
+				setPaused(false)
+				r.Reconcile(req)
+			})
+
+			It("should clear the paused condition", func() {
+				Ω(pausedCondition().Status).Should(Equal(corev1.ConditionFalse))
+				Ω(events()).Should(ContainElement("Normal Resumed Resumed the reconciliation of the cluster"))
+			})
+
+			It("should reconcile the resources", func() {
+				Ω(*getBookies().Spec.Replicas).Should(BeEquivalentTo(5))
+			})
+		})
+	})
+
+	Context("When an upgrade is paused for longer than its progress deadline", func() {
+		var c *testCluster
+
+		// stallUpgrade moves the last update of the upgrade two hours back, as
+		// if the cluster had been paused for that long
+		stallUpgrade := func() {
+			foundPravega := c.get()
+			i, _ := foundPravega.Status.GetClusterCondition(v1alpha1.ClusterConditionUpgrading)
+			foundPravega.Status.Conditions[i].LastUpdateTime = time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+			Ω(c.client.Update(context.TODO(), foundPravega)).Should(Succeed())
+		}
+
+		lastUpdate := func() time.Time {
+			t, err := time.Parse(time.RFC3339, c.upgradeCondition().LastUpdateTime)
+			Ω(err).Should(BeNil())
+			return t
+		}
+
+		setPaused := func(paused bool) {
+			foundPravega := c.get()
+			if paused {
+				foundPravega.Annotations = map[string]string{v1alpha1.PausedAnnotation: "true"}
+			} else {
+				delete(foundPravega.Annotations, v1alpha1.PausedAnnotation)
+			}
+			Ω(c.client.Update(context.TODO(), foundPravega)).Should(Succeed())
+		}
+
+		BeforeEach(func() {
+			p := newTestCluster()
+			p.Spec.UpgradePolicy.BookkeeperProgressDeadlineSeconds = 600
+			p.Spec.UpgradePolicy.Rollback = true
+			c = deployTestCluster(p)
+			c.changeSpec(func(spec *v1alpha1.ClusterSpec) {
+				spec.Version = "0.6.0"
+			})
+			c.reconcile(2)
+
+			// One bookie has been upgraded and is starting
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "example-bookie-0",
+					Namespace:   p.Namespace,
+					Labels:      util.LabelsForBookie(p),
+					Annotations: map[string]string{"pravega.version": "0.6.0"},
+				},
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{{}},
+				},
+			}
+			Ω(c.client.Create(context.TODO(), pod)).Should(Succeed())
+			c.setStatefulSetStatus(util.StatefulSetNameForBookie(p.Name), 3, 1, 2)
+			c.reconcile(1)
+			Ω(c.upgradeCondition().Reason).Should(Equal(v1alpha1.UpgradingBookkeeperReason))
+			Ω(c.upgradeCondition().Message).Should(Equal("1"))
+
+			setPaused(true)
+			c.reconcile(1)
+			c.events()
+		})
+
+		It("should not count the pause while the cluster is paused", func() {
+			stallUpgrade()
+			c.reconcile(1)
+			Ω(lastUpdate()).Should(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		It("should not fail the upgrade when the cluster is resumed", func() {
+			stallUpgrade()
+			setPaused(false)
+			c.reconcile(1)
+
+			foundPravega := c.get()
+			Ω(foundPravega.Status.Rollback).Should(BeNil())
+			Ω(foundPravega.Status.TargetVersion).Should(Equal("0.6.0"))
+			Ω(c.upgradeCondition().Status).Should(Equal(corev1.ConditionTrue))
+			Ω(c.upgradeCondition().Reason).Should(Equal(v1alpha1.UpgradingBookkeeperReason))
+			Ω(lastUpdate()).Should(BeTemporally("~", time.Now(), time.Minute))
+			Ω(c.events()).ShouldNot(ContainElement(ContainSubstring(ProgressDeadlineExceededReason)))
+		})
+	})
+})
@@ -13,33 +13,37 @@ package pravegacluster
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
 	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
 	"github.com/pravega/pravega-operator/pkg/controller/pravega"
+	"github.com/pravega/pravega-operator/pkg/metrics"
 	"github.com/pravega/pravega-operator/pkg/util"
+	log "github.com/sirupsen/logrus"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
-
-	log "github.com/sirupsen/logrus"
 )
 
-// ReconcileTime is the delay between reconciliations
-const ReconcileTime = 30 * time.Second
-
 // Add creates a new PravegaCluster Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
@@ -48,19 +52,74 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcilePravegaCluster{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+	return &ReconcilePravegaCluster{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetRecorder("pravega-operator"),
+	}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("pravegacluster-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("pravegacluster-controller", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: config.MaxConcurrentReconciles,
+	})
 	if err != nil {
 		return err
 	}
 
-	// Watch for changes to primary resource PravegaCluster
-	err = c.Watch(&source.Kind{Type: &pravegav1alpha1.PravegaCluster{}}, &handler.EnqueueRequestForObject{})
+	// Watch for changes to primary resource PravegaCluster, except for the
+	// updates of its status made by the operator itself
+	err = c.Watch(&source.Kind{Type: &pravegav1alpha1.PravegaCluster{}}, &handler.EnqueueRequestForObject{}, clusterChangedPredicate)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to secondary resources and requeue the owner PravegaCluster
+	for _, t := range []runtime.Object{
+		&appsv1.StatefulSet{},
+		&appsv1.Deployment{},
+		&corev1.Service{},
+		&corev1.ConfigMap{},
+		&policyv1beta1.PodDisruptionBudget{},
+	} {
+		err = c.Watch(&source.Kind{Type: t}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &pravegav1alpha1.PravegaCluster{},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Pods are owned by the stateful sets and replica sets, so they are mapped
+	// back to the PravegaCluster through their labels. So are the persistent
+	// volume claims created by the stateful sets, which carry the same labels,
+	// so that their owner references are synced with their reclaim policy as
+	// soon as they are created.
+	for _, t := range []runtime.Object{&corev1.Pod{}, &corev1.PersistentVolumeClaim{}} {
+		err = c.Watch(&source.Kind{Type: t}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(podToPravegaCluster),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// ZookeeperClusters are only watched if the zookeeper operator is
+	// installed. The kinds are discovered once at startup, so the clusters
+	// referencing one cannot be deployed until the operator is restarted
+	gvk := zookeeperClusterGVK
+	if _, err = mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		log.Warnf("not watching %s objects, the clusters referencing one are not deployed until the operator "+
+			"is restarted after installing the zookeeper operator: %v", gvk.Kind, err)
+		return nil
+	}
+	err = c.Watch(&source.Kind{Type: newZookeeperCluster()}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &zookeeperClusterMapper{client: mgr.GetClient()},
+	})
 	if err != nil {
 		return err
 	}
@@ -68,14 +127,58 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	return nil
 }
 
+// clusterChangedPredicate filters out the updates of a PravegaCluster that only
+// change its status. Otherwise every status update of the operator would
+// requeue the cluster right away, regardless of the delay requested by the
+// reconciliation. The annotations, labels and finalizers are not part of the
+// generation, so their changes are let through.
+var clusterChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if e.MetaOld == nil || e.MetaNew == nil {
+			return true
+		}
+		return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+			!reflect.DeepEqual(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations()) ||
+			!reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels()) ||
+			!reflect.DeepEqual(e.MetaOld.GetFinalizers(), e.MetaNew.GetFinalizers()) ||
+			!e.MetaOld.GetDeletionTimestamp().Equal(e.MetaNew.GetDeletionTimestamp())
+	},
+}
+
+// podToPravegaCluster maps a Pravega pod, or persistent volume claim, to the
+// request of the PravegaCluster it belongs to
+func podToPravegaCluster(o handler.MapObject) []reconcile.Request {
+	labels := o.Meta.GetLabels()
+	if labels["app"] != "pravega-cluster" || labels["pravega_cluster"] == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{
+				Namespace: o.Meta.GetNamespace(),
+				Name:      labels["pravega_cluster"],
+			},
+		},
+	}
+}
+
 var _ reconcile.Reconciler = &ReconcilePravegaCluster{}
 
 // ReconcilePravegaCluster reconciles a PravegaCluster object
 type ReconcilePravegaCluster struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
-	scheme *runtime.Scheme
+	client   client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+
+	// reconcileIDs holds the ID of the reconciliation in progress of each
+	// cluster, used to correlate the log lines of a reconciliation
+	reconcileIDs sync.Map
+
+	// zookeeperCleanups holds the attempt in progress to delete the metadata
+	// of each deleted cluster from ZooKeeper
+	zookeeperCleanups sync.Map
 }
 
 // Reconcile reads that state of the cluster for a PravegaCluster object and makes changes based on the state read
@@ -83,29 +186,70 @@ type ReconcilePravegaCluster struct {
 // Note:
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
-func (r *ReconcilePravegaCluster) Reconcile(request reconcile.Request) (reconcile.Result, error) {
-	log.Printf("Reconciling PravegaCluster %s/%s\n", request.Namespace, request.Name)
+func (r *ReconcilePravegaCluster) Reconcile(request reconcile.Request) (result reconcile.Result, err error) {
+	start := time.Now()
+	r.startReconcile(request.NamespacedName)
+	defer r.endReconcile(request.NamespacedName)
+	r.requestLogger(request.NamespacedName).Info("reconciling PravegaCluster")
 
 	// Fetch the PravegaCluster instance
 	pravegaCluster := &pravegav1alpha1.PravegaCluster{}
-	err := r.client.Get(context.TODO(), request.NamespacedName, pravegaCluster)
+	err = r.client.Get(context.TODO(), request.NamespacedName, pravegaCluster)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
-			log.Printf("PravegaCluster %s/%s not found. Ignoring since object must be deleted\n", request.Namespace, request.Name)
+			r.requestLogger(request.NamespacedName).Info("PravegaCluster not found. Ignoring since object must be deleted")
+			metrics.DeleteCluster(request.Namespace, request.Name)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
-		log.Printf("failed to get PravegaCluster: %v", err)
+		r.requestLogger(request.NamespacedName).Errorf("failed to get PravegaCluster: %v", err)
 		return reconcile.Result{}, err
 	}
 
-	// Set default configuration for unspecified values
+	defer func() {
+		metrics.ObserveReconcile(request.Namespace, request.Name, start, err)
+	}()
+
+	if !pravegaCluster.DeletionTimestamp.IsZero() {
+		// Clean up zookeeper metadata
+		result, err = r.reconcileDeletion(pravegaCluster)
+		if err != nil {
+			r.logger(pravegaCluster).Errorf("failed to clean up zookeeper: %v", err)
+		}
+		return result, err
+	}
+
+	if pravegaCluster.IsPaused() {
+		err = r.reconcilePaused(pravegaCluster)
+		if err != nil {
+			r.logger(pravegaCluster).Errorf("failed to reconcile paused cluster: %v", err)
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{RequeueAfter: config.ResyncPeriod}, nil
+	}
+	restartUpgradeOnResume(pravegaCluster)
+
+	if config.ValidateSpec {
+		valid, err := r.validateSpec(pravegaCluster)
+		if err != nil {
+			r.logger(pravegaCluster).Errorf("failed to update the status of an invalid cluster: %v", err)
+			return reconcile.Result{}, err
+		}
+		if !valid {
+			// The update of the spec triggers the next reconciliation
+			return reconcile.Result{}, nil
+		}
+	}
+
+	// The mutating webhook sets the defaults when the cluster is created or
+	// updated. They are only set here when the webhook is disabled, or for
+	// the clusters admitted before the webhook set them
 	changed := pravegaCluster.WithDefaults()
 	if changed {
-		log.Printf("Setting default settings for pravega-cluster: %s", request.Name)
+		r.logger(pravegaCluster).Info("setting default settings")
 		if err = r.client.Update(context.TODO(), pravegaCluster); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -113,19 +257,31 @@ func (r *ReconcilePravegaCluster) Reconcile(request reconcile.Request) (reconcil
 	}
 
 	err = r.run(pravegaCluster)
+	observeUpgrade(pravegaCluster)
 	if err != nil {
-		log.Printf("failed to reconcile pravega cluster (%s): %v", pravegaCluster.Name, err)
+		r.logger(pravegaCluster).Errorf("failed to reconcile cluster: %v", err)
 		return reconcile.Result{}, err
 	}
 
-	return reconcile.Result{RequeueAfter: ReconcileTime}, nil
+	// Changes to owned resources trigger a reconciliation. The periodic
+	// resync is a safety net, e.g. to detect upgrades that stopped progressing
+	return reconcile.Result{RequeueAfter: config.ResyncPeriod}, nil
 }
 
 func (r *ReconcilePravegaCluster) run(p *pravegav1alpha1.PravegaCluster) (err error) {
-	// Clean up zookeeper metadata
 	err = r.reconcileFinalizers(p)
 	if err != nil {
-		return fmt.Errorf("failed to clean up zookeeper: %v", err)
+		return err
+	}
+
+	waiting, err := r.syncZookeeperUri(p)
+	if err != nil {
+		return fmt.Errorf("failed to sync zookeeper uri: %v", err)
+	}
+	if waiting {
+		// The watch on the ZookeeperCluster or the periodic resync triggers
+		// the next reconciliation
+		return nil
 	}
 
 	err = r.deployCluster(p)
@@ -143,6 +299,11 @@ func (r *ReconcilePravegaCluster) run(p *pravegav1alpha1.PravegaCluster) (err er
 		return fmt.Errorf("failed to sync cluster version: %v", err)
 	}
 
+	err = r.syncClusterConfig(p)
+	if err != nil {
+		return fmt.Errorf("failed to sync cluster config: %v", err)
+	}
+
 	err = r.reconcileClusterStatus(p)
 	if err != nil {
 		return fmt.Errorf("failed to reconcile cluster status: %v", err)
@@ -153,50 +314,45 @@ func (r *ReconcilePravegaCluster) run(p *pravegav1alpha1.PravegaCluster) (err er
 func (r *ReconcilePravegaCluster) deployCluster(p *pravegav1alpha1.PravegaCluster) (err error) {
 	err = r.deployBookie(p)
 	if err != nil {
-		log.Printf("failed to deploy bookie: %v", err)
+		r.componentLogger(p, bookieComponent).Errorf("failed to deploy: %v", err)
 		return err
 	}
 
 	err = r.deployController(p)
 	if err != nil {
-		log.Printf("failed to deploy controller: %v", err)
+		r.componentLogger(p, controllerComponent).Errorf("failed to deploy: %v", err)
 		return err
 	}
 
 	err = r.deploySegmentStore(p)
 	if err != nil {
-		log.Printf("failed to deploy segment store: %v", err)
+		r.componentLogger(p, segmentStoreComponent).Errorf("failed to deploy: %v", err)
 		return err
 	}
 	return nil
 }
 
 func (r *ReconcilePravegaCluster) deployController(p *pravegav1alpha1.PravegaCluster) (err error) {
-	pdb := pravega.MakeControllerPodDisruptionBudget(p)
-	controllerutil.SetControllerReference(p, pdb, r.scheme)
-	err = r.client.Create(context.TODO(), pdb)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	err = r.syncPodDisruptionBudget(p, pravega.MakeControllerPodDisruptionBudget(p))
+	if err != nil {
 		return err
 	}
 
-	configMap := pravega.MakeControllerConfigMap(p)
-	controllerutil.SetControllerReference(p, configMap, r.scheme)
-	err = r.client.Create(context.TODO(), configMap)
-	if err != nil && !errors.IsAlreadyExists(err) {
-		return err
+	if !isUpgradePending(p) {
+		// During an upgrade, the config map is updated along with the pod template
+		err = r.syncConfigMap(p, pravega.MakeControllerConfigMap(p))
+		if err != nil {
+			return err
+		}
 	}
 
-	deployment := pravega.MakeControllerDeployment(p)
-	controllerutil.SetControllerReference(p, deployment, r.scheme)
-	err = r.client.Create(context.TODO(), deployment)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	err = r.syncDeployment(p, pravega.MakeControllerDeployment(p))
+	if err != nil {
 		return err
 	}
 
-	service := pravega.MakeControllerService(p)
-	controllerutil.SetControllerReference(p, service, r.scheme)
-	err = r.client.Create(context.TODO(), service)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	err = r.syncService(p, pravega.MakeControllerService(p))
+	if err != nil {
 		return err
 	}
 
@@ -204,46 +360,40 @@ func (r *ReconcilePravegaCluster) deployController(p *pravegav1alpha1.PravegaClu
 }
 
 func (r *ReconcilePravegaCluster) deploySegmentStore(p *pravegav1alpha1.PravegaCluster) (err error) {
-
-	headlessService := pravega.MakeSegmentStoreHeadlessService(p)
-	controllerutil.SetControllerReference(p, headlessService, r.scheme)
-	err = r.client.Create(context.TODO(), headlessService)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	err = r.syncService(p, pravega.MakeSegmentStoreHeadlessService(p))
+	if err != nil {
 		return err
 	}
 
 	if p.Spec.ExternalAccess.Enabled {
 		services := pravega.MakeSegmentStoreExternalServices(p)
 		for _, service := range services {
-			controllerutil.SetControllerReference(p, service, r.scheme)
-			err = r.client.Create(context.TODO(), service)
-			if err != nil && !errors.IsAlreadyExists(err) {
+			err = r.syncService(p, service)
+			if err != nil {
 				return err
 			}
 		}
 	}
 
-	pdb := pravega.MakeSegmentstorePodDisruptionBudget(p)
-	controllerutil.SetControllerReference(p, pdb, r.scheme)
-	err = r.client.Create(context.TODO(), pdb)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	err = r.syncPodDisruptionBudget(p, pravega.MakeSegmentstorePodDisruptionBudget(p))
+	if err != nil {
 		return err
 	}
 
-	configMap := pravega.MakeSegmentstoreConfigMap(p)
-	controllerutil.SetControllerReference(p, configMap, r.scheme)
-	err = r.client.Create(context.TODO(), configMap)
-	if err != nil && !errors.IsAlreadyExists(err) {
-		return err
+	if !isUpgradePending(p) {
+		err = r.syncConfigMap(p, pravega.MakeSegmentstoreConfigMap(p))
+		if err != nil {
+			return err
+		}
 	}
 
-	statefulSet := pravega.MakeSegmentStoreStatefulSet(p)
-	controllerutil.SetControllerReference(p, statefulSet, r.scheme)
-	for i := range statefulSet.Spec.VolumeClaimTemplates {
-		controllerutil.SetControllerReference(p, &statefulSet.Spec.VolumeClaimTemplates[i], r.scheme)
+	err = r.syncStatefulSet(p, pravega.MakeSegmentStoreStatefulSet(p))
+	if err != nil {
+		return err
 	}
-	err = r.client.Create(context.TODO(), statefulSet)
-	if err != nil && !errors.IsAlreadyExists(err) {
+
+	err = r.syncPvcReclaimPolicy(p, util.LabelsForSegmentStore(p), p.Spec.Pravega.CacheVolumeReclaimPolicy)
+	if err != nil {
 		return err
 	}
 
@@ -251,40 +401,71 @@ func (r *ReconcilePravegaCluster) deploySegmentStore(p *pravegav1alpha1.PravegaC
 }
 
 func (r *ReconcilePravegaCluster) deployBookie(p *pravegav1alpha1.PravegaCluster) (err error) {
-	headlessService := pravega.MakeBookieHeadlessService(p)
-	controllerutil.SetControllerReference(p, headlessService, r.scheme)
-	err = r.client.Create(context.TODO(), headlessService)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	err = r.syncService(p, pravega.MakeBookieHeadlessService(p))
+	if err != nil {
 		return err
 	}
 
-	pdb := pravega.MakeBookiePodDisruptionBudget(p)
-	controllerutil.SetControllerReference(p, pdb, r.scheme)
-	err = r.client.Create(context.TODO(), pdb)
-	if err != nil && !errors.IsAlreadyExists(err) {
+	err = r.syncPodDisruptionBudget(p, pravega.MakeBookiePodDisruptionBudget(p))
+	if err != nil {
 		return err
 	}
 
-	configMap := pravega.MakeBookieConfigMap(p)
-	controllerutil.SetControllerReference(p, configMap, r.scheme)
-	err = r.client.Create(context.TODO(), configMap)
-	if err != nil && !errors.IsAlreadyExists(err) {
-		return err
+	if !isUpgradePending(p) {
+		err = r.syncConfigMap(p, pravega.MakeBookieConfigMap(p))
+		if err != nil {
+			return err
+		}
 	}
 
-	statefulSet := pravega.MakeBookieStatefulSet(p)
-	controllerutil.SetControllerReference(p, statefulSet, r.scheme)
-	for i := range statefulSet.Spec.VolumeClaimTemplates {
-		controllerutil.SetControllerReference(p, &statefulSet.Spec.VolumeClaimTemplates[i], r.scheme)
+	err = r.syncStatefulSet(p, pravega.MakeBookieStatefulSet(p))
+	if err != nil {
+		return err
 	}
-	err = r.client.Create(context.TODO(), statefulSet)
-	if err != nil && !errors.IsAlreadyExists(err) {
+
+	err = r.syncPvcReclaimPolicy(p, util.LabelsForBookie(p), p.Spec.Bookkeeper.Storage.ReclaimPolicy)
+	if err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// observeUpgrade updates the upgrade metrics of the cluster from its
+// Upgrading condition
+func observeUpgrade(p *pravegav1alpha1.PravegaCluster) {
+	_, upgradeCondition := p.Status.GetClusterCondition(pravegav1alpha1.ClusterConditionUpgrading)
+	if upgradeCondition == nil || upgradeCondition.Status != corev1.ConditionTrue {
+		metrics.SetUpgradePhase(p.Namespace, p.Name, metrics.UpgradePhaseNone, 0)
+		return
+	}
+
+	phase := upgradeCondition.Reason
+	if phase == "" {
+		// The upgrade has been triggered but no component has been updated yet
+		phase = "Pending"
+	}
+	var elapsed time.Duration
+	if started, err := time.Parse(time.RFC3339, upgradeCondition.LastTransitionTime); err == nil {
+		elapsed = time.Since(started)
+	}
+	metrics.SetUpgradePhase(p.Namespace, p.Name, phase, elapsed)
+}
+
+// isUpgradePending returns true if the cluster or BookKeeper version in the
+// spec has not been rolled out yet, or if a failed upgrade is being rolled
+// back. Config maps depend on the versions, so they are left to the upgrade
+// process in that case.
+func isUpgradePending(p *pravegav1alpha1.PravegaCluster) bool {
+	if p.Status.IsRollingBack() {
+		return true
+	}
+	if p.Status.CurrentBookkeeperVersion != "" && p.BookkeeperVersion() != p.Status.CurrentBookkeeperVersion {
+		return true
+	}
+	return p.Status.CurrentVersion != "" && p.Spec.Version != p.Status.CurrentVersion
+}
+
 func (r *ReconcilePravegaCluster) syncClusterSize(p *pravegav1alpha1.PravegaCluster) (err error) {
 	err = r.syncBookieSize(p)
 	if err != nil {
@@ -313,13 +494,16 @@ func (r *ReconcilePravegaCluster) syncBookieSize(p *pravegav1alpha1.PravegaClust
 	}
 
 	if *sts.Spec.Replicas != p.Spec.Bookkeeper.Replicas {
+		previous := *sts.Spec.Replicas
 		sts.Spec.Replicas = &(p.Spec.Bookkeeper.Replicas)
 		err = r.client.Update(context.TODO(), sts)
 		if err != nil {
 			return fmt.Errorf("failed to update size of stateful-set (%s): %v", sts.Name, err)
 		}
+		r.recorder.Eventf(p, corev1.EventTypeNormal, ScaledReason,
+			"Scaled stateful-set %s from %d to %d replicas", sts.Name, previous, *sts.Spec.Replicas)
 
-		err = r.syncStatefulSetPvc(sts)
+		err = r.syncStatefulSetPvc(p, sts, p.Spec.Bookkeeper.Storage.ReclaimPolicy)
 		if err != nil {
 			return fmt.Errorf("failed to sync pvcs of stateful-set (%s): %v", sts.Name, err)
 		}
@@ -336,13 +520,16 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreSize(p *pravegav1alpha1.Praveg
 	}
 
 	if *sts.Spec.Replicas != p.Spec.Pravega.SegmentStoreReplicas {
+		previous := *sts.Spec.Replicas
 		sts.Spec.Replicas = &(p.Spec.Pravega.SegmentStoreReplicas)
 		err = r.client.Update(context.TODO(), sts)
 		if err != nil {
 			return fmt.Errorf("failed to update size of stateful-set (%s): %v", sts.Name, err)
 		}
+		r.recorder.Eventf(p, corev1.EventTypeNormal, ScaledReason,
+			"Scaled stateful-set %s from %d to %d replicas", sts.Name, previous, *sts.Spec.Replicas)
 
-		err = r.syncStatefulSetPvc(sts)
+		err = r.syncStatefulSetPvc(p, sts, p.Spec.Pravega.CacheVolumeReclaimPolicy)
 		if err != nil {
 			return fmt.Errorf("failed to sync pvcs of stateful-set (%s): %v", sts.Name, err)
 		}
@@ -359,87 +546,126 @@ func (r *ReconcilePravegaCluster) syncControllerSize(p *pravegav1alpha1.PravegaC
 	}
 
 	if *deploy.Spec.Replicas != p.Spec.Pravega.ControllerReplicas {
+		previous := *deploy.Spec.Replicas
 		deploy.Spec.Replicas = &(p.Spec.Pravega.ControllerReplicas)
 		err = r.client.Update(context.TODO(), deploy)
 		if err != nil {
 			return fmt.Errorf("failed to update size of deployment (%s): %v", deploy.Name, err)
 		}
+		r.recorder.Eventf(p, corev1.EventTypeNormal, ScaledReason,
+			"Scaled deployment %s from %d to %d replicas", deploy.Name, previous, *deploy.Spec.Replicas)
 	}
 	return nil
 }
 
 func (r *ReconcilePravegaCluster) reconcileFinalizers(p *pravegav1alpha1.PravegaCluster) (err error) {
-	if p.DeletionTimestamp.IsZero() {
-		if !util.ContainsString(p.ObjectMeta.Finalizers, util.ZkFinalizer) {
-			p.ObjectMeta.Finalizers = append(p.ObjectMeta.Finalizers, util.ZkFinalizer)
-			if err = r.client.Update(context.TODO(), p); err != nil {
-				return fmt.Errorf("failed to add the finalizer (%s): %v", p.Name, err)
-			}
+	if !util.ContainsString(p.ObjectMeta.Finalizers, util.ZkFinalizer) {
+		p.ObjectMeta.Finalizers = append(p.ObjectMeta.Finalizers, util.ZkFinalizer)
+		if err = r.client.Update(context.TODO(), p); err != nil {
+			return fmt.Errorf("failed to add the finalizer (%s): %v", p.Name, err)
 		}
-	} else {
-		if util.ContainsString(p.ObjectMeta.Finalizers, util.ZkFinalizer) {
-			p.ObjectMeta.Finalizers = util.RemoveString(p.ObjectMeta.Finalizers, util.ZkFinalizer)
-			if err = r.client.Update(context.TODO(), p); err != nil {
-				return fmt.Errorf("failed to update Pravega object (%s): %v", p.Name, err)
+	}
+	return nil
+}
+
+// syncStatefulSetPvc deletes the persistent volume claims of the pods removed
+// by a scale-down of the stateful set, unless the reclaim policy retains them.
+func (r *ReconcilePravegaCluster) syncStatefulSetPvc(p *pravegav1alpha1.PravegaCluster, sts *appsv1.StatefulSet,
+	policy pravegav1alpha1.ReclaimPolicy) error {
+	pvcList, err := r.getPvcs(sts.Namespace, sts.Spec.Template.Labels)
+	if err != nil {
+		return err
+	}
+
+	for _, pvcItem := range pvcList.Items {
+		if util.PvcIsOrphan(pvcItem.Name, *sts.Spec.Replicas) {
+			if policy != pravegav1alpha1.ReclaimPolicyDelete {
+				r.recorder.Eventf(p, corev1.EventTypeNormal, RetainedOrphanPvcReason,
+					"Retained pvc %s of stateful-set %s scaled down to %d replicas", pvcItem.Name, sts.Name, *sts.Spec.Replicas)
+				continue
 			}
-			if err = r.cleanUpZookeeperMeta(p); err != nil {
-				return fmt.Errorf("failed to clean up metadata (%s): %v", p.Name, err)
+
+			pvcDelete := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      pvcItem.Name,
+					Namespace: pvcItem.Namespace,
+				},
+			}
+
+			err = r.client.Delete(context.TODO(), pvcDelete)
+			if err != nil {
+				return fmt.Errorf("failed to delete pvc: %v", err)
 			}
+			r.recorder.Eventf(p, corev1.EventTypeNormal, DeletedOrphanPvcReason,
+				"Deleted pvc %s of stateful-set %s scaled down to %d replicas", pvcItem.Name, sts.Name, *sts.Spec.Replicas)
 		}
 	}
 	return nil
 }
 
-func (r *ReconcilePravegaCluster) cleanUpZookeeperMeta(p *pravegav1alpha1.PravegaCluster) (err error) {
-	if err = util.WaitForClusterToTerminate(r.client, p); err != nil {
-		return fmt.Errorf("failed to wait for cluster pods termination (%s): %v", p.Name, err)
+// syncPvcReclaimPolicy sets the cluster as the controller of the persistent
+// volume claims of a component if their reclaim policy is Delete, so that the
+// garbage collector deletes them along with the cluster, and removes that
+// owner reference otherwise.
+func (r *ReconcilePravegaCluster) syncPvcReclaimPolicy(p *pravegav1alpha1.PravegaCluster, podLabels map[string]string,
+	policy pravegav1alpha1.ReclaimPolicy) error {
+	pvcList, err := r.getPvcs(p.Namespace, podLabels)
+	if err != nil {
+		return err
 	}
 
-	if err = util.DeleteAllZnodes(p); err != nil {
-		return fmt.Errorf("failed to delete zookeeper znodes for (%s): %v", p.Name, err)
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		owned := metav1.IsControlledBy(pvc, p)
+		if owned == (policy == pravegav1alpha1.ReclaimPolicyDelete) {
+			continue
+		}
+
+		if owned {
+			var refs []metav1.OwnerReference
+			for _, ref := range pvc.OwnerReferences {
+				if ref.UID != p.UID {
+					refs = append(refs, ref)
+				}
+			}
+			pvc.OwnerReferences = refs
+		} else if err = controllerutil.SetControllerReference(p, pvc, r.scheme); err != nil {
+			return fmt.Errorf("failed to set owner of pvc (%s): %v", pvc.Name, err)
+		}
+
+		r.componentLogger(p, podLabels["component"]).Infof("applying reclaim policy %s to pvc (%s)", policy, pvc.Name)
+		err = r.client.Update(context.TODO(), pvc)
+		if err != nil {
+			return fmt.Errorf("failed to update pvc (%s): %v", pvc.Name, err)
+		}
 	}
 	return nil
 }
 
-func (r *ReconcilePravegaCluster) syncStatefulSetPvc(sts *appsv1.StatefulSet) error {
+func (r *ReconcilePravegaCluster) getPvcs(namespace string, podLabels map[string]string) (*corev1.PersistentVolumeClaimList, error) {
 	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
-		MatchLabels: sts.Spec.Template.Labels,
+		MatchLabels: podLabels,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to convert label selector: %v", err)
+		return nil, fmt.Errorf("failed to convert label selector: %v", err)
 	}
 
 	pvcList := &corev1.PersistentVolumeClaimList{}
 	pvclistOps := &client.ListOptions{
-		Namespace:     sts.Namespace,
+		Namespace:     namespace,
 		LabelSelector: selector,
 	}
 	err = r.client.List(context.TODO(), pvclistOps, pvcList)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	for _, pvcItem := range pvcList.Items {
-		if util.PvcIsOrphan(pvcItem.Name, *sts.Spec.Replicas) {
-			pvcDelete := &corev1.PersistentVolumeClaim{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      pvcItem.Name,
-					Namespace: pvcItem.Namespace,
-				},
-			}
-
-			err = r.client.Delete(context.TODO(), pvcDelete)
-			if err != nil {
-				return fmt.Errorf("failed to delete pvc: %v", err)
-			}
-		}
-	}
-	return nil
+	return pvcList, nil
 }
 
 func (r *ReconcilePravegaCluster) reconcileClusterStatus(p *pravegav1alpha1.PravegaCluster) error {
 
 	p.Status.InitConditions()
+	r.syncPausedCondition(p)
 
 	expectedSize := util.GetClusterExpectedSize(p)
 	listOps := &client.ListOptions{
@@ -477,9 +703,78 @@ func (r *ReconcilePravegaCluster) reconcileClusterStatus(p *pravegav1alpha1.Prav
 	p.Status.Members.Ready = readyMembers
 	p.Status.Members.Unready = unreadyMembers
 
+	p.Status.Bookkeeper = makeComponentStatus(podList.Items, util.LabelsForBookie(p), p.Spec.Bookkeeper.Replicas)
+	p.Status.SegmentStore = makeComponentStatus(podList.Items, util.LabelsForSegmentStore(p), p.Spec.Pravega.SegmentStoreReplicas)
+	p.Status.Controller = makeComponentStatus(podList.Items, util.LabelsForController(p), p.Spec.Pravega.ControllerReplicas)
+
+	err = r.syncUpdatedReplicas(p)
+	if err != nil {
+		return err
+	}
+
+	if !p.IsPaused() {
+		// The spec of a paused cluster is not reconciled
+		p.Status.ObservedGeneration = p.Generation
+	}
+
 	err = r.client.Status().Update(context.TODO(), p)
 	if err != nil {
 		return fmt.Errorf("failed to update cluster status: %v", err)
 	}
 	return nil
 }
+
+// makeComponentStatus returns the status of the component whose pods match
+// the given labels
+func makeComponentStatus(pods []corev1.Pod, podLabels map[string]string, replicas int32) pravegav1alpha1.ComponentStatus {
+	status := pravegav1alpha1.ComponentStatus{
+		Replicas: replicas,
+		Versions: map[string]string{},
+	}
+	selector := labels.SelectorFromSet(podLabels)
+	for i := range pods {
+		pod := &pods[i]
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		status.CurrentReplicas++
+		status.Versions[pod.Name] = util.GetPodVersion(pod)
+		if util.IsPodReady(pod) {
+			status.ReadyReplicas++
+			status.Members.Ready = append(status.Members.Ready, pod.Name)
+		} else {
+			status.Members.Unready = append(status.Members.Unready, pod.Name)
+		}
+	}
+	return status
+}
+
+// syncUpdatedReplicas sets the number of replicas running the latest pod
+// template of each component, as reported by the stateful sets and deployment
+func (r *ReconcilePravegaCluster) syncUpdatedReplicas(p *pravegav1alpha1.PravegaCluster) (err error) {
+	for _, c := range []struct {
+		name   string
+		status *pravegav1alpha1.ComponentStatus
+	}{
+		{util.StatefulSetNameForBookie(p.Name), &p.Status.Bookkeeper},
+		{util.StatefulSetNameForSegmentstore(p.Name), &p.Status.SegmentStore},
+	} {
+		sts := &appsv1.StatefulSet{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: c.name, Namespace: p.Namespace}, sts)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get stateful-set (%s): %v", c.name, err)
+		}
+		// A missing stateful-set, e.g. deleted by hand while the cluster is
+		// paused, has no updated replicas
+		c.status.UpdatedReplicas = sts.Status.UpdatedReplicas
+	}
+
+	deploy := &appsv1.Deployment{}
+	name := util.DeploymentNameForController(p.Name)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, deploy)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get deployment (%s): %v", name, err)
+	}
+	p.Status.Controller.UpdatedReplicas = deploy.Status.UpdatedReplicas
+	return nil
+}
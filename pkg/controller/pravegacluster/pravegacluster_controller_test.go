@@ -17,15 +17,20 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
 	"github.com/pravega/pravega-operator/pkg/util"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	. "github.com/onsi/ginkgo"
@@ -79,7 +84,7 @@ var _ = Describe("PravegaCluster Controller", func() {
 
 			BeforeEach(func() {
 				client = fake.NewFakeClient(p)
-				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
 				res, err = r.Reconcile(req)
 			})
 
@@ -112,7 +117,7 @@ var _ = Describe("PravegaCluster Controller", func() {
 				})
 
 				It("should requeue after ReconfileTime delay", func() {
-					Ω(res.RequeueAfter).To(Equal(ReconcileTime))
+					Ω(res.RequeueAfter).To(Equal(config.ResyncPeriod))
 				})
 
 				Context("Bookkeeper", func() {
@@ -274,7 +279,7 @@ var _ = Describe("PravegaCluster Controller", func() {
 				}
 				p.WithDefaults()
 				client = fake.NewFakeClient(p)
-				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
 				res, err = r.Reconcile(req)
 			})
 
@@ -283,7 +288,7 @@ var _ = Describe("PravegaCluster Controller", func() {
 			})
 
 			It("should requeue after ReconfileTime delay", func() {
-				Ω(res.RequeueAfter).To(Equal(ReconcileTime))
+				Ω(res.RequeueAfter).To(Equal(config.ResyncPeriod))
 			})
 
 			Context("Cluster", func() {
@@ -405,4 +410,182 @@ var _ = Describe("PravegaCluster Controller", func() {
 			})
 		})
 	})
+
+	Context("Pod watch", func() {
+		It("should map pravega pods to their cluster", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example-bookie-0",
+					Namespace: Namespace,
+					Labels:    util.LabelsForBookie(&v1alpha1.PravegaCluster{ObjectMeta: metav1.ObjectMeta{Name: Name}}),
+				},
+			}
+			requests := podToPravegaCluster(handler.MapObject{Meta: pod, Object: pod})
+			Ω(requests).Should(ConsistOf(reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: Name, Namespace: Namespace},
+			}))
+		})
+
+		It("should ignore other pods", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "other",
+					Namespace: Namespace,
+					Labels:    map[string]string{"app": "other"},
+				},
+			}
+			Ω(podToPravegaCluster(handler.MapObject{Meta: pod, Object: pod})).Should(BeEmpty())
+		})
+	})
+
+	Context("Cluster watch", func() {
+		var old, p *v1alpha1.PravegaCluster
+
+		update := func() bool {
+			return clusterChangedPredicate.Update(event.UpdateEvent{MetaOld: old, ObjectOld: old, MetaNew: p, ObjectNew: p})
+		}
+
+		BeforeEach(func() {
+			old = &v1alpha1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       Name,
+					Namespace:  Namespace,
+					Generation: 1,
+					Finalizers: []string{util.ZkFinalizer},
+				},
+			}
+			p = old.DeepCopy()
+		})
+
+		It("should ignore the updates of the status", func() {
+			p.Status.ZookeeperCleanup = &v1alpha1.ZookeeperCleanupStatus{Attempts: 1}
+			Ω(update()).Should(BeFalse())
+		})
+
+		It("should pass the updates of the spec", func() {
+			p.Generation = 2
+			Ω(update()).Should(BeTrue())
+		})
+
+		It("should pass the updates of the metadata", func() {
+			p.Annotations = map[string]string{v1alpha1.PausedAnnotation: "true"}
+			Ω(update()).Should(BeTrue())
+
+			p = old.DeepCopy()
+			p.Finalizers = nil
+			Ω(update()).Should(BeTrue())
+
+			p = old.DeepCopy()
+			now := metav1.Now()
+			p.DeletionTimestamp = &now
+			Ω(update()).Should(BeTrue())
+		})
+	})
 })
+
+// testCluster is a Pravega cluster deployed by the reconciler with a fake
+// client, for the tests that drive the cluster through several
+// reconciliations, e.g. upgrades
+type testCluster struct {
+	r        *ReconcilePravegaCluster
+	client   client.Client
+	recorder *record.FakeRecorder
+	req      reconcile.Request
+}
+
+// newTestCluster returns a Pravega cluster at version 0.5.0 with the defaults
+func newTestCluster() *v1alpha1.PravegaCluster {
+	p := &v1alpha1.PravegaCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+		},
+	}
+	p.Spec.Version = "0.5.0"
+	p.WithDefaults()
+	return p
+}
+
+// deployTestCluster creates a Pravega cluster and reconciles it until its
+// components are deployed
+func deployTestCluster(p *v1alpha1.PravegaCluster) *testCluster {
+	s := scheme.Scheme
+	s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+	c := &testCluster{
+		client:   fake.NewFakeClient(p),
+		recorder: record.NewFakeRecorder(100),
+		req: reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: p.Name, Namespace: p.Namespace},
+		},
+	}
+	c.r = &ReconcilePravegaCluster{client: c.client, scheme: s, recorder: c.recorder}
+	c.reconcile(2)
+	return c
+}
+
+// reconcile reconciles the cluster a number of times
+func (c *testCluster) reconcile(times int) {
+	for i := 0; i < times; i++ {
+		c.r.Reconcile(c.req)
+	}
+}
+
+// get returns the stored cluster
+func (c *testCluster) get() *v1alpha1.PravegaCluster {
+	p := &v1alpha1.PravegaCluster{}
+	Ω(c.client.Get(context.TODO(), c.req.NamespacedName, p)).Should(Succeed())
+	return p
+}
+
+// changeSpec changes the spec of the stored cluster, and marks its pods as
+// ready to bypass the pods ready check of the upgrades and rollouts
+func (c *testCluster) changeSpec(change func(spec *v1alpha1.ClusterSpec)) {
+	p := c.get()
+	change(&p.Spec)
+	p.Status.SetPodsReadyConditionTrue()
+	Ω(c.client.Update(context.TODO(), p)).Should(Succeed())
+}
+
+// statefulSet returns a stateful set of the cluster
+func (c *testCluster) statefulSet(name string) *appsv1.StatefulSet {
+	sts := &appsv1.StatefulSet{}
+	nn := types.NamespacedName{Name: name, Namespace: c.req.Namespace}
+	Ω(c.client.Get(context.TODO(), nn, sts)).Should(Succeed())
+	return sts
+}
+
+// setStatefulSetStatus sets the replica counts of the status of a stateful
+// set, which the fake client does not update
+func (c *testCluster) setStatefulSetStatus(name string, replicas, updated, ready int32) {
+	sts := c.statefulSet(name)
+	sts.Status.Replicas = replicas
+	sts.Status.UpdatedReplicas = updated
+	sts.Status.ReadyReplicas = ready
+	Ω(c.client.Update(context.TODO(), sts)).Should(Succeed())
+}
+
+// podExists returns whether a pod of the cluster exists
+func (c *testCluster) podExists(name string) bool {
+	err := c.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: c.req.Namespace}, &corev1.Pod{})
+	if errors.IsNotFound(err) {
+		return false
+	}
+	Ω(err).Should(BeNil())
+	return true
+}
+
+// upgradeCondition returns the upgrading condition of the stored cluster
+func (c *testCluster) upgradeCondition() *v1alpha1.ClusterCondition {
+	_, condition := c.get().Status.GetClusterCondition(v1alpha1.ClusterConditionUpgrading)
+	Ω(condition).ShouldNot(BeNil())
+	return condition
+}
+
+// events returns the events recorded since the last call
+func (c *testCluster) events() []string {
+	var events []string
+	for len(c.recorder.Events) > 0 {
+		events = append(events, <-c.recorder.Events)
+	}
+	return events
+}
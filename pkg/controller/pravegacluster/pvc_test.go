@@ -0,0 +1,192 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PVC reclaim policy", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s        = scheme.Scheme
+		r        *ReconcilePravegaCluster
+		client   client.Client
+		recorder *record.FakeRecorder
+		req      reconcile.Request
+		p        *v1alpha1.PravegaCluster
+		ledger   types.NamespacedName
+		cache    types.NamespacedName
+	)
+
+	getPvc := func(nn types.NamespacedName) *corev1.PersistentVolumeClaim {
+		pvc := &corev1.PersistentVolumeClaim{}
+		Ω(client.Get(context.TODO(), nn, pvc)).Should(Succeed())
+		return pvc
+	}
+
+	scaleBookies := func(replicas int32) {
+		foundPravega := &v1alpha1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+		foundPravega.Spec.Bookkeeper.Replicas = replicas
+		Ω(client.Update(context.TODO(), foundPravega)).Should(Succeed())
+		r.Reconcile(req)
+	}
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p = &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		p.WithDefaults()
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+		ledger = types.NamespacedName{Name: "ledger-example-bookie-3", Namespace: Namespace}
+		cache = types.NamespacedName{Name: "cache-example-pravega-segmentstore-0", Namespace: Namespace}
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewFakeClient(p,
+			&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ledger.Name,
+					Namespace: Namespace,
+					Labels:    util.LabelsForBookie(p),
+				},
+			},
+			&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      cache.Name,
+					Namespace: Namespace,
+					Labels:    util.LabelsForSegmentStore(p),
+				},
+			},
+		)
+		recorder = record.NewFakeRecorder(100)
+		r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: recorder}
+		r.Reconcile(req)
+		r.Reconcile(req)
+	})
+
+	Context("With the default policy", func() {
+		It("should make the cluster the controller of the pvcs", func() {
+			Ω(metav1.IsControlledBy(getPvc(ledger), p)).Should(BeTrue())
+			Ω(metav1.IsControlledBy(getPvc(cache), p)).Should(BeTrue())
+		})
+	})
+
+	Context("With the Retain policy", func() {
+		BeforeEach(func() {
+			p.Spec.Bookkeeper.Storage.ReclaimPolicy = v1alpha1.ReclaimPolicyRetain
+			p.Spec.Pravega.CacheVolumeReclaimPolicy = v1alpha1.ReclaimPolicyRetain
+		})
+
+		It("should not make the cluster the controller of the pvcs", func() {
+			Ω(getPvc(ledger).OwnerReferences).Should(BeEmpty())
+			Ω(getPvc(cache).OwnerReferences).Should(BeEmpty())
+		})
+
+		It("should keep the pvcs on scale-down", func() {
+			scaleBookies(4)
+			scaleBookies(3)
+			getPvc(ledger)
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			Ω(events).Should(ContainElement(
+				"Normal RetainedOrphanPVC Retained pvc ledger-example-bookie-3 of stateful-set example-bookie scaled down to 3 replicas"))
+		})
+	})
+
+	Context("When the policy changes to Retain", func() {
+		It("should release the pvcs", func() {
+			foundPravega := &v1alpha1.PravegaCluster{}
+			Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+			foundPravega.Spec.Bookkeeper.Storage.ReclaimPolicy = v1alpha1.ReclaimPolicyRetain
+			foundPravega.Spec.Pravega.CacheVolumeReclaimPolicy = v1alpha1.ReclaimPolicyRetain
+			Ω(client.Update(context.TODO(), foundPravega)).Should(Succeed())
+			r.Reconcile(req)
+			Ω(getPvc(ledger).OwnerReferences).Should(BeEmpty())
+			Ω(getPvc(cache).OwnerReferences).Should(BeEmpty())
+		})
+	})
+
+	Context("When the cluster is deleted", func() {
+		owned := func(nn types.NamespacedName) bool {
+			foundPravega := &v1alpha1.PravegaCluster{}
+			Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+			return metav1.IsControlledBy(getPvc(nn), foundPravega)
+		}
+
+		BeforeEach(func() {
+			p.Spec.Bookkeeper.Storage.ReclaimPolicy = v1alpha1.ReclaimPolicyRetain
+			p.Spec.Pravega.CacheVolumeReclaimPolicy = v1alpha1.ReclaimPolicyRetain
+		})
+
+		JustBeforeEach(func() {
+			foundPravega := &v1alpha1.PravegaCluster{}
+			Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+			now := metav1.Now()
+			foundPravega.DeletionTimestamp = &now
+			Ω(client.Update(context.TODO(), foundPravega)).Should(Succeed())
+			_, err := r.Reconcile(req)
+			Ω(err).Should(BeNil())
+
+			// Simulate a pvc created from the claim templates of a stateful
+			// set created by an older version of the operator, once the
+			// cluster pods are terminated
+			pvc := getPvc(ledger)
+			Ω(controllerutil.SetControllerReference(foundPravega, pvc, s)).Should(Succeed())
+			Ω(client.Update(context.TODO(), pvc)).Should(Succeed())
+			Ω(owned(ledger)).Should(BeTrue())
+
+			_, err = r.Reconcile(req)
+			Ω(err).Should(BeNil())
+		})
+
+		It("should release the retained pvcs before removing the finalizer", func() {
+			Ω(getPvc(ledger).OwnerReferences).Should(BeEmpty())
+			foundPravega := &v1alpha1.PravegaCluster{}
+			Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+			Ω(foundPravega.Finalizers).Should(ContainElement(util.ZkFinalizer))
+		})
+	})
+})
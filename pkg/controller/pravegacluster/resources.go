@@ -0,0 +1,349 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// syncConfigMap creates the config map if it does not exist. Otherwise, it
+// updates the data and labels owned by the operator when they drifted from
+// the desired state.
+func (r *ReconcilePravegaCluster) syncConfigMap(p *pravegav1alpha1.PravegaCluster, desired *corev1.ConfigMap) (err error) {
+	controllerutil.SetControllerReference(p, desired, r.scheme)
+	setConfigKeys(desired)
+
+	current := &corev1.ConfigMap{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.client.Create(context.TODO(), desired)
+		}
+		return fmt.Errorf("failed to get config map (%s): %v", desired.Name, err)
+	}
+
+	changes := mergeConfigMap(current, desired)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	r.componentLogger(p, desired.Labels["component"]).Infof("updating config map (%s): %s changed", current.Name, strings.Join(changes, ", "))
+	err = r.client.Update(context.TODO(), current)
+	if err != nil {
+		return fmt.Errorf("failed to update config map (%s): %v", current.Name, err)
+	}
+	return nil
+}
+
+// syncService creates the service if it does not exist. Otherwise, it updates
+// the fields owned by the operator when they drifted from the desired state.
+// Fields allocated by Kubernetes, such as the cluster IP or node ports, are
+// preserved.
+func (r *ReconcilePravegaCluster) syncService(p *pravegav1alpha1.PravegaCluster, desired *corev1.Service) (err error) {
+	controllerutil.SetControllerReference(p, desired, r.scheme)
+
+	current := &corev1.Service{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.client.Create(context.TODO(), desired)
+		}
+		return fmt.Errorf("failed to get service (%s): %v", desired.Name, err)
+	}
+
+	changes := mergeService(current, desired)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	r.componentLogger(p, desired.Labels["component"]).Infof("updating service (%s): %s changed", current.Name, strings.Join(changes, ", "))
+	err = r.client.Update(context.TODO(), current)
+	if err != nil {
+		return fmt.Errorf("failed to update service (%s): %v", current.Name, err)
+	}
+	return nil
+}
+
+// syncPodDisruptionBudget creates the pod disruption budget if it does not
+// exist. The spec of a pod disruption budget is immutable in the policy/v1beta1
+// API, so the budget is deleted and recreated when its spec drifted.
+func (r *ReconcilePravegaCluster) syncPodDisruptionBudget(p *pravegav1alpha1.PravegaCluster, desired *policyv1beta1.PodDisruptionBudget) (err error) {
+	controllerutil.SetControllerReference(p, desired, r.scheme)
+
+	current := &policyv1beta1.PodDisruptionBudget{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.client.Create(context.TODO(), desired)
+		}
+		return fmt.Errorf("failed to get pod disruption budget (%s): %v", desired.Name, err)
+	}
+
+	if reflect.DeepEqual(current.Spec.MinAvailable, desired.Spec.MinAvailable) &&
+		reflect.DeepEqual(current.Spec.MaxUnavailable, desired.Spec.MaxUnavailable) &&
+		reflect.DeepEqual(current.Spec.Selector, desired.Spec.Selector) {
+		return nil
+	}
+
+	r.componentLogger(p, desired.Labels["component"]).Infof("recreating pod disruption budget (%s): spec changed", current.Name)
+	err = r.client.Delete(context.TODO(), current)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pod disruption budget (%s): %v", current.Name, err)
+	}
+	err = r.client.Create(context.TODO(), desired)
+	if err != nil {
+		return fmt.Errorf("failed to create pod disruption budget (%s): %v", desired.Name, err)
+	}
+	return nil
+}
+
+// syncStatefulSet creates the stateful set if it does not exist. Pod template
+// changes on existing stateful sets are rolled out by the upgrade process, and
+// the number of replicas is synced by syncClusterSize.
+func (r *ReconcilePravegaCluster) syncStatefulSet(p *pravegav1alpha1.PravegaCluster, desired *appsv1.StatefulSet) (err error) {
+	// The owner references of the persistent volume claims depend on their
+	// reclaim policy and are synced by syncPvcReclaimPolicy
+	controllerutil.SetControllerReference(p, desired, r.scheme)
+
+	current := &appsv1.StatefulSet{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			err = r.client.Create(context.TODO(), desired)
+			if err != nil {
+				return fmt.Errorf("failed to create stateful-set (%s): %v", desired.Name, err)
+			}
+			r.recorder.Eventf(p, corev1.EventTypeNormal, CreatedReason,
+				"Created stateful-set %s with %d replicas", desired.Name, *desired.Spec.Replicas)
+			return nil
+		}
+		return fmt.Errorf("failed to get stateful-set (%s): %v", desired.Name, err)
+	}
+
+	if !mergeLabels(&current.ObjectMeta.Labels, desired.Labels) {
+		return nil
+	}
+
+	r.componentLogger(p, desired.Labels["component"]).Infof("updating stateful-set (%s): labels changed", current.Name)
+	err = r.client.Update(context.TODO(), current)
+	if err != nil {
+		return fmt.Errorf("failed to update stateful-set (%s): %v", current.Name, err)
+	}
+	return nil
+}
+
+// syncDeployment creates the deployment if it does not exist. Otherwise, it
+// updates the deployment settings owned by the operator. Pod template changes
+// are rolled out by the upgrade process, and the number of replicas is synced
+// by syncClusterSize.
+func (r *ReconcilePravegaCluster) syncDeployment(p *pravegav1alpha1.PravegaCluster, desired *appsv1.Deployment) (err error) {
+	controllerutil.SetControllerReference(p, desired, r.scheme)
+
+	current := &appsv1.Deployment{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			err = r.client.Create(context.TODO(), desired)
+			if err != nil {
+				return fmt.Errorf("failed to create deployment (%s): %v", desired.Name, err)
+			}
+			r.recorder.Eventf(p, corev1.EventTypeNormal, CreatedReason,
+				"Created deployment %s with %d replicas", desired.Name, *desired.Spec.Replicas)
+			return nil
+		}
+		return fmt.Errorf("failed to get deployment (%s): %v", desired.Name, err)
+	}
+
+	var changes []string
+	if mergeLabels(&current.ObjectMeta.Labels, desired.Labels) {
+		changes = append(changes, "labels")
+	}
+	if !reflect.DeepEqual(current.Spec.ProgressDeadlineSeconds, desired.Spec.ProgressDeadlineSeconds) {
+		current.Spec.ProgressDeadlineSeconds = desired.Spec.ProgressDeadlineSeconds
+		changes = append(changes, "progressDeadlineSeconds")
+	}
+	if !reflect.DeepEqual(current.Spec.RevisionHistoryLimit, desired.Spec.RevisionHistoryLimit) {
+		current.Spec.RevisionHistoryLimit = desired.Spec.RevisionHistoryLimit
+		changes = append(changes, "revisionHistoryLimit")
+	}
+	if !reflect.DeepEqual(current.Spec.Strategy, desired.Spec.Strategy) {
+		current.Spec.Strategy = desired.Spec.Strategy
+		changes = append(changes, "strategy")
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	r.componentLogger(p, desired.Labels["component"]).Infof("updating deployment (%s): %s changed", current.Name, strings.Join(changes, ", "))
+	err = r.client.Update(context.TODO(), current)
+	if err != nil {
+		return fmt.Errorf("failed to update deployment (%s): %v", current.Name, err)
+	}
+	return nil
+}
+
+// mergeConfigMap copies the operator owned fields from desired into current
+// and returns the list of fields that changed. The data keys owned by the
+// operator are those of desired and those listed in the ConfigKeysAnnotation
+// of current, the keys that are no longer desired are removed. Other keys are
+// left untouched.
+func mergeConfigMap(current, desired *corev1.ConfigMap) (changes []string) {
+	owned := configKeys(current)
+
+	if mergeLabels(&current.ObjectMeta.Labels, desired.Labels) {
+		changes = append(changes, "labels")
+	}
+	if mergeLabels(&current.ObjectMeta.Annotations, desired.Annotations) {
+		changes = append(changes, "annotations")
+	}
+
+	for k, v := range desired.Data {
+		if old, ok := current.Data[k]; ok && old == v {
+			continue
+		}
+		if current.Data == nil {
+			current.Data = map[string]string{}
+		}
+		current.Data[k] = v
+		changes = append(changes, fmt.Sprintf("data[%s]", k))
+	}
+	for _, k := range owned {
+		if _, ok := desired.Data[k]; ok {
+			continue
+		}
+		if _, ok := current.Data[k]; ok {
+			delete(current.Data, k)
+			changes = append(changes, fmt.Sprintf("data[%s]", k))
+		}
+	}
+	return changes
+}
+
+// setConfigKeys lists the data keys of a desired config map in its
+// ConfigKeysAnnotation
+func setConfigKeys(cm *corev1.ConfigMap) {
+	keys := make([]string, 0, len(cm.Data))
+	for k := range cm.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[util.ConfigKeysAnnotation] = strings.Join(keys, ",")
+}
+
+// configKeys returns the data keys listed in the ConfigKeysAnnotation of a
+// config map
+func configKeys(cm *corev1.ConfigMap) []string {
+	value := cm.Annotations[util.ConfigKeysAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// mergeService copies the operator owned fields from desired into current
+// and returns the list of fields that changed. Node ports allocated by
+// Kubernetes are kept for ports that are still present.
+func mergeService(current, desired *corev1.Service) (changes []string) {
+	if mergeLabels(&current.ObjectMeta.Labels, desired.Labels) {
+		changes = append(changes, "labels")
+	}
+	if mergeLabels(&current.ObjectMeta.Annotations, desired.Annotations) {
+		changes = append(changes, "annotations")
+	}
+
+	if current.Spec.Type != desired.Spec.Type {
+		current.Spec.Type = desired.Spec.Type
+		changes = append(changes, "type")
+	}
+
+	if !reflect.DeepEqual(current.Spec.Selector, desired.Spec.Selector) {
+		current.Spec.Selector = desired.Spec.Selector
+		changes = append(changes, "selector")
+	}
+
+	// The API server defaults the policy of the external services, it is only
+	// owned by the operator when the desired service sets it
+	if desired.Spec.ExternalTrafficPolicy != "" && current.Spec.ExternalTrafficPolicy != desired.Spec.ExternalTrafficPolicy {
+		current.Spec.ExternalTrafficPolicy = desired.Spec.ExternalTrafficPolicy
+		changes = append(changes, "externalTrafficPolicy")
+	}
+
+	ports := make([]corev1.ServicePort, len(desired.Spec.Ports))
+	for i, port := range desired.Spec.Ports {
+		ports[i] = port
+		if current.Spec.Type == corev1.ServiceTypeClusterIP {
+			continue
+		}
+		for _, currentPort := range current.Spec.Ports {
+			if currentPort.Name == port.Name && port.NodePort == 0 {
+				ports[i].NodePort = currentPort.NodePort
+			}
+		}
+	}
+	if !servicePortsEqual(current.Spec.Ports, ports) {
+		current.Spec.Ports = ports
+		changes = append(changes, "ports")
+	}
+	return changes
+}
+
+// servicePortsEqual compares the service ports ignoring the defaults set by
+// the API server on fields the operator does not specify.
+func servicePortsEqual(current, desired []corev1.ServicePort) bool {
+	if len(current) != len(desired) {
+		return false
+	}
+	for i := range desired {
+		c, d := current[i], desired[i]
+		if c.Name != d.Name || c.Port != d.Port || c.NodePort != d.NodePort {
+			return false
+		}
+		if d.Protocol != "" && c.Protocol != d.Protocol {
+			return false
+		}
+		if (d.TargetPort.IntVal != 0 || d.TargetPort.StrVal != "") && c.TargetPort != d.TargetPort {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeLabels adds the desired key/value pairs to current, leaving any other
+// key untouched, and returns whether current was modified.
+func mergeLabels(current *map[string]string, desired map[string]string) (changed bool) {
+	for k, v := range desired {
+		if old, ok := (*current)[k]; ok && old == v {
+			continue
+		}
+		if *current == nil {
+			*current = map[string]string{}
+		}
+		(*current)[k] = v
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,204 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Owned resources", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s = scheme.Scheme
+		r *ReconcilePravegaCluster
+	)
+
+	Context("Merge", func() {
+		It("should keep labels set by others", func() {
+			current := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"foo": "bar"},
+				},
+				Data: map[string]string{"a": "1", "b": "2"},
+			}
+			desired := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "pravega-cluster"},
+				},
+				Data: map[string]string{"a": "1", "c": "3"},
+			}
+			setConfigKeys(desired)
+			changes := mergeConfigMap(current, desired)
+			Ω(changes).Should(ConsistOf("labels", "annotations", "data[c]"))
+			Ω(current.Labels).Should(HaveKeyWithValue("foo", "bar"))
+			Ω(current.Labels).Should(HaveKeyWithValue("app", "pravega-cluster"))
+			Ω(current.Annotations).Should(HaveKeyWithValue(util.ConfigKeysAnnotation, "a,c"))
+			Ω(current.Data).Should(Equal(map[string]string{"a": "1", "b": "2", "c": "3"}))
+		})
+
+		It("should only remove the data keys owned by the operator", func() {
+			current := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{util.ConfigKeysAnnotation: "a,b"},
+				},
+				Data: map[string]string{"a": "1", "b": "2", "x": "9"},
+			}
+			desired := &corev1.ConfigMap{
+				Data: map[string]string{"a": "2"},
+			}
+			setConfigKeys(desired)
+			changes := mergeConfigMap(current, desired)
+			Ω(changes).Should(ConsistOf("annotations", "data[a]", "data[b]"))
+			Ω(current.Annotations).Should(HaveKeyWithValue(util.ConfigKeysAnnotation, "a"))
+			Ω(current.Data).Should(Equal(map[string]string{"a": "2", "x": "9"}))
+
+			Ω(mergeConfigMap(current, desired)).Should(BeEmpty())
+		})
+
+		It("should keep node ports allocated by kubernetes", func() {
+			current := &corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:      corev1.ServiceTypeNodePort,
+					ClusterIP: "10.0.0.1",
+					Ports:     []corev1.ServicePort{{Name: "grpc", Port: 9090, NodePort: 30001, Protocol: "TCP"}},
+				},
+			}
+			desired := &corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeNodePort,
+					Ports: []corev1.ServicePort{{Name: "grpc", Port: 9090}},
+				},
+			}
+			Ω(mergeService(current, desired)).Should(BeEmpty())
+			Ω(current.Spec.Ports[0].NodePort).Should(BeEquivalentTo(30001))
+			Ω(current.Spec.ClusterIP).Should(Equal("10.0.0.1"))
+		})
+
+		It("should keep the external traffic policy defaulted by kubernetes", func() {
+			current := &corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:                  corev1.ServiceTypeLoadBalancer,
+					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeCluster,
+					Ports:                 []corev1.ServicePort{{Name: "grpc", Port: 9090, NodePort: 30001, Protocol: "TCP"}},
+				},
+			}
+			desired := &corev1.Service{
+				Spec: corev1.ServiceSpec{
+					Type:  corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{Name: "grpc", Port: 9090}},
+				},
+			}
+			Ω(mergeService(current, desired)).Should(BeEmpty())
+			Ω(current.Spec.ExternalTrafficPolicy).Should(Equal(corev1.ServiceExternalTrafficPolicyTypeCluster))
+
+			desired.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+			Ω(mergeService(current, desired)).Should(ConsistOf("externalTrafficPolicy"))
+			Ω(current.Spec.ExternalTrafficPolicy).Should(Equal(corev1.ServiceExternalTrafficPolicyTypeLocal))
+		})
+	})
+
+	Context("Drift", func() {
+		var (
+			client client.Client
+			req    reconcile.Request
+			p      *v1alpha1.PravegaCluster
+		)
+
+		BeforeEach(func() {
+			req = reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      Name,
+					Namespace: Namespace,
+				},
+			}
+			p = &v1alpha1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      Name,
+					Namespace: Namespace,
+				},
+			}
+			p.WithDefaults()
+			s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+			client = fake.NewFakeClient(p)
+			r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
+			r.Reconcile(req)
+
+			foundPravega := &v1alpha1.PravegaCluster{}
+			_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+			foundPravega.Spec.Bookkeeper.Options["journalDirectories"] = "/bk/journal"
+			foundPravega.Spec.ExternalAccess.Enabled = true
+			foundPravega.Spec.ExternalAccess.Type = corev1.ServiceTypeNodePort
+			foundPravega.Spec.Pravega.SegmentStoreReplicas = 2
+			client.Update(context.TODO(), foundPravega)
+			r.Reconcile(req)
+		})
+
+		It("should update the bookie config map", func() {
+			foundCm := &corev1.ConfigMap{}
+			nn := types.NamespacedName{Name: util.ConfigMapNameForBookie(p.Name), Namespace: Namespace}
+			Ω(client.Get(context.TODO(), nn, foundCm)).Should(Succeed())
+			Ω(foundCm.Data).Should(HaveKeyWithValue("BK_journalDirectories", "/bk/journal"))
+		})
+
+		It("should keep the config map keys added by others", func() {
+			foundCm := &corev1.ConfigMap{}
+			nn := types.NamespacedName{Name: util.ConfigMapNameForBookie(p.Name), Namespace: Namespace}
+			Ω(client.Get(context.TODO(), nn, foundCm)).Should(Succeed())
+			foundCm.Data["EXTRA"] = "added by another controller"
+			Ω(client.Update(context.TODO(), foundCm)).Should(Succeed())
+
+			foundPravega := &v1alpha1.PravegaCluster{}
+			Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+			delete(foundPravega.Spec.Bookkeeper.Options, "journalDirectories")
+			Ω(client.Update(context.TODO(), foundPravega)).Should(Succeed())
+			r.Reconcile(req)
+
+			foundCm = &corev1.ConfigMap{}
+			Ω(client.Get(context.TODO(), nn, foundCm)).Should(Succeed())
+			Ω(foundCm.Data).Should(HaveKeyWithValue("EXTRA", "added by another controller"))
+			Ω(foundCm.Data).ShouldNot(HaveKey("BK_journalDirectories"))
+		})
+
+		It("should update the controller service type", func() {
+			foundSvc := &corev1.Service{}
+			nn := types.NamespacedName{Name: util.ServiceNameForController(p.Name), Namespace: Namespace}
+			Ω(client.Get(context.TODO(), nn, foundSvc)).Should(Succeed())
+			Ω(foundSvc.Spec.Type).Should(Equal(corev1.ServiceTypeNodePort))
+		})
+
+		It("should recreate the segment store pod disruption budget", func() {
+			foundPdb := &policyv1beta1.PodDisruptionBudget{}
+			nn := types.NamespacedName{Name: util.PdbNameForSegmentstore(p.Name), Namespace: Namespace}
+			Ω(client.Get(context.TODO(), nn, foundPdb)).Should(Succeed())
+			Ω(foundPdb.Spec.MaxUnavailable.IntValue()).Should(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,170 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// startRollback starts rolling back a failed upgrade to the version the
+// cluster was running before. The rollback reuses the upgrade process with the
+// previous version as target version, so that the previous pod templates and
+// config maps are restored with the same readiness-gated pod deletion.
+func (r *ReconcilePravegaCluster) startRollback(p *pravegav1alpha1.PravegaCluster, cause error) error {
+	p.Status.Rollback = &pravegav1alpha1.RollbackStatus{
+		Phase:                 pravegav1alpha1.RollbackInProgress,
+		FromVersion:           p.Status.TargetVersion,
+		ToVersion:             p.Status.CurrentVersion,
+		FromBookkeeperVersion: p.Status.TargetBookkeeperVersion,
+		ToBookkeeperVersion:   p.Status.CurrentBookkeeperVersion,
+		Reason:                cause.Error(),
+		StartTime:             time.Now().Format(time.RFC3339),
+	}
+	p.Status.TargetVersion = p.Status.CurrentVersion
+	p.Status.TargetBookkeeperVersion = p.Status.CurrentBookkeeperVersion
+	p.Status.Canary = nil
+	p.Status.SetUpgradingConditionTrue(pravegav1alpha1.RollingBackControllerReason, "0")
+	r.recorder.Eventf(p, corev1.EventTypeNormal, RollbackStartedReason,
+		"Rolling back %s", describeRollback(p.Status.Rollback))
+
+	return r.revertSpecVersion(p)
+}
+
+// syncRollback rolls back the components already upgraded in the reverse
+// order of the upgrade. A rollback that fails is left as is and needs manual
+// intervention.
+func (r *ReconcilePravegaCluster) syncRollback(p *pravegav1alpha1.PravegaCluster) (err error) {
+	rollback := p.Status.Rollback
+
+	if err = r.revertSpecVersion(p); err != nil {
+		return err
+	}
+
+	synced, err := r.syncComponentsVersionInOrder(p, []componentSyncVersionFun{
+		componentSyncVersionFun{
+			name: controllerComponent,
+			fun:  r.syncControllerVersion,
+		},
+		componentSyncVersionFun{
+			name: segmentStoreComponent,
+			fun:  r.syncSegmentStoreVersion,
+		},
+		componentSyncVersionFun{
+			name: bookieComponent,
+			fun:  r.syncBookkeeperVersion,
+		},
+	})
+	if err != nil {
+		r.logger(p).Errorf("error rolling back cluster version, need manual intervention. %v", err)
+		r.recorder.Eventf(p, corev1.EventTypeWarning, RollbackFailedReason,
+			"Failed to roll back %s: %v", describeRollback(rollback), err)
+		rollback.Phase = pravegav1alpha1.RollbackFailed
+		rollback.Message = err.Error()
+		rollback.CompletionTime = time.Now().Format(time.RFC3339)
+		p.Status.SetErrorConditionTrue("RollbackFailed", err.Error())
+		return r.clearUpgradeStatus(p)
+	}
+	if !synced {
+		return nil
+	}
+
+	r.logger(p).Infof("rolled back %s", describeRollback(rollback))
+	r.recorder.Eventf(p, corev1.EventTypeNormal, RollbackCompletedReason,
+		"Rolled back %s", describeRollback(rollback))
+	rollback.Phase = pravegav1alpha1.RollbackCompleted
+	rollback.CompletionTime = time.Now().Format(time.RFC3339)
+	return r.clearUpgradeStatus(p)
+}
+
+// revertSpecVersion sets the version and the images in the spec back to those
+// the cluster is rolled back to. The pod templates and config maps are
+// generated from the version and the images in the spec.
+func (r *ReconcilePravegaCluster) revertSpecVersion(p *pravegav1alpha1.PravegaCluster) error {
+	if p.Spec.Version == p.Status.TargetVersion && p.BookkeeperVersion() == p.Status.TargetBookkeeperVersion &&
+		!specImagesChanged(p) {
+		return nil
+	}
+
+	// need to deep copy the status struct, otherwise it will be overridden
+	// when updating the CR below
+	status := p.Status.DeepCopy()
+
+	p.Spec.Version = p.Status.TargetVersion
+	setSpecBookkeeperVersion(p, p.Status.TargetBookkeeperVersion)
+	revertSpecImages(p)
+	if err := r.client.Update(context.TODO(), p); err != nil {
+		return fmt.Errorf("failed to revert cluster version (%s): %v", p.Name, err)
+	}
+
+	p.Status = *status
+	return nil
+}
+
+// specImagesChanged returns true if the images in the spec are not those of
+// the versions the cluster is rolled back to. The images are unknown for
+// clusters that have not run a version since they were recorded in the status.
+func specImagesChanged(p *pravegav1alpha1.PravegaCluster) bool {
+	if image := p.Status.CurrentPravegaImage; image != nil && p.Spec.Pravega != nil && p.Spec.Pravega.Image != nil &&
+		p.Spec.Pravega.Image.ImageSpec != *image {
+		return true
+	}
+	if image := p.Status.CurrentBookkeeperImage; image != nil && p.Spec.Bookkeeper != nil && p.Spec.Bookkeeper.Image != nil &&
+		p.Spec.Bookkeeper.Image.ImageSpec != *image {
+		return true
+	}
+	return false
+}
+
+// revertSpecImages sets the images in the spec back to the images of the
+// versions the cluster is rolled back to, as the failed upgrade may have
+// changed their tag or digest too
+func revertSpecImages(p *pravegav1alpha1.PravegaCluster) {
+	if image := p.Status.CurrentPravegaImage; image != nil && p.Spec.Pravega != nil && p.Spec.Pravega.Image != nil {
+		p.Spec.Pravega.Image.ImageSpec = *image
+	}
+	if image := p.Status.CurrentBookkeeperImage; image != nil && p.Spec.Bookkeeper != nil && p.Spec.Bookkeeper.Image != nil {
+		p.Spec.Bookkeeper.Image.ImageSpec = *image
+	}
+}
+
+// describeRollback describes the versions changed by a rollback
+func describeRollback(rollback *pravegav1alpha1.RollbackStatus) string {
+	return describeUpgrade(rollback.FromVersion, rollback.ToVersion, rollback.FromBookkeeperVersion, rollback.ToBookkeeperVersion)
+}
+
+// upgradingReason returns the reason of the Upgrading condition while the
+// version of a component is synced, depending on whether the cluster is
+// upgraded or rolled back
+func upgradingReason(p *pravegav1alpha1.PravegaCluster, component string) string {
+	rollingBack := p.Status.IsRollingBack()
+	switch component {
+	case controllerComponent:
+		if rollingBack {
+			return pravegav1alpha1.RollingBackControllerReason
+		}
+		return pravegav1alpha1.UpgradingControllerReason
+	case segmentStoreComponent:
+		if rollingBack {
+			return pravegav1alpha1.RollingBackSegmentstoreReason
+		}
+		return pravegav1alpha1.UpgradingSegmentstoreReason
+	default:
+		if rollingBack {
+			return pravegav1alpha1.RollingBackBookkeeperReason
+		}
+		return pravegav1alpha1.UpgradingBookkeeperReason
+	}
+}
@@ -0,0 +1,165 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Upgrade rollback", func() {
+	var (
+		c       *testCluster
+		p       *v1alpha1.PravegaCluster
+		pod     *corev1.Pod
+		upgrade func(spec *v1alpha1.ClusterSpec)
+	)
+
+	bookieImage := func() string {
+		return c.statefulSet(util.StatefulSetNameForBookie(p.Name)).Spec.Template.Spec.Containers[0].Image
+	}
+
+	setBookiesStatus := func(replicas, updated, ready int32) {
+		c.setStatefulSetStatus(util.StatefulSetNameForBookie(p.Name), replicas, updated, ready)
+	}
+
+	BeforeEach(func() {
+		p = newTestCluster()
+		upgrade = func(spec *v1alpha1.ClusterSpec) {
+			spec.Version = "0.6.0"
+		}
+
+		// An upgraded bookie that keeps crashing
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "example-bookie-0",
+				Namespace:   p.Namespace,
+				Labels:      util.LabelsForBookie(p),
+				Annotations: map[string]string{"pravega.version": "0.6.0"},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		c = deployTestCluster(p)
+		c.changeSpec(upgrade)
+		c.reconcile(1)
+
+		// Upgrade the bookie pod template, then fail the upgrade
+		c.reconcile(1)
+		Ω(c.client.Create(context.TODO(), pod)).Should(Succeed())
+		setBookiesStatus(3, 1, 2)
+		c.events()
+		c.reconcile(1)
+	})
+
+	Context("When rollbacks are disabled", func() {
+		It("should stop the upgrade", func() {
+			foundPravega := c.get()
+			Ω(foundPravega.Status.Rollback).Should(BeNil())
+			Ω(foundPravega.Spec.Version).Should(Equal("0.5.0"))
+			Ω(c.upgradeCondition().Status).Should(Equal(corev1.ConditionFalse))
+			Ω(bookieImage()).Should(HaveSuffix(":0.6.0"))
+		})
+	})
+
+	Context("When rollbacks are enabled", func() {
+		BeforeEach(func() {
+			p.Spec.UpgradePolicy.Rollback = true
+		})
+
+		It("should start the rollback", func() {
+			foundPravega := c.get()
+			Ω(foundPravega.Status.Rollback).ShouldNot(BeNil())
+			Ω(foundPravega.Status.Rollback.Phase).Should(Equal(v1alpha1.RollbackInProgress))
+			Ω(foundPravega.Status.Rollback.FromVersion).Should(Equal("0.6.0"))
+			Ω(foundPravega.Status.Rollback.ToVersion).Should(Equal("0.5.0"))
+			Ω(foundPravega.Status.Rollback.Reason).Should(ContainSubstring("CrashLoopBackOff"))
+			Ω(foundPravega.Status.TargetVersion).Should(Equal("0.5.0"))
+			Ω(foundPravega.Spec.Version).Should(Equal("0.5.0"))
+			Ω(c.upgradeCondition().Status).Should(Equal(corev1.ConditionTrue))
+			Ω(c.events()).Should(ContainElement("Normal RollbackStarted Rolling back cluster from version 0.6.0 to 0.5.0"))
+		})
+
+		Context("When the failed upgrade also changed the images", func() {
+			BeforeEach(func() {
+				upgrade = func(spec *v1alpha1.ClusterSpec) {
+					spec.Version = "0.6.0"
+					spec.Pravega.Image.Tag = "0.6.0-patched"
+					spec.Bookkeeper.Image.Digest = "sha256:4b1c"
+				}
+			})
+
+			It("should restore the images", func() {
+				foundPravega := c.get()
+				Ω(foundPravega.Spec.Version).Should(Equal("0.5.0"))
+				Ω(foundPravega.Spec.Pravega.Image.Tag).Should(BeEmpty())
+				Ω(foundPravega.Spec.Bookkeeper.Image.Digest).Should(BeEmpty())
+				Ω(foundPravega.Status.CurrentPravegaImage.Tag).Should(BeEmpty())
+				Ω(foundPravega.Status.CurrentBookkeeperImage.Digest).Should(BeEmpty())
+			})
+
+			It("should restore the bookie pod template", func() {
+				c.reconcile(1)
+				Ω(bookieImage()).Should(HaveSuffix(":0.5.0"))
+			})
+		})
+
+		Context("When the rollback progresses", func() {
+			JustBeforeEach(func() {
+				c.reconcile(1)
+			})
+
+			It("should restore the bookie pod template", func() {
+				Ω(bookieImage()).Should(HaveSuffix(":0.5.0"))
+				Ω(c.upgradeCondition().Reason).Should(Equal(v1alpha1.RollingBackBookkeeperReason))
+			})
+
+			It("should delete the upgraded pods one at a time", func() {
+				setBookiesStatus(3, 2, 2)
+				c.reconcile(1)
+				Ω(c.podExists(pod.Name)).Should(BeFalse())
+				Ω(c.events()).Should(ContainElement("Normal RollingBackPod Deleted pod example-bookie-0 to roll it back from version 0.6.0 to 0.5.0"))
+			})
+
+			Context("When all pods are rolled back", func() {
+				JustBeforeEach(func() {
+					setBookiesStatus(3, 3, 3)
+					c.reconcile(1)
+				})
+
+				It("should complete the rollback", func() {
+					foundPravega := c.get()
+					Ω(foundPravega.Status.Rollback.Phase).Should(Equal(v1alpha1.RollbackCompleted))
+					Ω(foundPravega.Status.Rollback.CompletionTime).ShouldNot(BeEmpty())
+					Ω(foundPravega.Status.CurrentVersion).Should(Equal("0.5.0"))
+					Ω(foundPravega.Status.TargetVersion).Should(BeEmpty())
+					Ω(foundPravega.Spec.Version).Should(Equal("0.5.0"))
+					Ω(c.upgradeCondition().Status).Should(Equal(corev1.ConditionFalse))
+					Ω(c.events()).Should(ContainElement("Normal RollbackCompleted Rolled back cluster from version 0.6.0 to 0.5.0"))
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,300 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/pravega"
+	"github.com/pravega/pravega-operator/pkg/metrics"
+	"github.com/pravega/pravega-operator/pkg/util"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type componentSyncConfigFun struct {
+	name   string
+	reason string
+	fun    func(p *pravegav1alpha1.PravegaCluster, inProgress bool) (synced bool, err error)
+}
+
+// syncClusterConfig restarts the pods of the components whose rendered config
+// map or pod template changed outside of a version upgrade. Components are
+// restarted in the same order and with the same one-pod-at-a-time process as
+// in a version upgrade.
+func (r *ReconcilePravegaCluster) syncClusterConfig(p *pravegav1alpha1.PravegaCluster) (err error) {
+	_, upgradeCondition := p.Status.GetClusterCondition(pravegav1alpha1.ClusterConditionUpgrading)
+	if upgradeCondition == nil || isUpgradePending(p) {
+		return nil
+	}
+
+	reason := ""
+	if upgradeCondition.Status == corev1.ConditionTrue {
+		if !pravegav1alpha1.IsConfigUpdateReason(upgradeCondition.Reason) {
+			// A version upgrade is in progress
+			return nil
+		}
+		reason = upgradeCondition.Reason
+	}
+
+	defer func() {
+		if statusErr := r.client.Status().Update(context.TODO(), p); statusErr != nil {
+			r.logger(p).Errorf("failed to update cluster status: %v", statusErr)
+			if err == nil {
+				err = fmt.Errorf("failed to update cluster status: %v", statusErr)
+			}
+		}
+	}()
+
+	if err := r.syncComponentsConfig(p, reason); err != nil {
+		r.logger(p).Errorf("error syncing cluster config, need manual intervention. %v", err)
+		r.recorder.Eventf(p, corev1.EventTypeWarning, ConfigUpdateFailedReason,
+			"Failed to apply config changes: %v", err)
+		p.Status.SetErrorConditionTrue("ConfigUpdateFailed", err.Error())
+		p.Status.SetUpgradingConditionFalse()
+	}
+	return nil
+}
+
+func (r *ReconcilePravegaCluster) syncComponentsConfig(p *pravegav1alpha1.PravegaCluster, reason string) (err error) {
+	var synced bool
+
+	_, readyCondition := p.Status.GetClusterCondition(pravegav1alpha1.ClusterConditionPodsReady)
+	ready := readyCondition != nil && readyCondition.Status == corev1.ConditionTrue
+
+	for _, component := range []componentSyncConfigFun{
+		componentSyncConfigFun{
+			name:   bookieComponent,
+			reason: pravegav1alpha1.UpdatingBookkeeperConfigReason,
+			fun:    r.syncBookkeeperConfig,
+		},
+		componentSyncConfigFun{
+			name:   segmentStoreComponent,
+			reason: pravegav1alpha1.UpdatingSegmentstoreConfigReason,
+			fun:    r.syncSegmentStoreConfig,
+		},
+		componentSyncConfigFun{
+			name:   controllerComponent,
+			reason: pravegav1alpha1.UpdatingControllerConfigReason,
+			fun:    r.syncControllerConfig,
+		},
+	} {
+		inProgress := component.reason == reason
+		if reason != "" && !inProgress {
+			// Finish the rollout in progress before looking at other components
+			continue
+		}
+
+		if !inProgress && !ready {
+			// Do not start restarting pods if there are unready pods
+			return nil
+		}
+
+		synced, err = component.fun(p, inProgress)
+		if err != nil {
+			return fmt.Errorf("failed to sync %s config. %s", component.name, err)
+		}
+
+		if !synced {
+			// component config sync is still in progress
+			// Do not continue with the next component until this one is done
+			return nil
+		}
+
+		if inProgress {
+			r.componentLogger(p, component.name).Info("config sync has been completed")
+			reason = ""
+		}
+	}
+
+	// All component pods are running with the latest config
+	p.Status.SetUpgradingConditionFalse()
+	return nil
+}
+
+func (r *ReconcilePravegaCluster) syncControllerConfig(p *pravegav1alpha1.PravegaCluster, inProgress bool) (synced bool, err error) {
+	deploy := &appsv1.Deployment{}
+	name := util.DeploymentNameForController(p.Name)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, deploy)
+	if err != nil {
+		return false, fmt.Errorf("failed to get deployment (%s): %v", name, err)
+	}
+
+	template := pravega.MakeControllerPodTemplate(p)
+	currentHash, changed, err := r.syncPodTemplate(p, deploy, &deploy.Spec.Template, template)
+	if err != nil {
+		return false, err
+	}
+	if changed {
+		p.Status.SetUpgradingConditionTrue(pravegav1alpha1.UpdatingControllerConfigReason, "0")
+		return false, nil
+	}
+
+	if !inProgress {
+		return true, nil
+	}
+
+	if deploy.Status.UpdatedReplicas == deploy.Status.Replicas &&
+		deploy.Status.UpdatedReplicas == deploy.Status.ReadyReplicas {
+		return true, nil
+	}
+
+	for _, v := range deploy.Status.Conditions {
+		if v.Type == appsv1.DeploymentProgressing &&
+			v.Status == corev1.ConditionFalse && v.Reason == "ProgressDeadlineExceeded" {
+			r.recorder.Eventf(p, corev1.EventTypeWarning, ProgressDeadlineExceededReason,
+				"Deployment %s made no progress applying config hash %s: %s", deploy.Name, currentHash, v.Message)
+			return false, fmt.Errorf("updating deployment (%s) failed due to %s", deploy.Name, v.Reason)
+		}
+	}
+
+	pods, err := r.getPodsWithConfigHash(deploy.Spec.Template.Labels, deploy.Namespace, currentHash, true)
+	if err != nil {
+		return false, err
+	}
+	_, err = r.checkUpdatedPods(p, pods)
+	return false, err
+}
+
+func (r *ReconcilePravegaCluster) syncSegmentStoreConfig(p *pravegav1alpha1.PravegaCluster, inProgress bool) (synced bool, err error) {
+	return r.syncStatefulSetConfig(p, util.StatefulSetNameForSegmentstore(p.Name),
+		pravega.MakeSegmentStorePodTemplate(p), pravegav1alpha1.UpdatingSegmentstoreConfigReason, inProgress)
+}
+
+func (r *ReconcilePravegaCluster) syncBookkeeperConfig(p *pravegav1alpha1.PravegaCluster, inProgress bool) (synced bool, err error) {
+	return r.syncStatefulSetConfig(p, util.StatefulSetNameForBookie(p.Name),
+		pravega.MakeBookiePodTemplate(p), pravegav1alpha1.UpdatingBookkeeperConfigReason, inProgress)
+}
+
+func (r *ReconcilePravegaCluster) syncStatefulSetConfig(p *pravegav1alpha1.PravegaCluster, name string,
+	template corev1.PodTemplateSpec, reason string, inProgress bool) (synced bool, err error) {
+	sts := &appsv1.StatefulSet{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, sts)
+	if err != nil {
+		return false, fmt.Errorf("failed to get statefulset (%s): %v", name, err)
+	}
+
+	currentHash, changed, err := r.syncPodTemplate(p, sts, &sts.Spec.Template, template)
+	if err != nil {
+		return false, err
+	}
+	if changed {
+		p.Status.SetUpgradingConditionTrue(reason, "0")
+		return false, nil
+	}
+
+	if !inProgress {
+		return true, nil
+	}
+
+	outdated, err := r.getPodsWithConfigHash(sts.Spec.Template.Labels, sts.Namespace, currentHash, false)
+	if err != nil {
+		return false, err
+	}
+	updated, err := r.getPodsWithConfigHash(sts.Spec.Template.Labels, sts.Namespace, currentHash, true)
+	if err != nil {
+		return false, err
+	}
+
+	logger := r.componentLogger(p, sts.Spec.Template.Labels["component"])
+	logger.Debugf("statefulset (%s) config status: %d updated, %d outdated", sts.Name, len(updated), len(outdated))
+
+	ready, err := r.checkUpdatedPods(p, updated)
+	if err != nil {
+		// Abort if there is any errors with the updated pods
+		return false, err
+	}
+
+	if len(outdated) == 0 {
+		return ready, nil
+	}
+
+	// Check if the component fails to have progress within a timeout
+	err = r.checkUpgradeCondition(p, reason, int32(len(updated)), progressDeadline(p, sts.Spec.Template.Labels["component"]))
+	if err != nil {
+		return false, fmt.Errorf("updating statefulset (%s) failed due to %v", sts.Name, err)
+	}
+
+	if ready {
+		pod := outdated[0]
+		logger.Infof("restarting pod: %s", pod.Name)
+		err = r.client.Delete(context.TODO(), pod)
+		if err != nil {
+			return false, err
+		}
+		metrics.UpgradePodDeletions.WithLabelValues(p.Namespace, p.Name, pod.Labels["component"]).Inc()
+		r.recorder.Eventf(p, corev1.EventTypeNormal, RestartingPodReason,
+			"Deleted pod %s to apply config hash %s (was %s)", pod.Name, currentHash, util.GetPodConfigHash(pod))
+	}
+
+	// Wait until next reconcile iteration
+	return false, nil
+}
+
+// syncPodTemplate compares the config hash of the live pod template with the
+// desired one and updates the owner object if they differ. Pod templates
+// created before config hashes were introduced have no hash, and may differ
+// from the desired template in any way: they are replaced as well, so that
+// the pods are restarted once with the desired config.
+func (r *ReconcilePravegaCluster) syncPodTemplate(p *pravegav1alpha1.PravegaCluster, owner runtime.Object, current *corev1.PodTemplateSpec,
+	desired corev1.PodTemplateSpec) (currentHash string, changed bool, err error) {
+	desiredHash := desired.Annotations[util.ConfigHashAnnotation]
+	currentHash = current.Annotations[util.ConfigHashAnnotation]
+
+	if currentHash == desiredHash {
+		return currentHash, false, nil
+	}
+
+	r.componentLogger(p, desired.Labels["component"]).Infof("updating pod template with config hash '%s' (was '%s')", desiredHash, currentHash)
+	*current = desired
+	err = r.client.Update(context.TODO(), owner)
+	if err != nil {
+		return "", false, err
+	}
+	return desiredHash, true, nil
+}
+
+// getPodsWithConfigHash returns the pods matching the labels whose config hash
+// is equal to, or different from, the given hash.
+func (r *ReconcilePravegaCluster) getPodsWithConfigHash(podLabels map[string]string, namespace string,
+	hash string, equal bool) ([]*corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: podLabels,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert label selector: %v", err)
+	}
+
+	podList := &corev1.PodList{}
+	podlistOps := &client.ListOptions{
+		Namespace:     namespace,
+		LabelSelector: selector,
+	}
+	err = r.client.List(context.TODO(), podlistOps, podList)
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []*corev1.Pod
+	for _, podItem := range podList.Items {
+		if (util.GetPodConfigHash(&podItem) == hash) != equal {
+			continue
+		}
+		pods = append(pods, podItem.DeepCopy())
+	}
+	return pods, nil
+}
@@ -0,0 +1,187 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/pravega"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config rollout", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s      = scheme.Scheme
+		r      *ReconcilePravegaCluster
+		client client.Client
+		req    reconcile.Request
+		p      *v1alpha1.PravegaCluster
+	)
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p = &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		p.WithDefaults()
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+		client = fake.NewFakeClient(p)
+		r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
+		r.Reconcile(req)
+
+		foundPravega := &v1alpha1.PravegaCluster{}
+		_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+		foundPravega.Spec.Bookkeeper.Options["journalDirectories"] = "/bk/journal"
+		// bypass the pods ready check in the rollout logic
+		foundPravega.Status.SetPodsReadyConditionTrue()
+		client.Update(context.TODO(), foundPravega)
+		r.Reconcile(req)
+	})
+
+	It("should stamp the config hash on the pod template", func() {
+		foundPravega := &v1alpha1.PravegaCluster{}
+		_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+		sts := &appsv1.StatefulSet{}
+		nn := types.NamespacedName{Name: util.StatefulSetNameForBookie(p.Name), Namespace: Namespace}
+		Ω(client.Get(context.TODO(), nn, sts)).Should(Succeed())
+		expected := pravega.MakeBookiePodTemplate(foundPravega).Annotations[util.ConfigHashAnnotation]
+		Ω(sts.Spec.Template.Annotations).Should(HaveKeyWithValue(util.ConfigHashAnnotation, expected))
+	})
+
+	It("should set upgrade condition reason to UpdatingBookkeeperConfigReason", func() {
+		foundPravega := &v1alpha1.PravegaCluster{}
+		_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+		_, upgradeCondition := foundPravega.Status.GetClusterCondition(v1alpha1.ClusterConditionUpgrading)
+		Ω(upgradeCondition.Status).Should(Equal(corev1.ConditionTrue))
+		Ω(upgradeCondition.Reason).Should(Equal(v1alpha1.UpdatingBookkeeperConfigReason))
+	})
+
+	It("should not change the cluster version", func() {
+		foundPravega := &v1alpha1.PravegaCluster{}
+		_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+		Ω(foundPravega.Status.CurrentVersion).Should(Equal("0.5.0"))
+		Ω(foundPravega.Status.TargetVersion).Should(BeEmpty())
+	})
+})
+
+var _ = Describe("Image tag rollout", func() {
+	var c *testCluster
+
+	BeforeEach(func() {
+		c = deployTestCluster(newTestCluster())
+		c.changeSpec(func(spec *v1alpha1.ClusterSpec) {
+			spec.Bookkeeper.Image.Tag = "0.5.0-cve-fix"
+		})
+		c.reconcile(1)
+	})
+
+	It("should roll out the new image without an upgrade", func() {
+		Ω(c.upgradeCondition().Reason).Should(Equal(v1alpha1.UpdatingBookkeeperConfigReason))
+		Ω(c.get().Status.TargetBookkeeperVersion).Should(BeEmpty())
+
+		sts := c.statefulSet(util.StatefulSetNameForBookie(c.req.Name))
+		Ω(sts.Spec.Template.Spec.Containers[0].Image).Should(Equal("pravega/bookkeeper:0.5.0-cve-fix"))
+		Ω(sts.Spec.Template.Annotations["pravega.version"]).Should(Equal("0.5.0"))
+	})
+})
+
+var _ = Describe("Pod template without config hash", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		client client.Client
+		req    reconcile.Request
+		sts    *appsv1.StatefulSet
+	)
+
+	BeforeEach(func() {
+		req = reconcile.Request{NamespacedName: types.NamespacedName{Name: Name, Namespace: Namespace}}
+		p := &v1alpha1.PravegaCluster{ObjectMeta: metav1.ObjectMeta{Name: Name, Namespace: Namespace}}
+		p.Spec.Version = "0.5.0"
+		p.WithDefaults()
+		scheme.Scheme.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+		client = fake.NewFakeClient(p)
+		r := &ReconcilePravegaCluster{client: client, scheme: scheme.Scheme, recorder: &record.FakeRecorder{}}
+		r.Reconcile(req)
+
+		// Simulate a stateful set created before config hashes, whose pods
+		// run an outdated config
+		sts = &appsv1.StatefulSet{}
+		nn := types.NamespacedName{Name: util.StatefulSetNameForBookie(Name), Namespace: Namespace}
+		Ω(client.Get(context.TODO(), nn, sts)).Should(Succeed())
+		delete(sts.Spec.Template.Annotations, util.ConfigHashAnnotation)
+		sts.Spec.Template.Spec.Containers[0].Image = "pravega/bookkeeper:outdated"
+		Ω(client.Update(context.TODO(), sts)).Should(Succeed())
+
+		foundPravega := &v1alpha1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+		foundPravega.Status.SetPodsReadyConditionTrue()
+		Ω(client.Update(context.TODO(), foundPravega)).Should(Succeed())
+		r.Reconcile(req)
+		Ω(client.Get(context.TODO(), nn, sts)).Should(Succeed())
+	})
+
+	It("should apply the desired pod template", func() {
+		foundPravega := &v1alpha1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+		expected := pravega.MakeBookiePodTemplate(foundPravega)
+		Ω(sts.Spec.Template.Annotations).Should(HaveKeyWithValue(util.ConfigHashAnnotation, expected.Annotations[util.ConfigHashAnnotation]))
+		Ω(sts.Spec.Template.Spec.Containers[0].Image).Should(Equal(expected.Spec.Containers[0].Image))
+	})
+
+	It("should roll out the pods", func() {
+		foundPravega := &v1alpha1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), req.NamespacedName, foundPravega)).Should(Succeed())
+		_, upgradeCondition := foundPravega.Status.GetClusterCondition(v1alpha1.ClusterConditionUpgrading)
+		Ω(upgradeCondition.Status).Should(Equal(corev1.ConditionTrue))
+		Ω(upgradeCondition.Reason).Should(Equal(v1alpha1.UpdatingBookkeeperConfigReason))
+	})
+
+	It("should replace the controller pods one at a time", func() {
+		deploy := &appsv1.Deployment{}
+		nn := types.NamespacedName{Name: util.DeploymentNameForController(Name), Namespace: Namespace}
+		Ω(client.Get(context.TODO(), nn, deploy)).Should(Succeed())
+		Ω(deploy.Spec.Strategy.RollingUpdate).ShouldNot(BeNil())
+		Ω(deploy.Spec.Strategy.RollingUpdate.MaxUnavailable.IntValue()).Should(BeZero())
+		Ω(deploy.Spec.Strategy.RollingUpdate.MaxSurge.IntValue()).Should(Equal(1))
+	})
+})
@@ -0,0 +1,128 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster status", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s            = scheme.Scheme
+		r            *ReconcilePravegaCluster
+		client       client.Client
+		req          reconcile.Request
+		foundPravega *v1alpha1.PravegaCluster
+	)
+
+	makePod := func(name string, labels map[string]string, version string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   Namespace,
+				Labels:      labels,
+				Annotations: map[string]string{"pravega.version": version},
+			},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p := &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       Name,
+				Namespace:  Namespace,
+				Generation: 3,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		p.WithDefaults()
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+		client = fake.NewFakeClient(
+			p,
+			makePod("example-bookie-0", util.LabelsForBookie(p), "0.5.0", true),
+			makePod("example-bookie-1", util.LabelsForBookie(p), "0.5.0", true),
+			makePod("example-bookie-2", util.LabelsForBookie(p), "0.4.0", false),
+			makePod("example-pravega-segmentstore-0", util.LabelsForSegmentStore(p), "0.5.0", true),
+			makePod("example-pravega-controller-abc", util.LabelsForController(p), "0.5.0", false),
+		)
+		r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
+		r.Reconcile(req)
+
+		foundPravega = &v1alpha1.PravegaCluster{}
+		_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
+	})
+
+	It("should report the bookkeeper status", func() {
+		status := foundPravega.Status.Bookkeeper
+		Ω(status.Replicas).Should(BeEquivalentTo(3))
+		Ω(status.CurrentReplicas).Should(BeEquivalentTo(3))
+		Ω(status.ReadyReplicas).Should(BeEquivalentTo(2))
+		Ω(status.Members.Ready).Should(ConsistOf("example-bookie-0", "example-bookie-1"))
+		Ω(status.Members.Unready).Should(ConsistOf("example-bookie-2"))
+		Ω(status.Versions).Should(HaveKeyWithValue("example-bookie-2", "0.4.0"))
+	})
+
+	It("should report the segment store status", func() {
+		status := foundPravega.Status.SegmentStore
+		Ω(status.Replicas).Should(BeEquivalentTo(1))
+		Ω(status.ReadyReplicas).Should(BeEquivalentTo(1))
+		Ω(status.Members.Ready).Should(ConsistOf("example-pravega-segmentstore-0"))
+		Ω(status.Versions).Should(Equal(map[string]string{"example-pravega-segmentstore-0": "0.5.0"}))
+	})
+
+	It("should report the controller status", func() {
+		status := foundPravega.Status.Controller
+		Ω(status.ReadyReplicas).Should(BeEquivalentTo(0))
+		Ω(status.Members.Unready).Should(ConsistOf("example-pravega-controller-abc"))
+	})
+
+	It("should report the cluster status", func() {
+		Ω(foundPravega.Status.CurrentReplicas).Should(BeEquivalentTo(5))
+		Ω(foundPravega.Status.ReadyReplicas).Should(BeEquivalentTo(3))
+	})
+
+	It("should set the observed generation", func() {
+		Ω(foundPravega.Status.ObservedGeneration).Should(BeEquivalentTo(3))
+	})
+})
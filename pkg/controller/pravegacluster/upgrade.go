@@ -17,15 +17,14 @@ import (
 
 	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
 	"github.com/pravega/pravega-operator/pkg/controller/pravega"
+	"github.com/pravega/pravega-operator/pkg/metrics"
 	"github.com/pravega/pravega-operator/pkg/util"
-	log "github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 type componentSyncVersionFun struct {
@@ -46,6 +45,21 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1alpha1.PravegaC
 		// the current version to the version in the spec
 		p.Status.SetUpgradingConditionFalse()
 		p.Status.CurrentVersion = p.Spec.Version
+		p.Status.CurrentBookkeeperVersion = p.BookkeeperVersion()
+		setStatusCurrentImages(p)
+		return nil
+	}
+
+	if p.Status.CurrentBookkeeperVersion == "" {
+		// The status was written by a release that did not track the
+		// BookKeeper version, which was always the cluster version
+		p.Status.CurrentBookkeeperVersion = p.Status.CurrentVersion
+		p.Status.TargetBookkeeperVersion = p.Status.TargetVersion
+	}
+
+	if upgradeCondition.Status == corev1.ConditionTrue && pravegav1alpha1.IsConfigUpdateReason(upgradeCondition.Reason) {
+		// A configuration rollout is in progress, the version sync will start
+		// once all pods have been restarted
 		return nil
 	}
 
@@ -53,19 +67,37 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1alpha1.PravegaC
 		// Upgrade process already in progress
 
 		if p.Status.TargetVersion == "" {
-			log.Println("syncing to an unknown version: cancelling upgrade process")
+			r.logger(p).Warn("syncing to an unknown version: cancelling upgrade process")
 			return r.clearUpgradeStatus(p)
 		}
 
-		if p.Status.TargetVersion == p.Status.CurrentVersion {
-			log.Printf("syncing to version '%s' completed", p.Status.TargetVersion)
+		if p.Status.IsRollingBack() {
+			return r.syncRollback(p)
+		}
+
+		if p.Status.TargetVersion == p.Status.CurrentVersion &&
+			p.Status.TargetBookkeeperVersion == p.Status.CurrentBookkeeperVersion {
+			r.logger(p).Info("syncing to target version completed")
+			if p.Status.CurrentBookkeeperVersion != p.Status.CurrentVersion {
+				r.recorder.Eventf(p, corev1.EventTypeNormal, UpgradeCompletedReason,
+					"Upgraded cluster to version %s with bookkeeper version %s", p.Status.TargetVersion, p.Status.TargetBookkeeperVersion)
+			} else {
+				r.recorder.Eventf(p, corev1.EventTypeNormal, UpgradeCompletedReason,
+					"Upgraded cluster to version %s", p.Status.TargetVersion)
+			}
 			return r.clearUpgradeStatus(p)
 		}
 
 		if err := r.syncComponentsVersion(p); err != nil {
-			log.Printf("error syncing cluster version, need manual intervention. %v", err)
-			// TODO: Trigger roll back to previous version
+			r.recorder.Eventf(p, corev1.EventTypeWarning, UpgradeFailedReason,
+				"Failed to upgrade %s: %v", describeUpgrade(p.Status.CurrentVersion, p.Status.TargetVersion,
+					p.Status.CurrentBookkeeperVersion, p.Status.TargetBookkeeperVersion), err)
 			p.Status.SetErrorConditionTrue("UpgradeFailed", err.Error())
+			if p.Spec.UpgradePolicy.IsRollbackEnabled() {
+				r.logger(p).Errorf("error syncing cluster version, rolling back. %v", err)
+				return r.startRollback(p, err)
+			}
+			r.logger(p).Errorf("error syncing cluster version, need manual intervention. %v", err)
 			r.clearUpgradeStatus(p)
 		}
 		return nil
@@ -73,24 +105,35 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1alpha1.PravegaC
 
 	// No upgrade in progress
 
-	if p.Spec.Version == p.Status.CurrentVersion {
-		// No intention to upgrade
+	if p.Spec.Version == p.Status.CurrentVersion && p.BookkeeperVersion() == p.Status.CurrentBookkeeperVersion {
+		// No intention to upgrade. The images of the spec are those of the
+		// current versions, a tag or digest may have changed though
+		setStatusCurrentImages(p)
 		return nil
 	}
 
 	if readyCondition == nil || readyCondition.Status != corev1.ConditionTrue {
 		r.clearUpgradeStatus(p)
-		log.Print("cannot trigger upgrade if there are unready pods")
+		r.logger(p).Warn("cannot trigger upgrade if there are unready pods")
 		return nil
 	}
 
 	// Need to sync cluster versions
-	log.Printf("syncing cluster version from %s to %s", p.Status.CurrentVersion, p.Spec.Version)
+	if p.BookkeeperVersion() != p.Status.CurrentBookkeeperVersion {
+		r.logger(p).Infof("syncing cluster version from %s to %s, bookkeeper version from %s to %s", p.Status.CurrentVersion,
+			p.Spec.Version, p.Status.CurrentBookkeeperVersion, p.BookkeeperVersion())
+	} else {
+		r.logger(p).Infof("syncing cluster version from %s to %s", p.Status.CurrentVersion, p.Spec.Version)
+	}
 
 	// Setting target version and condition.
 	// The upgrade process will start on the next reconciliation
 	p.Status.TargetVersion = p.Spec.Version
+	p.Status.TargetBookkeeperVersion = p.BookkeeperVersion()
 	p.Status.SetUpgradingConditionTrue("", "")
+	r.recorder.Eventf(p, corev1.EventTypeNormal, UpgradeStartedReason,
+		"Upgrading %s", describeUpgrade(p.Status.CurrentVersion, p.Status.TargetVersion,
+			p.Status.CurrentBookkeeperVersion, p.Status.TargetBookkeeperVersion))
 
 	return nil
 }
@@ -98,11 +141,14 @@ func (r *ReconcilePravegaCluster) syncClusterVersion(p *pravegav1alpha1.PravegaC
 func (r *ReconcilePravegaCluster) clearUpgradeStatus(p *pravegav1alpha1.PravegaCluster) (err error) {
 	p.Status.SetUpgradingConditionFalse()
 	p.Status.TargetVersion = ""
+	p.Status.TargetBookkeeperVersion = ""
+	p.Status.Canary = nil
 	// need to deep copy the status struct, otherwise it will be overridden
 	// when updating the CR below
 	status := p.Status.DeepCopy()
 
 	p.Spec.Version = p.Status.CurrentVersion
+	setSpecBookkeeperVersion(p, p.Status.CurrentBookkeeperVersion)
 	if err := r.client.Update(context.TODO(), p); err != nil {
 		return err
 	}
@@ -112,39 +158,48 @@ func (r *ReconcilePravegaCluster) clearUpgradeStatus(p *pravegav1alpha1.PravegaC
 }
 
 func (r *ReconcilePravegaCluster) syncComponentsVersion(p *pravegav1alpha1.PravegaCluster) (err error) {
-	var synced bool
-
-	for _, component := range []componentSyncVersionFun{
+	synced, err := r.syncComponentsVersionInOrder(p, []componentSyncVersionFun{
 		componentSyncVersionFun{
-			name: "bookkeeper",
+			name: bookieComponent,
 			fun:  r.syncBookkeeperVersion,
 		},
 		componentSyncVersionFun{
-			name: "segmentstore",
+			name: segmentStoreComponent,
 			fun:  r.syncSegmentStoreVersion,
 		},
 		componentSyncVersionFun{
-			name: "controller",
+			name: controllerComponent,
 			fun:  r.syncControllerVersion,
 		},
-	} {
+	})
+	if err != nil || !synced {
+		return err
+	}
+
+	// All component versions have been synced
+	p.Status.CurrentVersion = p.Status.TargetVersion
+	p.Status.CurrentBookkeeperVersion = p.Status.TargetBookkeeperVersion
+	return nil
+}
+
+// syncComponentsVersionInOrder syncs the version of the given components one
+// after the other, and returns true once all of them are synced
+func (r *ReconcilePravegaCluster) syncComponentsVersionInOrder(p *pravegav1alpha1.PravegaCluster, components []componentSyncVersionFun) (synced bool, err error) {
+	for _, component := range components {
 		synced, err = component.fun(p)
 		if err != nil {
-			return fmt.Errorf("failed to sync %s version. %s", component.name, err)
+			return false, fmt.Errorf("failed to sync %s version. %s", component.name, err)
 		}
 
 		if synced {
-			log.Printf("%s version sync has been completed", component.name)
+			r.componentLogger(p, component.name).Info("version sync has been completed")
 		} else {
 			// component version sync is still in progress
 			// Do not continue with the next component until this one is done
-			return nil
+			return false, nil
 		}
 	}
-
-	// All component versions have been synced
-	p.Status.CurrentVersion = p.Status.TargetVersion
-	return nil
+	return true, nil
 }
 
 func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1alpha1.PravegaCluster) (synced bool, err error) {
@@ -160,14 +215,12 @@ func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1alpha1.Prave
 		return false, err
 	}
 
-	if deploy.Spec.Template.Spec.Containers[0].Image != targetImage {
+	if templateOutdated(&deploy.Spec.Template, targetImage, p.Status.TargetVersion) {
 		// Need to update pod template
 		// This will trigger the rolling upgrade process
-		log.Printf("updating deployment (%s) pod template image to '%s'", deploy.Name, targetImage)
+		r.componentLogger(p, controllerComponent).Infof("updating deployment (%s) pod template image to '%s'", deploy.Name, targetImage)
 
-		configMap := pravega.MakeControllerConfigMap(p)
-		controllerutil.SetControllerReference(p, configMap, r.scheme)
-		err = r.client.Update(context.TODO(), configMap)
+		err = r.syncConfigMap(p, pravega.MakeControllerConfigMap(p))
 		if err != nil {
 			return false, err
 		}
@@ -178,15 +231,16 @@ func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1alpha1.Prave
 			return false, err
 		}
 
-		// Set the upgrade condition reason to be UpgradingControllerReason, message to be 0
-		p.Status.SetUpgradingConditionTrue(pravegav1alpha1.UpgradingControllerReason, "0")
+		// Set the upgrade condition reason to be UpgradingControllerReason, or
+		// RollingBackControllerReason during a rollback, message to be 0
+		p.Status.SetUpgradingConditionTrue(upgradingReason(p, controllerComponent), "0")
 
 		// Updated pod template. Upgrade process has been triggered
 		return false, nil
 	}
 
 	// Pod template already updated
-	log.Printf("deployment (%s) status: %d updated, %d ready, %d target", deploy.Name,
+	r.componentLogger(p, controllerComponent).Debugf("deployment (%s) status: %d updated, %d ready, %d target", deploy.Name,
 		deploy.Status.UpdatedReplicas, deploy.Status.ReadyReplicas, deploy.Status.Replicas)
 
 	// Check whether the upgrade is in progress or has completed
@@ -197,6 +251,8 @@ func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1alpha1.Prave
 			if v.Type == appsv1.DeploymentProgressing &&
 				v.Status == corev1.ConditionFalse && v.Reason == "ProgressDeadlineExceeded" {
 				// upgrade fails
+				r.recorder.Eventf(p, corev1.EventTypeWarning, ProgressDeadlineExceededReason,
+					"Deployment %s made no progress upgrading to version %s: %s", deploy.Name, p.Status.TargetVersion, v.Message)
 				return false, fmt.Errorf("updating deployment (%s) failed due to %s", deploy.Name, v.Reason)
 			}
 		}
@@ -205,7 +261,7 @@ func (r *ReconcilePravegaCluster) syncControllerVersion(p *pravegav1alpha1.Prave
 		if err != nil {
 			return false, err
 		}
-		_, err = r.checkUpdatedPods(pods, p.Status.TargetVersion)
+		_, err = r.checkUpdatedPods(p, pods)
 		if err != nil {
 			// Abort if there is any errors with the updated pods
 			return false, err
@@ -227,19 +283,19 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersion(p *pravegav1alpha1.Pra
 		return false, fmt.Errorf("failed to get statefulset (%s): %v", sts.Name, err)
 	}
 
+	logger := r.componentLogger(p, segmentStoreComponent)
+
 	targetImage, err := util.PravegaTargetImage(p)
 	if err != nil {
 		return false, err
 	}
 
-	if sts.Spec.Template.Spec.Containers[0].Image != targetImage {
+	if templateOutdated(&sts.Spec.Template, targetImage, p.Status.TargetVersion) {
 		// Need to update pod template
 		// This will trigger the rolling upgrade process
-		log.Printf("updating statefulset (%s) template image to '%s'", sts.Name, targetImage)
+		logger.Infof("updating statefulset (%s) template image to '%s'", sts.Name, targetImage)
 
-		configMap := pravega.MakeSegmentstoreConfigMap(p)
-		controllerutil.SetControllerReference(p, configMap, r.scheme)
-		err = r.client.Update(context.TODO(), configMap)
+		err = r.syncConfigMap(p, pravega.MakeSegmentstoreConfigMap(p))
 		if err != nil {
 			return false, err
 		}
@@ -250,8 +306,9 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersion(p *pravegav1alpha1.Pra
 			return false, err
 		}
 
-		// Set the upgrade condition reason to be UpgradingSegmentstoreReason, message to be 0
-		p.Status.SetUpgradingConditionTrue(pravegav1alpha1.UpgradingSegmentstoreReason, "0")
+		// Set the upgrade condition reason to be UpgradingSegmentstoreReason, or
+		// RollingBackSegmentstoreReason during a rollback, message to be 0
+		p.Status.SetUpgradingConditionTrue(upgradingReason(p, segmentStoreComponent), "0")
 
 		// Updated pod template. Upgrade process has been triggered
 		return false, nil
@@ -259,7 +316,7 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersion(p *pravegav1alpha1.Pra
 
 	// Pod template already updated
 
-	log.Printf("statefulset (%s) status: %d updated, %d ready, %d target", sts.Name,
+	logger.Debugf("statefulset (%s) status: %d updated, %d ready, %d target", sts.Name,
 		sts.Status.UpdatedReplicas, sts.Status.ReadyReplicas, sts.Status.Replicas)
 	// Check whether the upgrade is in progress or has completed
 	if sts.Status.UpdatedReplicas == sts.Status.Replicas &&
@@ -269,23 +326,30 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersion(p *pravegav1alpha1.Pra
 	}
 	// Upgrade still in progress
 
-	// Check if segmentstore fail to have progress within a timeout
-	err = checkUpgradeCondition(p, pravegav1alpha1.UpgradingSegmentstoreReason, sts.Status.UpdatedReplicas)
-	if err != nil {
-		return false, fmt.Errorf("updating statefulset (%s) failed due to %v", sts.Name, err)
-	}
-
 	// If all replicas are ready, upgrade an old pod
 	pods, err := r.getStsPodsWithVersion(sts, p.Status.TargetVersion)
 	if err != nil {
 		return false, err
 	}
-	ready, err := r.checkUpdatedPods(pods, p.Status.TargetVersion)
+	ready, err := r.checkUpdatedPods(p, pods)
 	if err != nil {
 		// Abort if there is any errors with the updated pods
 		return false, err
 	}
 
+	// Hold the upgrade once the canary pods are ready, until it is approved
+	waiting, err := r.syncCanaryApproval(p, segmentStoreComponent, pods, ready)
+	if err != nil || waiting {
+		return false, err
+	}
+
+	// Check if segmentstore fail to have progress within a timeout
+	err = r.checkUpgradeCondition(p, upgradingReason(p, segmentStoreComponent), sts.Status.UpdatedReplicas,
+		progressDeadline(p, segmentStoreComponent))
+	if err != nil {
+		return false, fmt.Errorf("updating statefulset (%s) failed due to %v", sts.Name, err)
+	}
+
 	if ready {
 		pod, err := r.getOneOutdatedPod(sts, p.Status.TargetVersion)
 		if err != nil {
@@ -296,9 +360,9 @@ func (r *ReconcilePravegaCluster) syncSegmentStoreVersion(p *pravegav1alpha1.Pra
 			return false, fmt.Errorf("could not obtain outdated pod")
 		}
 
-		log.Infof("upgrading pod: %s", pod.Name)
+		logger.Infof("upgrading pod: %s", pod.Name)
 
-		err = r.client.Delete(context.TODO(), pod)
+		err = r.deleteOutdatedPod(p, pod)
 		if err != nil {
 			return false, err
 		}
@@ -316,19 +380,19 @@ func (r *ReconcilePravegaCluster) syncBookkeeperVersion(p *pravegav1alpha1.Prave
 		return false, fmt.Errorf("failed to get statefulset (%s): %v", sts.Name, err)
 	}
 
+	logger := r.componentLogger(p, bookieComponent)
+
 	targetImage, err := util.BookkeeperTargetImage(p)
 	if err != nil {
 		return false, err
 	}
 
-	if sts.Spec.Template.Spec.Containers[0].Image != targetImage {
+	if templateOutdated(&sts.Spec.Template, targetImage, p.Status.TargetBookkeeperVersion) {
 		// Need to update pod template
 		// This will trigger the rolling upgrade process
-		log.Printf("updating statefulset (%s) template image to '%s'", sts.Name, targetImage)
+		logger.Infof("updating statefulset (%s) template image to '%s'", sts.Name, targetImage)
 
-		configMap := pravega.MakeBookieConfigMap(p)
-		controllerutil.SetControllerReference(p, configMap, r.scheme)
-		err = r.client.Update(context.TODO(), configMap)
+		err = r.syncConfigMap(p, pravega.MakeBookieConfigMap(p))
 		if err != nil {
 			return false, err
 		}
@@ -339,8 +403,9 @@ func (r *ReconcilePravegaCluster) syncBookkeeperVersion(p *pravegav1alpha1.Prave
 			return false, err
 		}
 
-		// Set the upgrade condition reason to be UpgradingBookkeeperReason, message to be 0
-		p.Status.SetUpgradingConditionTrue(pravegav1alpha1.UpgradingBookkeeperReason, "0")
+		// Set the upgrade condition reason to be UpgradingBookkeeperReason, or
+		// RollingBackBookkeeperReason during a rollback, message to be 0
+		p.Status.SetUpgradingConditionTrue(upgradingReason(p, bookieComponent), "0")
 
 		// Updated pod template
 		return false, nil
@@ -348,7 +413,7 @@ func (r *ReconcilePravegaCluster) syncBookkeeperVersion(p *pravegav1alpha1.Prave
 
 	// Pod template already updated
 
-	log.Printf("statefulset (%s) status: %d updated, %d ready, %d target", sts.Name,
+	logger.Debugf("statefulset (%s) status: %d updated, %d ready, %d target", sts.Name,
 		sts.Status.UpdatedReplicas, sts.Status.ReadyReplicas, sts.Status.Replicas)
 
 	// Check whether the upgrade is in progress or has completed
@@ -362,25 +427,32 @@ func (r *ReconcilePravegaCluster) syncBookkeeperVersion(p *pravegav1alpha1.Prave
 
 	// Upgrade still in progress
 
-	// Check if bookkeeper fail to have progress
-	err = checkUpgradeCondition(p, pravegav1alpha1.UpgradingBookkeeperReason, sts.Status.UpdatedReplicas)
-	if err != nil {
-		return false, fmt.Errorf("updating statefulset (%s) failed due to %v", sts.Name, err)
-	}
-
 	// If all replicas are ready, upgrade an old pod
-	pods, err := r.getStsPodsWithVersion(sts, p.Status.TargetVersion)
+	pods, err := r.getStsPodsWithVersion(sts, p.Status.TargetBookkeeperVersion)
 	if err != nil {
 		return false, err
 	}
-	ready, err := r.checkUpdatedPods(pods, p.Status.TargetVersion)
+	ready, err := r.checkUpdatedPods(p, pods)
 	if err != nil {
 		// Abort if there is any errors with the updated pods
 		return false, err
 	}
 
+	// Hold the upgrade once the canary pods are ready, until it is approved
+	waiting, err := r.syncCanaryApproval(p, bookieComponent, pods, ready)
+	if err != nil || waiting {
+		return false, err
+	}
+
+	// Check if bookkeeper fail to have progress
+	err = r.checkUpgradeCondition(p, upgradingReason(p, bookieComponent), sts.Status.UpdatedReplicas,
+		progressDeadline(p, bookieComponent))
+	if err != nil {
+		return false, fmt.Errorf("updating statefulset (%s) failed due to %v", sts.Name, err)
+	}
+
 	if ready {
-		pod, err := r.getOneOutdatedPod(sts, p.Status.TargetVersion)
+		pod, err := r.getOneOutdatedPod(sts, p.Status.TargetBookkeeperVersion)
 		if err != nil {
 			return false, err
 		}
@@ -389,9 +461,9 @@ func (r *ReconcilePravegaCluster) syncBookkeeperVersion(p *pravegav1alpha1.Prave
 			return false, fmt.Errorf("could not obtain outdated pod")
 		}
 
-		log.Infof("upgrading pod: %s", pod.Name)
+		logger.Infof("upgrading pod: %s", pod.Name)
 
-		err = r.client.Delete(context.TODO(), pod)
+		err = r.deleteOutdatedPod(p, pod)
 		if err != nil {
 			return false, err
 		}
@@ -401,13 +473,37 @@ func (r *ReconcilePravegaCluster) syncBookkeeperVersion(p *pravegav1alpha1.Prave
 	return false, nil
 }
 
-func (r *ReconcilePravegaCluster) checkUpdatedPods(pods []*corev1.Pod, version string) (bool, error) {
+// deleteOutdatedPod deletes a pod running another version than the target
+// version, so that its stateful set recreates it from the updated template
+func (r *ReconcilePravegaCluster) deleteOutdatedPod(p *pravegav1alpha1.PravegaCluster, pod *corev1.Pod) error {
+	err := r.client.Delete(context.TODO(), pod)
+	if err != nil {
+		return err
+	}
+	component := pod.Labels["component"]
+	metrics.UpgradePodDeletions.WithLabelValues(p.Namespace, p.Name, component).Inc()
+	if p.Status.IsRollingBack() {
+		r.recorder.Eventf(p, corev1.EventTypeNormal, RollingBackPodReason,
+			"Deleted pod %s to roll it back from version %s to %s", pod.Name, util.GetPodVersion(pod), targetVersion(p, component))
+		return nil
+	}
+	r.recorder.Eventf(p, corev1.EventTypeNormal, UpgradingPodReason,
+		"Deleted pod %s to upgrade it from version %s to %s", pod.Name, util.GetPodVersion(pod), targetVersion(p, component))
+	return nil
+}
+
+func (r *ReconcilePravegaCluster) checkUpdatedPods(p *pravegav1alpha1.PravegaCluster, pods []*corev1.Pod) (bool, error) {
+	readyTimeout := time.Duration(p.Spec.UpgradePolicy.PodReadyTimeoutSeconds) * time.Second
 	for _, pod := range pods {
 		if !util.IsPodReady(pod) {
 			// At least one updated pod is still not ready, check if it is faulty.
 			if faulty, err := util.IsPodFaulty(pod); faulty {
 				return false, err
 			}
+			// or if it has been starting for too long
+			if readyTimeout > 0 && !pod.CreationTimestamp.IsZero() && time.Since(pod.CreationTimestamp.Time) > readyTimeout {
+				return false, fmt.Errorf("pod %s is not ready after %v", pod.Name, readyTimeout)
+			}
 			return false, nil
 		}
 	}
@@ -484,15 +580,83 @@ func (r *ReconcilePravegaCluster) getPodsWithVersion(selector labels.Selector, n
 	return pods, nil
 }
 
-func checkUpgradeCondition(p *pravegav1alpha1.PravegaCluster, reason string, updatedReplicas int32) error {
+// targetVersion returns the version a component is upgraded to
+func targetVersion(p *pravegav1alpha1.PravegaCluster, component string) string {
+	if component == bookieComponent {
+		return p.Status.TargetBookkeeperVersion
+	}
+	return p.Status.TargetVersion
+}
+
+// setSpecBookkeeperVersion sets the BookKeeper version in the spec, leaving it
+// unset if it is the cluster version
+func setSpecBookkeeperVersion(p *pravegav1alpha1.PravegaCluster, version string) {
+	if p.Spec.Bookkeeper == nil || p.BookkeeperVersion() == version {
+		return
+	}
+	p.Spec.Bookkeeper.Version = version
+}
+
+// setStatusCurrentImages records the images of the spec as the images of the
+// current versions, so that a rollback restores them
+func setStatusCurrentImages(p *pravegav1alpha1.PravegaCluster) {
+	if p.Spec.Pravega != nil && p.Spec.Pravega.Image != nil {
+		image := p.Spec.Pravega.Image.ImageSpec
+		p.Status.CurrentPravegaImage = &image
+	}
+	if p.Spec.Bookkeeper != nil && p.Spec.Bookkeeper.Image != nil {
+		image := p.Spec.Bookkeeper.Image.ImageSpec
+		p.Status.CurrentBookkeeperImage = &image
+	}
+}
+
+// templateOutdated returns true if a pod template does not run the target
+// image and version. The image alone is not enough when it is pinned to a tag
+// or digest that does not change with the version.
+func templateOutdated(template *corev1.PodTemplateSpec, image, version string) bool {
+	return template.Spec.Containers[0].Image != image || template.Annotations["pravega.version"] != version
+}
+
+// describeUpgrade describes the versions changed by an upgrade. The BookKeeper
+// version is only described when it does not follow the cluster version.
+func describeUpgrade(from, to, bookkeeperFrom, bookkeeperTo string) string {
+	cluster := fmt.Sprintf("cluster from version %s to %s", from, to)
+	if bookkeeperFrom == "" || bookkeeperFrom == bookkeeperTo || (bookkeeperFrom == from && bookkeeperTo == to) {
+		return cluster
+	}
+	bookkeeper := fmt.Sprintf("bookkeeper from version %s to %s", bookkeeperFrom, bookkeeperTo)
+	if from == to {
+		return bookkeeper
+	}
+	return cluster + " and " + bookkeeper
+}
+
+// progressDeadline returns the time a component may take to make progress
+// during an upgrade or a config rollout, as set in the upgrade policy
+func progressDeadline(p *pravegav1alpha1.PravegaCluster, component string) time.Duration {
+	var seconds int32
+	switch component {
+	case controllerComponent:
+		seconds = p.Spec.UpgradePolicy.ControllerProgressDeadlineSeconds
+	case segmentStoreComponent:
+		seconds = p.Spec.UpgradePolicy.SegmentStoreProgressDeadlineSeconds
+	default:
+		seconds = p.Spec.UpgradePolicy.BookkeeperProgressDeadlineSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (r *ReconcilePravegaCluster) checkUpgradeCondition(p *pravegav1alpha1.PravegaCluster, reason string, updatedReplicas int32, deadline time.Duration) error {
 	_, lastCondition := p.Status.GetClusterCondition(pravegav1alpha1.ClusterConditionUpgrading)
 	if lastCondition.Reason == reason && lastCondition.Message == fmt.Sprint(updatedReplicas) {
 		// if reason and message are the same as before, which means there is no progress since the last reconciling,
 		// then check if it reaches the timeout.
 		parsedTime, _ := time.Parse(time.RFC3339, lastCondition.LastUpdateTime)
-		if time.Now().After(parsedTime.Add(time.Duration(10 * time.Minute))) {
+		if time.Now().After(parsedTime.Add(deadline)) {
 			// timeout
-			return fmt.Errorf("progress deadline exceeded")
+			r.recorder.Eventf(p, corev1.EventTypeWarning, ProgressDeadlineExceededReason,
+				"No progress in %s since %s: %d pods updated", reason, lastCondition.LastUpdateTime, updatedReplicas)
+			return fmt.Errorf("progress deadline of %v exceeded", deadline)
 		}
 		// it hasn't reached timeout
 		return nil
@@ -13,8 +13,10 @@ package pravegacluster
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/pravega"
 	"github.com/pravega/pravega-operator/pkg/util"
 
 	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
@@ -23,6 +25,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -78,7 +81,7 @@ var _ = Describe("Pravega Cluster", func() {
 
 			BeforeEach(func() {
 				client = fake.NewFakeClient(p)
-				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
 				_, err = r.Reconcile(req)
 			})
 
@@ -109,6 +112,75 @@ var _ = Describe("Pravega Cluster", func() {
 			})
 		})
 
+		Context("Upgrade bookkeeper only", func() {
+			var c *testCluster
+
+			BeforeEach(func() {
+				c = deployTestCluster(newTestCluster())
+				c.changeSpec(func(spec *v1alpha1.ClusterSpec) {
+					spec.Bookkeeper.Version = "0.6.0"
+				})
+				c.reconcile(2)
+			})
+
+			It("should only upgrade bookkeeper", func() {
+				foundPravega := c.get()
+				Ω(foundPravega.Status.TargetVersion).Should(Equal("0.5.0"))
+				Ω(foundPravega.Status.TargetBookkeeperVersion).Should(Equal("0.6.0"))
+				Ω(c.events()).Should(ContainElement("Normal UpgradeStarted Upgrading bookkeeper from version 0.5.0 to 0.6.0"))
+			})
+
+			It("should update the bookie pod template only", func() {
+				bookies := c.statefulSet(util.StatefulSetNameForBookie(Name))
+				Ω(bookies.Spec.Template.Spec.Containers[0].Image).Should(Equal("pravega/bookkeeper:0.6.0"))
+				Ω(bookies.Spec.Template.Annotations["pravega.version"]).Should(Equal("0.6.0"))
+				segmentStores := c.statefulSet(util.StatefulSetNameForSegmentstore(Name))
+				Ω(segmentStores.Spec.Template.Spec.Containers[0].Image).Should(Equal("pravega/pravega:0.5.0"))
+			})
+
+			Context("When the bookies are upgraded", func() {
+				BeforeEach(func() {
+					c.setStatefulSetStatus(util.StatefulSetNameForBookie(Name), 3, 3, 3)
+					c.reconcile(2)
+				})
+
+				It("should complete the upgrade", func() {
+					foundPravega := c.get()
+					Ω(foundPravega.Status.CurrentVersion).Should(Equal("0.5.0"))
+					Ω(foundPravega.Status.CurrentBookkeeperVersion).Should(Equal("0.6.0"))
+					Ω(foundPravega.Status.TargetBookkeeperVersion).Should(BeEmpty())
+					Ω(foundPravega.Spec.Bookkeeper.Version).Should(Equal("0.6.0"))
+					Ω(c.upgradeCondition().Status).Should(Equal(corev1.ConditionFalse))
+					Ω(c.events()).Should(ContainElement("Normal UpgradeCompleted Upgraded cluster to version 0.5.0 with bookkeeper version 0.6.0"))
+				})
+			})
+		})
+
+		Context("Upgrade with a pinned image", func() {
+			const digest = "sha256:4b1c8bd86e3d3cb8c34c05c8bbd1c9a1e1bbbdc1a3d1ba1a3e6bd8b6f6e2c0d1"
+
+			var c *testCluster
+
+			BeforeEach(func() {
+				p := newTestCluster()
+				p.Spec.Pravega.Image.Digest = digest
+				c = deployTestCluster(p)
+				c.changeSpec(func(spec *v1alpha1.ClusterSpec) {
+					spec.Version = "0.6.0"
+				})
+				c.reconcile(2)
+			})
+
+			It("should update the pod template although the image does not change", func() {
+				c.setStatefulSetStatus(util.StatefulSetNameForBookie(Name), 3, 3, 3)
+				c.reconcile(1)
+
+				sts := c.statefulSet(util.StatefulSetNameForSegmentstore(Name))
+				Ω(sts.Spec.Template.Spec.Containers[0].Image).Should(Equal("pravega/pravega@" + digest))
+				Ω(sts.Spec.Template.Annotations["pravega.version"]).Should(Equal("0.6.0"))
+			})
+		})
+
 		Context("Upgrade to new version", func() {
 			var (
 				client client.Client
@@ -120,7 +192,7 @@ var _ = Describe("Pravega Cluster", func() {
 				}
 				p.WithDefaults()
 				client = fake.NewFakeClient(p)
-				r = &ReconcilePravegaCluster{client: client, scheme: s}
+				r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
 				_, _ = r.Reconcile(req)
 				foundPravega := &v1alpha1.PravegaCluster{}
 				_ = client.Get(context.TODO(), req.NamespacedName, foundPravega)
@@ -190,6 +262,7 @@ var _ = Describe("Pravega Cluster", func() {
 					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, sts)
 					targetImage, _ := util.BookkeeperTargetImage(foundPravega)
 					sts.Spec.Template.Spec.Containers[0].Image = targetImage
+					sts.Spec.Template.Annotations["pravega.version"] = "0.6.0"
 					r.client.Update(context.TODO(), sts)
 
 					_, _ = r.Reconcile(req)
@@ -220,6 +293,7 @@ var _ = Describe("Pravega Cluster", func() {
 					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, sts)
 					targetImage, _ := util.BookkeeperTargetImage(foundPravega)
 					sts.Spec.Template.Spec.Containers[0].Image = targetImage
+					sts.Spec.Template.Annotations["pravega.version"] = "0.6.0"
 					r.client.Update(context.TODO(), sts)
 
 					// Segmentstore
@@ -227,6 +301,7 @@ var _ = Describe("Pravega Cluster", func() {
 					_ = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, sts)
 					targetImage, _ = util.PravegaTargetImage(foundPravega)
 					sts.Spec.Template.Spec.Containers[0].Image = targetImage
+					sts.Spec.Template.Annotations["pravega.version"] = "0.6.0"
 					r.client.Update(context.TODO(), sts)
 
 					_, _ = r.Reconcile(req)
@@ -244,3 +319,80 @@ var _ = Describe("Pravega Cluster", func() {
 		})
 	})
 })
+
+var _ = Describe("Upgrade policy", func() {
+	var (
+		r *ReconcilePravegaCluster
+		p *v1alpha1.PravegaCluster
+	)
+
+	BeforeEach(func() {
+		p = newTestCluster()
+		p.Spec.UpgradePolicy.SegmentStoreProgressDeadlineSeconds = 3600
+		p.Spec.UpgradePolicy.BookkeeperProgressDeadlineSeconds = 60
+		p.Spec.UpgradePolicy.PodReadyTimeoutSeconds = 300
+		r = &ReconcilePravegaCluster{recorder: record.NewFakeRecorder(100)}
+	})
+
+	Context("Progress deadlines", func() {
+		BeforeEach(func() {
+			p.Status.SetUpgradingConditionTrue(pravegav1alpha1.UpgradingBookkeeperReason, "1")
+			i, _ := p.Status.GetClusterCondition(pravegav1alpha1.ClusterConditionUpgrading)
+			p.Status.Conditions[i].LastUpdateTime = time.Now().Add(-5 * time.Minute).Format(time.RFC3339)
+		})
+
+		It("should use the deadline of each component", func() {
+			Ω(progressDeadline(p, controllerComponent)).Should(Equal(10 * time.Minute))
+			Ω(progressDeadline(p, segmentStoreComponent)).Should(Equal(time.Hour))
+			Ω(progressDeadline(p, bookieComponent)).Should(Equal(time.Minute))
+		})
+
+		It("should set the progress deadline of the controller deployment", func() {
+			p.Spec.UpgradePolicy.ControllerProgressDeadlineSeconds = 120
+			Ω(*pravega.MakeControllerDeployment(p).Spec.ProgressDeadlineSeconds).Should(BeEquivalentTo(120))
+		})
+
+		It("should fail once the deadline is exceeded", func() {
+			err := r.checkUpgradeCondition(p, pravegav1alpha1.UpgradingBookkeeperReason, 1, progressDeadline(p, bookieComponent))
+			Ω(err).Should(MatchError("progress deadline of 1m0s exceeded"))
+		})
+
+		It("should wait until the deadline is exceeded", func() {
+			err := r.checkUpgradeCondition(p, pravegav1alpha1.UpgradingBookkeeperReason, 1, progressDeadline(p, segmentStoreComponent))
+			Ω(err).Should(BeNil())
+		})
+	})
+
+	Context("Pod ready timeout", func() {
+		var pod *corev1.Pod
+
+		BeforeEach(func() {
+			pod = &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "example-bookie-0"},
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{{}},
+				},
+			}
+		})
+
+		It("should wait for a starting pod", func() {
+			pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+			ready, err := r.checkUpdatedPods(p, []*corev1.Pod{pod})
+			Ω(ready).Should(BeFalse())
+			Ω(err).Should(BeNil())
+		})
+
+		It("should fail if a pod is not ready in time", func() {
+			pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-10 * time.Minute))
+			_, err := r.checkUpdatedPods(p, []*corev1.Pod{pod})
+			Ω(err).Should(MatchError("pod example-bookie-0 is not ready after 5m0s"))
+		})
+
+		It("should not time out if disabled", func() {
+			p.Spec.UpgradePolicy.PodReadyTimeoutSeconds = 0
+			pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+			_, err := r.checkUpdatedPods(p, []*corev1.Pod{pod})
+			Ω(err).Should(BeNil())
+		})
+	})
+})
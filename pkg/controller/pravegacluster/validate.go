@@ -0,0 +1,52 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/validation"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// validateSpec runs the checks of the validating webhook when the webhook is
+// disabled. A cluster whose spec is not valid gets the Error condition with
+// the InvalidSpec reason and is not reconciled. The condition is cleared once
+// the spec is fixed.
+func (r *ReconcilePravegaCluster) validateSpec(p *pravegav1alpha1.PravegaCluster) (valid bool, err error) {
+	_, condition := p.Status.GetClusterCondition(pravegav1alpha1.ClusterConditionError)
+	invalid := condition != nil && condition.Status == corev1.ConditionTrue && condition.Reason == InvalidSpecReason
+
+	errs := validation.ValidateCluster(p)
+	if len(errs) == 0 {
+		if invalid {
+			r.logger(p).Info("cluster spec is valid")
+			p.Status.SetErrorConditionFalse()
+		}
+		return true, nil
+	}
+
+	message := errs.ToAggregate().Error()
+	if !invalid || condition.Message != message {
+		r.logger(p).Warnf("cluster spec is not valid: %s", message)
+		r.recorder.Eventf(p, corev1.EventTypeWarning, InvalidSpecReason,
+			"Not reconciling the cluster until its spec is fixed: %s", message)
+	}
+
+	p.Status.InitConditions()
+	p.Status.SetErrorConditionTrue(InvalidSpecReason, message)
+	if err = r.client.Status().Update(context.TODO(), p); err != nil {
+		return false, fmt.Errorf("failed to update cluster status: %v", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,166 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/controller/config"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spec validation", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s        = scheme.Scheme
+		r        *ReconcilePravegaCluster
+		client   client.Client
+		recorder *record.FakeRecorder
+		req      reconcile.Request
+		p        *v1alpha1.PravegaCluster
+		res      reconcile.Result
+		err      error
+	)
+
+	get := func() *v1alpha1.PravegaCluster {
+		p := &v1alpha1.PravegaCluster{}
+		Ω(client.Get(context.TODO(), req.NamespacedName, p)).Should(Succeed())
+		return p
+	}
+
+	bookiesExist := func() bool {
+		nn := types.NamespacedName{Name: util.StatefulSetNameForBookie(Name), Namespace: Namespace}
+		err := client.Get(context.TODO(), nn, &appsv1.StatefulSet{})
+		if errors.IsNotFound(err) {
+			return false
+		}
+		Ω(err).Should(BeNil())
+		return true
+	}
+
+	errorCondition := func() *v1alpha1.ClusterCondition {
+		_, condition := get().Status.GetClusterCondition(v1alpha1.ClusterConditionError)
+		Ω(condition).ShouldNot(BeNil())
+		return condition
+	}
+
+	events := func() []string {
+		var events []string
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		return events
+	}
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p = &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		p.Spec.Pravega = &v1alpha1.PravegaSpec{
+			Tier2: &v1alpha1.Tier2Spec{Ecs: &v1alpha1.ECSSpec{Uri: "https://ecs:9021", Bucket: "pravega"}},
+		}
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewFakeClient(p)
+		recorder = record.NewFakeRecorder(100)
+		r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: recorder}
+		res, err = r.Reconcile(req)
+	})
+
+	Context("When the webhook is enabled", func() {
+		It("should not validate the cluster", func() {
+			Ω(err).Should(BeNil())
+			Ω(res.Requeue).Should(BeTrue())
+		})
+	})
+
+	Context("When the webhook is disabled", func() {
+		BeforeEach(func() {
+			config.ValidateSpec = true
+		})
+
+		AfterEach(func() {
+			config.ValidateSpec = false
+		})
+
+		It("should not reconcile an invalid cluster", func() {
+			Ω(err).Should(BeNil())
+			Ω(res.Requeue).Should(BeFalse())
+			Ω(res.RequeueAfter).Should(BeZero())
+			r.Reconcile(req)
+			Ω(bookiesExist()).Should(BeFalse())
+		})
+
+		It("should set the error condition", func() {
+			condition := errorCondition()
+			Ω(condition.Status).Should(Equal(corev1.ConditionTrue))
+			Ω(condition.Reason).Should(Equal(InvalidSpecReason))
+			Ω(condition.Message).Should(Equal("spec.pravega.tier2.ecs.credentials: Required value: name of the secret holding the ECS credentials"))
+		})
+
+		It("should record a single event", func() {
+			r.Reconcile(req)
+			Ω(events()).Should(Equal([]string{
+				"Warning InvalidSpec Not reconciling the cluster until its spec is fixed: " +
+					"spec.pravega.tier2.ecs.credentials: Required value: name of the secret holding the ECS credentials",
+			}))
+		})
+
+		Context("When the spec is fixed", func() {
+			JustBeforeEach(func() {
+				foundPravega := get()
+				foundPravega.Spec.Pravega.Tier2.Ecs.Credentials = "ecs-credentials"
+				Ω(client.Update(context.TODO(), foundPravega)).Should(Succeed())
+				r.Reconcile(req)
+				r.Reconcile(req)
+			})
+
+			It("should reconcile the cluster", func() {
+				Ω(bookiesExist()).Should(BeTrue())
+			})
+
+			It("should clear the error condition", func() {
+				Ω(errorCondition().Status).Should(Equal(corev1.ConditionFalse))
+			})
+		})
+	})
+})
@@ -0,0 +1,148 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+	"fmt"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// zookeeperClusterGVK is the kind of the ZookeeperCluster objects managed by
+// the zookeeper operator. They are handled as unstructured objects, so that
+// the zookeeper operator is not a build dependency.
+var zookeeperClusterGVK = schema.GroupVersionKind{
+	Group:   "zookeeper.pravega.io",
+	Version: "v1beta1",
+	Kind:    "ZookeeperCluster",
+}
+
+func newZookeeperCluster() *unstructured.Unstructured {
+	zk := &unstructured.Unstructured{}
+	zk.SetGroupVersionKind(zookeeperClusterGVK)
+	return zk
+}
+
+// getZookeeperCluster returns the client address reported by the referenced
+// ZookeeperCluster and whether all its replicas are ready. The address is
+// empty if the ZookeeperCluster does not exist or has not reported it yet.
+func (r *ReconcilePravegaCluster) getZookeeperCluster(ref *pravegav1alpha1.ZookeeperClusterReference) (address string, ready bool, err error) {
+	zk := newZookeeperCluster()
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, zk)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", false, nil
+		}
+		if meta.IsNoMatchError(err) {
+			return "", false, fmt.Errorf("failed to get zookeeper cluster (%s/%s): restart the operator after installing the zookeeper operator: %v",
+				ref.Namespace, ref.Name, err)
+		}
+		return "", false, fmt.Errorf("failed to get zookeeper cluster (%s/%s): %v", ref.Namespace, ref.Name, err)
+	}
+
+	address, _, _ = unstructured.NestedString(zk.Object, "status", "internalClientEndpoint")
+	replicas, _, _ := unstructured.NestedInt64(zk.Object, "spec", "replicas")
+	readyReplicas, _, _ := unstructured.NestedInt64(zk.Object, "status", "readyReplicas")
+	return address, replicas > 0 && readyReplicas >= replicas, nil
+}
+
+// syncZookeeperUri sets the ZooKeeper URI in the status of a cluster that
+// references a ZookeeperCluster to the address reported by the
+// ZookeeperCluster. The spec is left unchanged, so that the cluster keeps
+// following the ZookeeperCluster. It returns
+// true if the cluster has to wait for the ZookeeperCluster: while its address
+// is unknown, and while it is not ready if the bookies are not deployed yet.
+func (r *ReconcilePravegaCluster) syncZookeeperUri(p *pravegav1alpha1.PravegaCluster) (waiting bool, err error) {
+	ref := p.Spec.Zookeeper.ClusterRef
+	if ref == nil {
+		return false, nil
+	}
+
+	address, ready, err := r.getZookeeperCluster(ref)
+	if err != nil {
+		return false, err
+	}
+	if address == "" {
+		r.logger(p).Infof("waiting for zookeeper cluster (%s/%s) to report its client address", ref.Namespace, ref.Name)
+		r.recorder.Eventf(p, corev1.EventTypeNormal, WaitingForZookeeperReason,
+			"Waiting for ZookeeperCluster %s/%s to report its client address", ref.Namespace, ref.Name)
+		return true, nil
+	}
+	p.Status.ZookeeperUri = address
+
+	if ready {
+		return false, nil
+	}
+
+	sts := &appsv1.StatefulSet{}
+	name := util.StatefulSetNameForBookie(p.Name)
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: p.Namespace}, sts)
+	if err == nil {
+		// The cluster is already deployed
+		return false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to get stateful-set (%s): %v", name, err)
+	}
+
+	r.logger(p).Infof("waiting for zookeeper cluster (%s/%s) to be ready", ref.Namespace, ref.Name)
+	r.recorder.Eventf(p, corev1.EventTypeNormal, WaitingForZookeeperReason,
+		"Waiting for ZookeeperCluster %s/%s to be ready before deploying the bookies", ref.Namespace, ref.Name)
+	return true, nil
+}
+
+// zookeeperClusterMapper maps a ZookeeperCluster to the requests of the
+// PravegaClusters that reference it
+type zookeeperClusterMapper struct {
+	client client.Client
+}
+
+var _ handler.Mapper = &zookeeperClusterMapper{}
+
+func (m *zookeeperClusterMapper) Map(o handler.MapObject) []reconcile.Request {
+	clusters := &pravegav1alpha1.PravegaClusterList{}
+	err := m.client.List(context.TODO(), &client.ListOptions{}, clusters)
+	if err != nil {
+		log.Errorf("failed to list Pravega clusters: %v", err)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, p := range clusters.Items {
+		if p.Spec.Zookeeper == nil || p.Spec.Zookeeper.ClusterRef == nil {
+			continue
+		}
+		ref := p.Spec.Zookeeper.ClusterRef
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = p.Namespace
+		}
+		if ref.Name == o.Meta.GetName() && namespace == o.Meta.GetNamespace() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: p.Name, Namespace: p.Namespace},
+			})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,225 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package pravegacluster
+
+import (
+	"context"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Zookeeper secret", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s      = scheme.Scheme
+		r      *ReconcilePravegaCluster
+		client client.Client
+		req    reconcile.Request
+	)
+
+	getConfigMap := func(name string) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		Ω(client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: Namespace}, cm)).Should(Succeed())
+		return cm
+	}
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p := &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		p.Spec.Zookeeper = &v1alpha1.ZookeeperSpec{
+			Secret: "zk-secret",
+			Auth:   true,
+			TLS:    true,
+		}
+		p.WithDefaults()
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+		client = fake.NewFakeClient(p)
+		r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: &record.FakeRecorder{}}
+		r.Reconcile(req)
+	})
+
+	It("should configure the controller", func() {
+		cm := getConfigMap(util.ConfigMapNameForController(Name))
+		Ω(cm.Data["JAVA_OPTS"]).Should(ContainSubstring("-Djava.security.auth.login.config=/etc/zookeeper-secret/jaas.conf"))
+		Ω(cm.Data["JAVA_OPTS"]).Should(ContainSubstring("-Dcontroller.zk.secureConnection=true"))
+	})
+
+	It("should configure the segment store", func() {
+		cm := getConfigMap(util.ConfigMapNameForSegmentstore(Name))
+		Ω(cm.Data["JAVA_OPTS"]).Should(ContainSubstring("-Dpravegaservice.secureZK=true"))
+		Ω(cm.Data["JAVA_OPTS"]).Should(ContainSubstring("-Dpravegaservice.zkTrustStorePasswordPath=/etc/zookeeper-secret/truststore.password"))
+	})
+
+	It("should configure the bookies", func() {
+		cm := getConfigMap(util.ConfigMapNameForBookie(Name))
+		Ω(cm.Data["BOOKIE_EXTRA_OPTS"]).Should(ContainSubstring("-Dzookeeper.client.secure=true"))
+		Ω(cm.Data["BOOKIE_EXTRA_OPTS"]).ShouldNot(ContainSubstring("password"))
+
+		sts := &appsv1.StatefulSet{}
+		nn := types.NamespacedName{Name: util.StatefulSetNameForBookie(Name), Namespace: Namespace}
+		Ω(client.Get(context.TODO(), nn, sts)).Should(Succeed())
+		container := sts.Spec.Template.Spec.Containers[0]
+		Ω(container.Env).Should(HaveLen(2))
+		Ω(container.Env[0].ValueFrom.SecretKeyRef.Name).Should(Equal("zk-secret"))
+		Ω(container.Env[1].Value).Should(Equal("$(BOOKIE_EXTRA_OPTS) -Dzookeeper.ssl.trustStore.password=$(ZK_TRUSTSTORE_PASSWORD)"))
+		Ω(container.VolumeMounts).Should(ContainElement(corev1.VolumeMount{Name: "zookeeper-secret", MountPath: "/etc/zookeeper-secret"}))
+	})
+
+	Context("When the cluster is deleted", func() {
+		BeforeEach(func() {
+			r.Reconcile(req)
+			p := &v1alpha1.PravegaCluster{}
+			Ω(client.Get(context.TODO(), req.NamespacedName, p)).Should(Succeed())
+			now := metav1.Now()
+			p.DeletionTimestamp = &now
+			Ω(client.Update(context.TODO(), p)).Should(Succeed())
+			r.Reconcile(req)
+		})
+
+		It("should report a missing secret", func() {
+			p := &v1alpha1.PravegaCluster{}
+			Ω(client.Get(context.TODO(), req.NamespacedName, p)).Should(Succeed())
+			Ω(p.Status.ZookeeperCleanup.Message).Should(ContainSubstring("failed to get zookeeper secret (zk-secret)"))
+			Ω(p.Finalizers).Should(ContainElement(util.ZkFinalizer))
+		})
+	})
+})
+
+var _ = Describe("Zookeeper cluster reference", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s      = scheme.Scheme
+		r      *ReconcilePravegaCluster
+		client client.Client
+		req    reconcile.Request
+		p      *v1alpha1.PravegaCluster
+		zk     *unstructured.Unstructured
+	)
+
+	getBookies := func() (*appsv1.StatefulSet, error) {
+		sts := &appsv1.StatefulSet{}
+		nn := types.NamespacedName{Name: util.StatefulSetNameForBookie(Name), Namespace: Namespace}
+		return sts, client.Get(context.TODO(), nn, sts)
+	}
+
+	BeforeEach(func() {
+		req = reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p = &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+		}
+		p.Spec.Version = "0.5.0"
+		p.Spec.Zookeeper = &v1alpha1.ZookeeperSpec{
+			ClusterRef: &v1alpha1.ZookeeperClusterReference{Name: "zookeeper"},
+		}
+		p.WithDefaults()
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p, &v1alpha1.PravegaClusterList{})
+
+		zk = newZookeeperCluster()
+		zk.SetName("zookeeper")
+		zk.SetNamespace(Namespace)
+		Ω(unstructured.SetNestedField(zk.Object, int64(3), "spec", "replicas")).Should(Succeed())
+		Ω(unstructured.SetNestedField(zk.Object, "zookeeper-client:2181", "status", "internalClientEndpoint")).Should(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		client = fake.NewFakeClient(p, zk)
+		r = &ReconcilePravegaCluster{client: client, scheme: s, recorder: record.NewFakeRecorder(100)}
+		r.Reconcile(req)
+	})
+
+	Context("When the zookeeper cluster is not ready", func() {
+		BeforeEach(func() {
+			Ω(unstructured.SetNestedField(zk.Object, int64(1), "status", "readyReplicas")).Should(Succeed())
+		})
+
+		It("should not deploy the bookies", func() {
+			_, err := getBookies()
+			Ω(errors.IsNotFound(err)).Should(BeTrue())
+		})
+	})
+
+	Context("When the zookeeper cluster is ready", func() {
+		BeforeEach(func() {
+			Ω(unstructured.SetNestedField(zk.Object, int64(3), "status", "readyReplicas")).Should(Succeed())
+		})
+
+		It("should connect the cluster to the zookeeper cluster", func() {
+			_, err := getBookies()
+			Ω(err).Should(BeNil())
+			cm := &corev1.ConfigMap{}
+			nn := types.NamespacedName{Name: util.ConfigMapNameForBookie(Name), Namespace: Namespace}
+			Ω(client.Get(context.TODO(), nn, cm)).Should(Succeed())
+			Ω(cm.Data["ZK_URL"]).Should(Equal("zookeeper-client:2181"))
+		})
+
+		It("should report the address in the status and keep the spec", func() {
+			found := &v1alpha1.PravegaCluster{}
+			Ω(client.Get(context.TODO(), req.NamespacedName, found)).Should(Succeed())
+			Ω(found.Spec.ZookeeperUri).Should(Equal(v1alpha1.DefaultZookeeperUri))
+			Ω(found.Status.ZookeeperUri).Should(Equal("zookeeper-client:2181"))
+		})
+	})
+
+	It("should map the zookeeper cluster to the clusters referencing it", func() {
+		mapper := &zookeeperClusterMapper{client: client}
+		requests := mapper.Map(handler.MapObject{Meta: zk, Object: zk})
+		Ω(requests).Should(Equal([]reconcile.Request{req}))
+
+		other := newZookeeperCluster()
+		other.SetName("other")
+		other.SetNamespace(Namespace)
+		Ω(mapper.Map(handler.MapObject{Meta: other, Object: other})).Should(BeEmpty())
+	})
+})
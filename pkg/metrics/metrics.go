@@ -0,0 +1,167 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "pravega_operator"
+
+	// UpgradePhaseNone is the upgrade phase of a cluster that is not upgrading
+	UpgradePhaseNone = "None"
+
+	// Results of the ZooKeeper metadata cleanup
+	ZookeeperCleanupSucceeded = "succeeded"
+	ZookeeperCleanupFailed    = "failed"
+
+	// Results of the admission webhook
+	AdmissionAllowed = "allowed"
+	AdmissionDenied  = "denied"
+)
+
+var (
+	// ReconcileTotal counts the reconciliations of each Pravega cluster
+	ReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_total",
+		Help:      "Total number of reconciliations per Pravega cluster",
+	}, []string{"namespace", "cluster"})
+
+	// ReconcileErrors counts the failed reconciliations of each Pravega cluster
+	ReconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_errors_total",
+		Help:      "Total number of failed reconciliations per Pravega cluster",
+	}, []string{"namespace", "cluster"})
+
+	// ReconcileDuration observes the time spent reconciling each Pravega cluster
+	ReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Time spent reconciling a Pravega cluster",
+	}, []string{"namespace", "cluster"})
+
+	// UpgradePhase is set to 1 for the current upgrade phase of each Pravega
+	// cluster. The phase is the reason of the Upgrading condition, or None
+	UpgradePhase = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upgrade_phase",
+		Help:      "Current upgrade phase of a Pravega cluster",
+	}, []string{"namespace", "cluster", "phase"})
+
+	// UpgradeElapsed is the time since the current upgrade of each Pravega
+	// cluster started, or 0 if the cluster is not upgrading
+	UpgradeElapsed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upgrade_elapsed_seconds",
+		Help:      "Time since the current upgrade of a Pravega cluster started",
+	}, []string{"namespace", "cluster"})
+
+	// UpgradePodDeletions counts the pods deleted by the operator to upgrade
+	// them or to apply a configuration change
+	UpgradePodDeletions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upgrade_pod_deletions_total",
+		Help:      "Total number of pods deleted during upgrades",
+	}, []string{"namespace", "cluster", "component"})
+
+	// ZookeeperCleanups counts the outcomes of the ZooKeeper metadata cleanup
+	// of deleted Pravega clusters
+	ZookeeperCleanups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "zookeeper_cleanup_total",
+		Help:      "Total number of ZooKeeper metadata cleanups by result",
+	}, []string{"result"})
+
+	// WebhookAdmissions counts the requests handled by the admission webhook
+	WebhookAdmissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_admission_total",
+		Help:      "Total number of admission requests by result and reason",
+	}, []string{"result", "reason"})
+
+	// WebhookCertificateExpiry is the expiry time of the certificate of the
+	// admission webhook, when it is loaded from a directory
+	WebhookCertificateExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "webhook_certificate_expiry_timestamp_seconds",
+		Help:      "Expiry time of the certificate of the admission webhook in seconds since the epoch",
+	})
+
+	// upgradePhases keeps the last upgrade phase reported for each cluster,
+	// so that the series of the previous phase can be removed
+	upgradePhases = map[string]string{}
+	mutex         sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		ReconcileTotal,
+		ReconcileErrors,
+		ReconcileDuration,
+		UpgradePhase,
+		UpgradeElapsed,
+		UpgradePodDeletions,
+		ZookeeperCleanups,
+		WebhookAdmissions,
+		WebhookCertificateExpiry,
+	)
+}
+
+// ObserveReconcile records a reconciliation of the given cluster that started
+// at the given time
+func ObserveReconcile(ns, cluster string, start time.Time, err error) {
+	ReconcileTotal.WithLabelValues(ns, cluster).Inc()
+	ReconcileDuration.WithLabelValues(ns, cluster).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ReconcileErrors.WithLabelValues(ns, cluster).Inc()
+	}
+}
+
+// SetUpgradePhase records the current upgrade phase of the given cluster and
+// the time elapsed since the upgrade started
+func SetUpgradePhase(ns, cluster, phase string, elapsed time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	key := ns + "/" + cluster
+	if previous, ok := upgradePhases[key]; ok && previous != phase {
+		UpgradePhase.DeleteLabelValues(ns, cluster, previous)
+	}
+	upgradePhases[key] = phase
+	UpgradePhase.WithLabelValues(ns, cluster, phase).Set(1)
+	UpgradeElapsed.WithLabelValues(ns, cluster).Set(elapsed.Seconds())
+}
+
+// DeleteCluster removes the series of a deleted cluster
+func DeleteCluster(ns, cluster string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	key := ns + "/" + cluster
+	if previous, ok := upgradePhases[key]; ok {
+		UpgradePhase.DeleteLabelValues(ns, cluster, previous)
+		delete(upgradePhases, key)
+	}
+	UpgradeElapsed.DeleteLabelValues(ns, cluster)
+	ReconcileTotal.DeleteLabelValues(ns, cluster)
+	ReconcileErrors.DeleteLabelValues(ns, cluster)
+	ReconcileDuration.DeleteLabelValues(ns, cluster)
+	UpgradePodDeletions.Delete(prometheus.Labels{"namespace": ns, "cluster": cluster, "component": "bookie"})
+	UpgradePodDeletions.Delete(prometheus.Labels{"namespace": ns, "cluster": cluster, "component": "pravega-segmentstore"})
+	UpgradePodDeletions.Delete(prometheus.Labels{"namespace": ns, "cluster": cluster, "component": "pravega-controller"})
+}
@@ -0,0 +1,69 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Metrics")
+}
+
+var _ = Describe("Metrics", func() {
+	const (
+		Namespace = "default"
+		Name      = "example"
+	)
+
+	AfterEach(func() {
+		DeleteCluster(Namespace, Name)
+	})
+
+	Context("Reconcile", func() {
+		It("should count reconciliations and errors", func() {
+			ObserveReconcile(Namespace, Name, time.Now(), nil)
+			ObserveReconcile(Namespace, Name, time.Now(), fmt.Errorf("boom"))
+			Ω(testutil.ToFloat64(ReconcileTotal.WithLabelValues(Namespace, Name))).Should(Equal(2.0))
+			Ω(testutil.ToFloat64(ReconcileErrors.WithLabelValues(Namespace, Name))).Should(Equal(1.0))
+		})
+	})
+
+	Context("Upgrade phase", func() {
+		BeforeEach(func() {
+			SetUpgradePhase(Namespace, Name, "UpgradingBookkeeper", time.Minute)
+			SetUpgradePhase(Namespace, Name, "UpgradingSegmentstore", 2*time.Minute)
+		})
+
+		It("should only report the current phase", func() {
+			Ω(testutil.ToFloat64(UpgradePhase)).Should(Equal(1.0))
+			Ω(testutil.ToFloat64(UpgradePhase.WithLabelValues(Namespace, Name, "UpgradingSegmentstore"))).Should(Equal(1.0))
+		})
+
+		It("should report the elapsed time", func() {
+			Ω(testutil.ToFloat64(UpgradeElapsed.WithLabelValues(Namespace, Name))).Should(Equal(120.0))
+		})
+
+		It("should remove the series of deleted clusters", func() {
+			DeleteCluster(Namespace, Name)
+			Ω(testutil.CollectAndCompare(UpgradePhase, strings.NewReader(""))).Should(Succeed())
+		})
+	})
+})
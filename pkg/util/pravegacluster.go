@@ -27,6 +27,14 @@ var (
 
 const (
 	MajorMinorVersionRegexp string = `^v?(?P<Version>[0-9]+\.[0-9]+\.[0-9]+)`
+
+	// ConfigHashAnnotation is the pod annotation that holds the hash of the
+	// rendered config map and pod template the pod was created from
+	ConfigHashAnnotation = "pravega.config-hash"
+
+	// ConfigKeysAnnotation is the config map annotation that lists the keys
+	// of the data owned by the operator
+	ConfigKeysAnnotation = "pravega.pravega.io/config-keys"
 )
 
 func init() {
@@ -164,31 +172,72 @@ func GetClusterExpectedSize(p *v1alpha1.PravegaCluster) (size int) {
 }
 
 func PravegaImage(p *v1alpha1.PravegaCluster) (image string) {
-	return fmt.Sprintf("%s:%s", p.Spec.Pravega.Image.Repository, p.Spec.Version)
+	return imageReference(&p.Spec.Pravega.Image.ImageSpec, p.Spec.Version)
 }
 
 func BookkeeperImage(p *v1alpha1.PravegaCluster) (image string) {
-	return fmt.Sprintf("%s:%s", p.Spec.Bookkeeper.Image.Repository, p.Spec.Version)
+	return imageReference(&p.Spec.Bookkeeper.Image.ImageSpec, p.BookkeeperVersion())
 }
 
 func PravegaTargetImage(p *v1alpha1.PravegaCluster) (string, error) {
 	if p.Status.TargetVersion == "" {
 		return "", fmt.Errorf("target version is not set")
 	}
-	return fmt.Sprintf("%s:%s", p.Spec.Pravega.Image.Repository, p.Status.TargetVersion), nil
+	return imageReference(&p.Spec.Pravega.Image.ImageSpec, p.Status.TargetVersion), nil
 }
 
 func BookkeeperTargetImage(p *v1alpha1.PravegaCluster) (string, error) {
-	if p.Status.TargetVersion == "" {
-		return "", fmt.Errorf("target version is not set")
+	if p.Status.TargetBookkeeperVersion == "" {
+		return "", fmt.Errorf("target bookkeeper version is not set")
+	}
+	return imageReference(&p.Spec.Bookkeeper.Image.ImageSpec, p.Status.TargetBookkeeperVersion), nil
+}
+
+// imageReference returns the reference of an image of the given version. The
+// digest and the tag of the image, if set, take precedence over the version.
+// A repository pinned to a digest, e.g. "pravega/pravega@sha256:...", is used
+// as is.
+func imageReference(image *v1alpha1.ImageSpec, version string) string {
+	if IsDigestReference(image.Repository) {
+		return image.Repository
+	}
+	if image.Digest != "" {
+		return fmt.Sprintf("%s@%s", image.Repository, image.Digest)
+	}
+	if image.Tag != "" {
+		return fmt.Sprintf("%s:%s", image.Repository, image.Tag)
 	}
-	return fmt.Sprintf("%s:%s", p.Spec.Bookkeeper.Image.Repository, p.Status.TargetVersion), nil
+	return fmt.Sprintf("%s:%s", image.Repository, version)
+}
+
+// IsDigestReference returns true if an image reference is pinned to a digest
+func IsDigestReference(image string) bool {
+	return strings.Contains(image, "@")
+}
+
+// ImagePullSecrets returns the image pull secrets of the cluster followed by
+// those of a component, without duplicates
+func ImagePullSecrets(p *v1alpha1.PravegaCluster, component []v1.LocalObjectReference) []v1.LocalObjectReference {
+	var secrets []v1.LocalObjectReference
+	seen := map[string]bool{}
+	for _, secret := range append(append([]v1.LocalObjectReference{}, p.Spec.ImagePullSecrets...), component...) {
+		if seen[secret.Name] {
+			continue
+		}
+		seen[secret.Name] = true
+		secrets = append(secrets, secret)
+	}
+	return secrets
 }
 
 func GetPodVersion(pod *v1.Pod) string {
 	return pod.GetAnnotations()["pravega.version"]
 }
 
+func GetPodConfigHash(pod *v1.Pod) string {
+	return pod.GetAnnotations()[ConfigHashAnnotation]
+}
+
 func CompareVersions(v1, v2, operator string) (bool, error) {
 	normv1, err := NormalizeVersion(v1)
 	if err != nil {
@@ -0,0 +1,163 @@
+/**
+ * Copyright (c) 2018 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+)
+
+// VersionMatrix lists the supported Pravega versions and, for each of them,
+// the versions it can be upgraded to
+type VersionMatrix map[string][]string
+
+// VersionMatrixCompiledIn is the source of the version matrix built into the
+// operator
+const VersionMatrixCompiledIn = "compiled-in table"
+
+var (
+	// defaultVersionMatrix is used until a version matrix file is loaded, and
+	// if no version matrix file is configured
+	defaultVersionMatrix = VersionMatrix{
+		"0.1.0": []string{"0.1.0"},
+		"0.2.0": []string{"0.2.0"},
+		"0.3.0": []string{"0.3.0", "0.3.1", "0.3.2"},
+		"0.3.1": []string{"0.3.1", "0.3.2"},
+		"0.3.2": []string{"0.3.2"},
+		"0.4.0": []string{"0.4.0"},
+		"0.5.0": []string{"0.5.0", "0.6.0"},
+		"0.6.0": []string{"0.6.0"},
+	}
+
+	versionMatrix = &versionMatrixState{
+		matrix: defaultVersionMatrix,
+		source: VersionMatrixCompiledIn,
+	}
+)
+
+type versionMatrixState struct {
+	sync.RWMutex
+	matrix  VersionMatrix
+	source  string
+	modTime time.Time
+	size    int64
+}
+
+// SupportedVersions returns the version matrix in use and where it was loaded
+// from
+func SupportedVersions() (VersionMatrix, string) {
+	versionMatrix.RLock()
+	defer versionMatrix.RUnlock()
+	return versionMatrix.matrix, versionMatrix.source
+}
+
+// ResetVersionMatrix goes back to the version matrix built into the operator
+func ResetVersionMatrix() {
+	versionMatrix.Lock()
+	defer versionMatrix.Unlock()
+	versionMatrix.matrix = defaultVersionMatrix
+	versionMatrix.source = VersionMatrixCompiledIn
+	versionMatrix.modTime = time.Time{}
+	versionMatrix.size = 0
+}
+
+// LoadVersionMatrix loads the version matrix from a YAML or JSON file, unless
+// the file has not changed since it was last loaded. The file maps each
+// supported version to the list of versions it can be upgraded to. The
+// version matrix in use is left unchanged if the file cannot be loaded.
+func LoadVersionMatrix(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read version matrix (%s): %v", path, err)
+	}
+
+	versionMatrix.RLock()
+	unchanged := versionMatrix.source == path && info.ModTime().Equal(versionMatrix.modTime) && info.Size() == versionMatrix.size
+	versionMatrix.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read version matrix (%s): %v", path, err)
+	}
+	matrix, err := parseVersionMatrix(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse version matrix (%s): %v", path, err)
+	}
+
+	versionMatrix.Lock()
+	versionMatrix.matrix = matrix
+	versionMatrix.source = path
+	versionMatrix.modTime = info.ModTime()
+	versionMatrix.size = info.Size()
+	versionMatrix.Unlock()
+	log.Infof("loaded version matrix with %d versions from %s", len(matrix), path)
+	return nil
+}
+
+// WatchVersionMatrix reloads the version matrix from a file every interval
+// until stop is closed, so that changes to the file, e.g. to a mounted config
+// map, are picked up without restarting the operator
+func WatchVersionMatrix(path string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := LoadVersionMatrix(path); err != nil {
+				_, source := SupportedVersions()
+				log.Errorf("%v, still using the version matrix from %s", err, source)
+			}
+		}
+	}
+}
+
+// parseVersionMatrix normalizes the versions of a version matrix. Every
+// version can be upgraded to itself, e.g. to a newer build of the same version.
+func parseVersionMatrix(data []byte) (VersionMatrix, error) {
+	raw := map[string][]string{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no versions")
+	}
+
+	matrix := VersionMatrix{}
+	for version, upgrades := range raw {
+		from, err := NormalizeVersion(version)
+		if err != nil {
+			return nil, err
+		}
+		list := []string{from}
+		for _, upgrade := range upgrades {
+			to, err := NormalizeVersion(upgrade)
+			if err != nil {
+				return nil, err
+			}
+			if to != from {
+				list = append(list, to)
+			}
+		}
+		matrix[from] = list
+	}
+	return matrix, nil
+}
@@ -12,7 +12,12 @@ package util
 
 import (
 	"container/list"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
@@ -25,16 +30,92 @@ const (
 	ZkFinalizer = "cleanUpZookeeper"
 )
 
-// Delete all znodes related to a specific Pravega cluster
-func DeleteAllZnodes(p *v1alpha1.PravegaCluster) (err error) {
-	host := []string{p.Spec.ZookeeperUri}
-	conn, _, err := zk.Connect(host, time.Second*5)
+// Delete all znodes related to a specific Pravega cluster. Requests to an
+// unavailable ZooKeeper wait until the connection is established, so the
+// deletion is aborted if it does not complete within the timeout. The secret
+// data holds the credentials and certificates to use when authentication or
+// TLS is enabled.
+func DeleteAllZnodes(p *v1alpha1.PravegaCluster, secret map[string][]byte, timeout time.Duration) (err error) {
+	dialer := net.DialTimeout
+	if p.Spec.Zookeeper.IsTLSEnabled() {
+		tlsConfig, err := zookeeperTLSConfig(secret)
+		if err != nil {
+			return err
+		}
+		dialer = func(network, address string, timeout time.Duration) (net.Conn, error) {
+			return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, network, address, tlsConfig)
+		}
+	}
+
+	host := []string{p.ZookeeperAddress()}
+	conn, _, err := zk.Connect(host, time.Second*5, zk.WithDialer(dialer))
 	if err != nil {
 		return fmt.Errorf("failed to connect to zookeeper: %v", err)
 	}
-	defer conn.Close()
 
-	root := fmt.Sprintf("/%s/%s", PravegaPath, p.Name)
+	var (
+		once     sync.Once
+		timedOut int32
+	)
+	closeConn := func() {
+		once.Do(conn.Close)
+	}
+	defer closeConn()
+
+	// Closing the connection makes the pending requests fail
+	timer := time.AfterFunc(timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		closeConn()
+	})
+	defer timer.Stop()
+
+	defer func() {
+		if err != nil && atomic.LoadInt32(&timedOut) == 1 {
+			err = fmt.Errorf("timed out after %v: %v", timeout, err)
+		}
+	}()
+
+	if p.Spec.Zookeeper.IsAuthEnabled() {
+		username := string(secret[v1alpha1.ZookeeperSecretUsernameKey])
+		password := string(secret[v1alpha1.ZookeeperSecretPasswordKey])
+		if username == "" {
+			return fmt.Errorf("missing key %s in zookeeper secret", v1alpha1.ZookeeperSecretUsernameKey)
+		}
+		err = conn.AddAuth("digest", []byte(username+":"+password))
+		if err != nil {
+			return fmt.Errorf("failed to authenticate to zookeeper: %v", err)
+		}
+	}
+
+	return deleteZnodes(conn, fmt.Sprintf("/%s/%s", PravegaPath, p.Name))
+}
+
+// zookeeperTLSConfig returns the TLS configuration to connect to ZooKeeper
+// with the CA certificate, and the optional client certificate, of the secret
+func zookeeperTLSConfig(secret map[string][]byte) (*tls.Config, error) {
+	caCert, ok := secret[v1alpha1.ZookeeperSecretCACertKey]
+	if !ok {
+		return nil, fmt.Errorf("missing key %s in zookeeper secret", v1alpha1.ZookeeperSecretCACertKey)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse zookeeper CA certificate")
+	}
+	config := &tls.Config{RootCAs: pool}
+
+	cert, hasCert := secret[v1alpha1.ZookeeperSecretCertKey]
+	key, hasKey := secret[v1alpha1.ZookeeperSecretKeyKey]
+	if hasCert && hasKey {
+		certificate, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse zookeeper client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+func deleteZnodes(conn *zk.Conn, root string) error {
 	exist, _, err := conn.Exists(root)
 	if err != nil {
 		return fmt.Errorf("failed to check if zookeeper path exists: %v", err)
@@ -49,7 +130,7 @@ func DeleteAllZnodes(p *v1alpha1.PravegaCluster) (err error) {
 
 		for tree.Len() != 0 {
 			err := conn.Delete(tree.Back().Value.(string), -1)
-			if err != nil {
+			if err != nil && err != zk.ErrNoNode {
 				return fmt.Errorf("failed to delete znode (%s): %v", tree.Back().Value.(string), err)
 			}
 			tree.Remove(tree.Back())
@@ -0,0 +1,360 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateCluster validates the spec of a Pravega cluster. It is run by the
+// validating webhook, and by the reconciler when the webhook is disabled. The
+// spec does not need to have its defaults set. The errors hold the path of
+// the invalid fields, e.g. "spec.pravega.tier2.ecs.credentials".
+func ValidateCluster(p *pravegav1alpha1.PravegaCluster) field.ErrorList {
+	allErrs := validateClusterSpec(&p.Spec, field.NewPath("spec"))
+	// The external access of the controller differs from the external access
+	// of the cluster when it is set in v1beta1
+	if e := p.ControllerExternalAccess(); e != p.Spec.ExternalAccess {
+		allErrs = append(allErrs, validateExternalAccess(e, field.NewPath("spec", "pravega", "controllerExternalAccess"))...)
+	}
+	return allErrs
+}
+
+// InvalidError returns the error of a Pravega cluster that is not valid, in
+// the format used by the API server
+func InvalidError(p *pravegav1alpha1.PravegaCluster, errs field.ErrorList) error {
+	return errors.NewInvalid(pravegav1alpha1.SchemeGroupVersion.WithKind("PravegaCluster").GroupKind(), p.Name, errs)
+}
+
+// ValidateClusterUpdate validates the update of a Pravega cluster. Besides
+// the checks of ValidateCluster, it rejects the changes to the fields that
+// cannot change once the cluster is created, unless the cluster has the
+// AllowImmutableChangesAnnotation.
+func ValidateClusterUpdate(p, old *pravegav1alpha1.PravegaCluster) field.ErrorList {
+	allErrs := ValidateCluster(p)
+	if !p.AllowsImmutableChanges() {
+		allErrs = append(allErrs, ValidateImmutableFields(p, old)...)
+	}
+	return allErrs
+}
+
+// segmentStoreContainerCountOptions are the Pravega options setting the
+// number of segment containers, which is persisted in ZooKeeper
+var segmentStoreContainerCountOptions = []string{"pravegaservice.containerCount", "pravegaservice.container.count"}
+
+// ValidateImmutableFields returns the fields changed by the update of a
+// Pravega cluster that cannot change once the cluster is created. The volume
+// claim templates of the stateful sets are immutable, and the tier 2 storage,
+// the ZooKeeper URI or ZookeeperCluster and the number of segment containers
+// are persisted by Pravega. The defaults are set on copies of the clusters, so that the
+// fields left unset are compared with the values the operator would set.
+func ValidateImmutableFields(p, old *pravegav1alpha1.PravegaCluster) field.ErrorList {
+	allErrs := field.ErrorList{}
+	newSpec, oldSpec := p.DeepCopy(), old.DeepCopy()
+	newSpec.WithDefaults()
+	oldSpec.WithDefaults()
+	fldPath := field.NewPath("spec")
+
+	// Referencing another ZookeeperCluster, or none, moves the cluster to
+	// another ZooKeeper just like changing the URI. The URI is not used by the
+	// clusters that reference a ZookeeperCluster.
+	newRef, oldRef := newSpec.Spec.Zookeeper.ClusterRef, oldSpec.Spec.Zookeeper.ClusterRef
+	if !equality.Semantic.DeepEqual(newRef, oldRef) {
+		allErrs = append(allErrs, immutable(fldPath.Child("zookeeper", "clusterRef")))
+	} else if newRef == nil && newSpec.Spec.ZookeeperUri != oldSpec.Spec.ZookeeperUri {
+		allErrs = append(allErrs, immutable(fldPath.Child("zookeeperUri")))
+	}
+
+	bkPath := fldPath.Child("bookkeeper", "storage")
+	newStorage, oldStorage := newSpec.Spec.Bookkeeper.Storage, oldSpec.Spec.Bookkeeper.Storage
+	if !equality.Semantic.DeepEqual(newStorage.LedgerVolumeClaimTemplate, oldStorage.LedgerVolumeClaimTemplate) {
+		allErrs = append(allErrs, immutable(bkPath.Child("ledgerVolumeClaimTemplate")))
+	}
+	if !equality.Semantic.DeepEqual(newStorage.JournalVolumeClaimTemplate, oldStorage.JournalVolumeClaimTemplate) {
+		allErrs = append(allErrs, immutable(bkPath.Child("journalVolumeClaimTemplate")))
+	}
+	if !equality.Semantic.DeepEqual(newStorage.IndexVolumeClaimTemplate, oldStorage.IndexVolumeClaimTemplate) {
+		allErrs = append(allErrs, immutable(bkPath.Child("indexVolumeClaimTemplate")))
+	}
+
+	pravegaPath := fldPath.Child("pravega")
+	newPravega, oldPravega := newSpec.Spec.Pravega, oldSpec.Spec.Pravega
+	if !equality.Semantic.DeepEqual(newPravega.CacheVolumeClaimTemplate, oldPravega.CacheVolumeClaimTemplate) {
+		allErrs = append(allErrs, immutable(pravegaPath.Child("cacheVolumeClaimTemplate")))
+	}
+	allErrs = append(allErrs, validateTier2Update(newPravega.Tier2, oldPravega.Tier2, pravegaPath.Child("tier2"))...)
+	for _, key := range segmentStoreContainerCountOptions {
+		if newPravega.Options[key] != oldPravega.Options[key] {
+			allErrs = append(allErrs, immutable(pravegaPath.Child("options").Key(key)))
+		}
+	}
+	return allErrs
+}
+
+// validateTier2Update checks that neither the tier 2 storage type nor the
+// location of the data in the storage change
+func validateTier2Update(s, old *pravegav1alpha1.Tier2Spec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	storageType, oldStorageType := tier2Type(s), tier2Type(old)
+	if storageType != oldStorageType {
+		allErrs = append(allErrs, field.Forbidden(fldPath, fmt.Sprintf(
+			"tier 2 storage type cannot change from %s to %s once the cluster is created, %s",
+			oldStorageType, storageType, forceChange)))
+		return allErrs
+	}
+
+	switch storageType {
+	case "filesystem":
+		if tier2ClaimName(s) != tier2ClaimName(old) {
+			allErrs = append(allErrs, immutable(fldPath.Child("filesystem", "persistentVolumeClaim", "claimName")))
+		}
+	case "ecs":
+		if s.Ecs.Root != old.Ecs.Root {
+			allErrs = append(allErrs, immutable(fldPath.Child("ecs", "root")))
+		}
+	case "hdfs":
+		if s.Hdfs.Root != old.Hdfs.Root {
+			allErrs = append(allErrs, immutable(fldPath.Child("hdfs", "root")))
+		}
+	}
+	return allErrs
+}
+
+func tier2Type(s *pravegav1alpha1.Tier2Spec) string {
+	switch {
+	case s.Ecs != nil:
+		return "ecs"
+	case s.Hdfs != nil:
+		return "hdfs"
+	default:
+		return "filesystem"
+	}
+}
+
+func tier2ClaimName(s *pravegav1alpha1.Tier2Spec) string {
+	if s.FileSystem == nil || s.FileSystem.PersistentVolumeClaim == nil {
+		return ""
+	}
+	return s.FileSystem.PersistentVolumeClaim.ClaimName
+}
+
+var forceChange = fmt.Sprintf("set the annotation %s=true to force the change", pravegav1alpha1.AllowImmutableChangesAnnotation)
+
+func immutable(fldPath *field.Path) *field.Error {
+	return field.Forbidden(fldPath, "field is immutable once the cluster is created, "+forceChange)
+}
+
+func validateClusterSpec(spec *pravegav1alpha1.ClusterSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if spec.Zookeeper != nil && spec.Zookeeper.ClusterRef != nil && spec.Zookeeper.ClusterRef.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("zookeeper", "clusterRef", "name"), ""))
+	}
+	if spec.ExternalAccess != nil {
+		allErrs = append(allErrs, validateExternalAccess(spec.ExternalAccess, fldPath.Child("externalAccess"))...)
+	}
+	allErrs = append(allErrs, validateImagePullSecrets(spec.ImagePullSecrets, fldPath.Child("imagePullSecrets"))...)
+	if spec.Bookkeeper != nil {
+		allErrs = append(allErrs, validateBookkeeperSpec(spec.Bookkeeper, fldPath.Child("bookkeeper"))...)
+	}
+	if spec.Pravega != nil {
+		allErrs = append(allErrs, validatePravegaSpec(spec.Pravega, fldPath.Child("pravega"))...)
+	}
+	return allErrs
+}
+
+var supportedServiceTypes = []string{string(corev1.ServiceTypeLoadBalancer), string(corev1.ServiceTypeNodePort)}
+
+func validateExternalAccess(e *pravegav1alpha1.ExternalAccess, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if e.Type != "" && !contains(supportedServiceTypes, string(e.Type)) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), e.Type, supportedServiceTypes))
+	}
+	if e.DomainName != "" {
+		// The domain name may be fully qualified, i.e. end with a dot
+		for _, msg := range validation.IsDNS1123Subdomain(strings.TrimSuffix(e.DomainName, ".")) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("domainName"), e.DomainName, msg))
+		}
+	}
+	return allErrs
+}
+
+func validateBookkeeperSpec(s *pravegav1alpha1.BookkeeperSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateNonnegative(int64(s.Replicas), fldPath.Child("replicas"))...)
+	if s.Storage != nil {
+		allErrs = append(allErrs, validateReclaimPolicy(s.Storage.ReclaimPolicy, fldPath.Child("storage", "reclaimPolicy"))...)
+	}
+	allErrs = append(allErrs, validateImagePullSecrets(s.ImagePullSecrets, fldPath.Child("imagePullSecrets"))...)
+	allErrs = append(allErrs, validateResources(s.Resources, fldPath.Child("resources"))...)
+	return allErrs
+}
+
+func validatePravegaSpec(s *pravegav1alpha1.PravegaSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateNonnegative(int64(s.ControllerReplicas), fldPath.Child("controllerReplicas"))...)
+	allErrs = append(allErrs, validateNonnegative(int64(s.SegmentStoreReplicas), fldPath.Child("segmentStoreReplicas"))...)
+	allErrs = append(allErrs, validateReclaimPolicy(s.CacheVolumeReclaimPolicy, fldPath.Child("cacheVolumeReclaimPolicy"))...)
+	if s.Tier2 != nil {
+		allErrs = append(allErrs, validateTier2(s.Tier2, fldPath.Child("tier2"))...)
+	}
+	allErrs = append(allErrs, validateImagePullSecrets(s.ImagePullSecrets, fldPath.Child("imagePullSecrets"))...)
+	allErrs = append(allErrs, validateResources(s.ControllerResources, fldPath.Child("controllerResources"))...)
+	allErrs = append(allErrs, validateResources(s.SegmentStoreResources, fldPath.Child("segmentStoreResources"))...)
+	return allErrs
+}
+
+// validateTier2 checks that at most one tier 2 storage type is set, and that
+// it has the fields the segment stores need to connect to it
+func validateTier2(s *pravegav1alpha1.Tier2Spec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	types := 0
+
+	if s.FileSystem != nil {
+		types++
+		fsPath := fldPath.Child("filesystem")
+		if s.FileSystem.PersistentVolumeClaim == nil {
+			allErrs = append(allErrs, field.Required(fsPath.Child("persistentVolumeClaim"), ""))
+		} else if s.FileSystem.PersistentVolumeClaim.ClaimName == "" {
+			allErrs = append(allErrs, field.Required(fsPath.Child("persistentVolumeClaim", "claimName"), ""))
+		}
+	}
+
+	if s.Ecs != nil {
+		ecsPath := fldPath.Child("ecs")
+		if types > 0 {
+			allErrs = append(allErrs, field.Forbidden(ecsPath, "may not specify more than 1 tier 2 storage type"))
+		} else {
+			types++
+			if s.Ecs.Uri == "" {
+				allErrs = append(allErrs, field.Required(ecsPath.Child("uri"), ""))
+			}
+			if s.Ecs.Bucket == "" {
+				allErrs = append(allErrs, field.Required(ecsPath.Child("bucket"), ""))
+			}
+			if s.Ecs.Credentials == "" {
+				allErrs = append(allErrs, field.Required(ecsPath.Child("credentials"), "name of the secret holding the ECS credentials"))
+			} else {
+				allErrs = append(allErrs, validateObjectName(s.Ecs.Credentials, ecsPath.Child("credentials"))...)
+			}
+		}
+	}
+
+	if s.Hdfs != nil {
+		hdfsPath := fldPath.Child("hdfs")
+		if types > 0 {
+			allErrs = append(allErrs, field.Forbidden(hdfsPath, "may not specify more than 1 tier 2 storage type"))
+		} else {
+			if s.Hdfs.Uri == "" {
+				allErrs = append(allErrs, field.Required(hdfsPath.Child("uri"), ""))
+			}
+			allErrs = append(allErrs, validateNonnegative(int64(s.Hdfs.ReplicationFactor), hdfsPath.Child("replicationFactor"))...)
+		}
+	}
+	return allErrs
+}
+
+// validateResources checks that the resource quantities are not negative,
+// and that the requests do not exceed the limits
+func validateResources(r *corev1.ResourceRequirements, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if r == nil {
+		return allErrs
+	}
+
+	// The resources are sorted so that the errors are in a stable order
+	for _, name := range resourceNames(r.Limits) {
+		quantity := r.Limits[name]
+		if quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("limits").Key(string(name)), quantity.String(),
+				"must be greater than or equal to 0"))
+		}
+	}
+	for _, name := range resourceNames(r.Requests) {
+		quantity := r.Requests[name]
+		path := fldPath.Child("requests").Key(string(name))
+		if quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(path, quantity.String(), "must be greater than or equal to 0"))
+			continue
+		}
+		if limit, ok := r.Limits[name]; ok && quantity.Cmp(limit) > 0 {
+			allErrs = append(allErrs, field.Invalid(path, quantity.String(),
+				fmt.Sprintf("must be less than or equal to %s limit of %s", name, limit.String())))
+		}
+	}
+	return allErrs
+}
+
+var supportedReclaimPolicies = []string{string(pravegav1alpha1.ReclaimPolicyRetain), string(pravegav1alpha1.ReclaimPolicyDelete)}
+
+func validateReclaimPolicy(policy pravegav1alpha1.ReclaimPolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if policy != "" && !contains(supportedReclaimPolicies, string(policy)) {
+		allErrs = append(allErrs, field.NotSupported(fldPath, policy, supportedReclaimPolicies))
+	}
+	return allErrs
+}
+
+func validateImagePullSecrets(secrets []corev1.LocalObjectReference, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, secret := range secrets {
+		path := fldPath.Index(i).Child("name")
+		if secret.Name == "" {
+			allErrs = append(allErrs, field.Required(path, ""))
+			continue
+		}
+		allErrs = append(allErrs, validateObjectName(secret.Name, path)...)
+	}
+	return allErrs
+}
+
+func validateObjectName(name string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, msg := range validation.IsDNS1123Subdomain(name) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, msg))
+	}
+	return allErrs
+}
+
+func validateNonnegative(value int64, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if value < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, "must be greater than or equal to 0"))
+	}
+	return allErrs
+}
+
+func resourceNames(resources corev1.ResourceList) []corev1.ResourceName {
+	var names []corev1.ResourceName
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Validation")
+}
+
+var _ = Describe("Cluster validation", func() {
+	var (
+		p *v1alpha1.PravegaCluster
+	)
+
+	fields := func(errs field.ErrorList) []string {
+		var fields []string
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		return fields
+	}
+
+	BeforeEach(func() {
+		p = &v1alpha1.PravegaCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example",
+				Namespace: "default",
+			},
+		}
+	})
+
+	It("should accept an empty spec", func() {
+		Ω(ValidateCluster(p)).Should(BeEmpty())
+	})
+
+	It("should accept the defaults", func() {
+		p.WithDefaults()
+		Ω(ValidateCluster(p)).Should(BeEmpty())
+	})
+
+	Context("Tier 2", func() {
+		BeforeEach(func() {
+			p.Spec.Pravega = &v1alpha1.PravegaSpec{Tier2: &v1alpha1.Tier2Spec{}}
+		})
+
+		It("should reject more than one storage type", func() {
+			p.Spec.Pravega.Tier2.FileSystem = &v1alpha1.FileSystemSpec{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "pravega-tier2"},
+			}
+			p.Spec.Pravega.Tier2.Hdfs = &v1alpha1.HDFSSpec{Uri: "hdfs://hdfs:8020"}
+			errs := ValidateCluster(p)
+			Ω(errs).Should(HaveLen(1))
+			Ω(errs[0].Type).Should(Equal(field.ErrorTypeForbidden))
+			Ω(errs[0].Field).Should(Equal("spec.pravega.tier2.hdfs"))
+		})
+
+		It("should require the ECS credentials", func() {
+			p.Spec.Pravega.Tier2.Ecs = &v1alpha1.ECSSpec{Uri: "https://ecs:9021", Bucket: "pravega"}
+			errs := ValidateCluster(p)
+			Ω(fields(errs)).Should(Equal([]string{"spec.pravega.tier2.ecs.credentials"}))
+			Ω(errs[0].Type).Should(Equal(field.ErrorTypeRequired))
+		})
+
+		It("should accept a complete ECS configuration", func() {
+			p.Spec.Pravega.Tier2.Ecs = &v1alpha1.ECSSpec{Uri: "https://ecs:9021", Bucket: "pravega", Credentials: "ecs-credentials"}
+			Ω(ValidateCluster(p)).Should(BeEmpty())
+		})
+
+		It("should require the claim name of the filesystem", func() {
+			p.Spec.Pravega.Tier2.FileSystem = &v1alpha1.FileSystemSpec{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{},
+			}
+			Ω(fields(ValidateCluster(p))).Should(Equal([]string{"spec.pravega.tier2.filesystem.persistentVolumeClaim.claimName"}))
+		})
+	})
+
+	Context("Replicas", func() {
+		It("should reject negative replicas", func() {
+			p.Spec.Bookkeeper = &v1alpha1.BookkeeperSpec{Replicas: -1}
+			p.Spec.Pravega = &v1alpha1.PravegaSpec{ControllerReplicas: 1, SegmentStoreReplicas: -3}
+			errs := ValidateCluster(p)
+			Ω(fields(errs)).Should(Equal([]string{"spec.bookkeeper.replicas", "spec.pravega.segmentStoreReplicas"}))
+			Ω(errs[1].Error()).Should(Equal("spec.pravega.segmentStoreReplicas: Invalid value: -3: must be greater than or equal to 0"))
+		})
+	})
+
+	Context("Resources", func() {
+		It("should reject limits below requests", func() {
+			p.Spec.Bookkeeper = &v1alpha1.BookkeeperSpec{
+				Resources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("2"),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("1"),
+						corev1.ResourceMemory: resource.MustParse("2Gi"),
+					},
+				},
+			}
+			errs := ValidateCluster(p)
+			Ω(errs).Should(HaveLen(1))
+			Ω(errs[0].Error()).Should(Equal(`spec.bookkeeper.resources.requests[cpu]: Invalid value: "2": must be less than or equal to cpu limit of 1`))
+		})
+
+		It("should reject negative quantities", func() {
+			p.Spec.Pravega = &v1alpha1.PravegaSpec{
+				ControllerResources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("-1Gi")},
+				},
+			}
+			Ω(fields(ValidateCluster(p))).Should(Equal([]string{"spec.pravega.controllerResources.limits[memory]"}))
+		})
+	})
+
+	Context("External access", func() {
+		BeforeEach(func() {
+			p.Spec.ExternalAccess = &v1alpha1.ExternalAccess{Enabled: true, Type: corev1.ServiceTypeNodePort}
+		})
+
+		It("should accept a domain name", func() {
+			p.Spec.ExternalAccess.DomainName = "pravega.example.com."
+			Ω(ValidateCluster(p)).Should(BeEmpty())
+		})
+
+		It("should reject invalid characters in the domain name", func() {
+			p.Spec.ExternalAccess.DomainName = "pravega_example.com"
+			Ω(fields(ValidateCluster(p))).Should(Equal([]string{"spec.externalAccess.domainName"}))
+		})
+
+		It("should reject unsupported service types", func() {
+			p.Spec.ExternalAccess.Type = corev1.ServiceTypeExternalName
+			errs := ValidateCluster(p)
+			Ω(fields(errs)).Should(Equal([]string{"spec.externalAccess.type"}))
+			Ω(errs[0].Type).Should(Equal(field.ErrorTypeNotSupported))
+		})
+	})
+
+	Context("Other fields", func() {
+		It("should reject unsupported reclaim policies", func() {
+			p.Spec.Pravega = &v1alpha1.PravegaSpec{CacheVolumeReclaimPolicy: "Recycle"}
+			Ω(fields(ValidateCluster(p))).Should(Equal([]string{"spec.pravega.cacheVolumeReclaimPolicy"}))
+		})
+
+		It("should require the names of the image pull secrets", func() {
+			p.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}, {}}
+			Ω(fields(ValidateCluster(p))).Should(Equal([]string{"spec.imagePullSecrets[1].name"}))
+		})
+
+		It("should report all the errors", func() {
+			p.Spec.Bookkeeper = &v1alpha1.BookkeeperSpec{Replicas: -1}
+			p.Spec.ExternalAccess = &v1alpha1.ExternalAccess{DomainName: "-pravega"}
+			errs := ValidateCluster(p)
+			Ω(errs).Should(HaveLen(2))
+			Ω(InvalidError(p, errs).Error()).Should(HavePrefix(`PravegaCluster.pravega.pravega.io "example" is invalid: [`))
+		})
+	})
+
+	Context("Cluster update", func() {
+		var (
+			old *v1alpha1.PravegaCluster
+		)
+
+		BeforeEach(func() {
+			p.Spec.Version = "0.5.0"
+			p.WithDefaults()
+			old = p.DeepCopy()
+		})
+
+		It("should accept changes to mutable fields", func() {
+			p.Spec.Pravega.SegmentStoreReplicas = 5
+			p.Spec.Pravega.Options["pravegaservice.cacheMaxSize"] = "1073741824"
+			Ω(ValidateClusterUpdate(p, old)).Should(BeEmpty())
+		})
+
+		It("should compare unset fields with their defaults", func() {
+			p.Spec.Bookkeeper.Storage = nil
+			p.Spec.Pravega.CacheVolumeClaimTemplate = nil
+			p.Spec.Pravega.Tier2 = nil
+			Ω(ValidateClusterUpdate(p, old)).Should(BeEmpty())
+		})
+
+		It("should reject changes to the volume claim templates", func() {
+			p.Spec.Bookkeeper.Storage.LedgerVolumeClaimTemplate.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("20Gi")
+			p.Spec.Pravega.CacheVolumeClaimTemplate.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("40Gi")
+			errs := ValidateClusterUpdate(p, old)
+			Ω(fields(errs)).Should(Equal([]string{
+				"spec.bookkeeper.storage.ledgerVolumeClaimTemplate",
+				"spec.pravega.cacheVolumeClaimTemplate",
+			}))
+			Ω(errs[0].Error()).Should(Equal("spec.bookkeeper.storage.ledgerVolumeClaimTemplate: Forbidden: field is immutable once the cluster is created, " +
+				"set the annotation pravega.pravega.io/allow-immutable-changes=true to force the change"))
+		})
+
+		It("should reject a change of the tier 2 storage type", func() {
+			p.Spec.Pravega.Tier2 = &v1alpha1.Tier2Spec{Hdfs: &v1alpha1.HDFSSpec{Uri: "hdfs://hdfs:8020", Root: "/pravega"}}
+			errs := ValidateClusterUpdate(p, old)
+			Ω(fields(errs)).Should(Equal([]string{"spec.pravega.tier2"}))
+			Ω(errs[0].Detail).Should(HavePrefix("tier 2 storage type cannot change from filesystem to hdfs"))
+		})
+
+		It("should reject a change of the tier 2 root", func() {
+			p.Spec.Pravega.Tier2 = &v1alpha1.Tier2Spec{Ecs: &v1alpha1.ECSSpec{Uri: "https://ecs:9021", Bucket: "pravega", Root: "/a", Credentials: "ecs"}}
+			old = p.DeepCopy()
+			p.Spec.Pravega.Tier2.Ecs.Root = "/b"
+			Ω(fields(ValidateClusterUpdate(p, old))).Should(Equal([]string{"spec.pravega.tier2.ecs.root"}))
+		})
+
+		It("should reject changes to the ZooKeeper URI and the container count", func() {
+			p.Spec.ZookeeperUri = "zookeeper:2181"
+			p.Spec.Pravega.Options["pravegaservice.containerCount"] = "8"
+			Ω(fields(ValidateClusterUpdate(p, old))).Should(Equal([]string{
+				"spec.zookeeperUri",
+				"spec.pravega.options[pravegaservice.containerCount]",
+			}))
+		})
+
+		It("should accept changes to the unused ZooKeeper URI of a referenced ZookeeperCluster", func() {
+			p.Spec.Zookeeper.ClusterRef = &v1alpha1.ZookeeperClusterReference{Name: "zookeeper"}
+			old = p.DeepCopy()
+			p.Spec.ZookeeperUri = "zookeeper-client.default.svc.cluster.local:2181"
+			Ω(ValidateClusterUpdate(p, old)).Should(BeEmpty())
+		})
+
+		It("should reject the addition of a ZookeeperCluster reference", func() {
+			p.Spec.Zookeeper.ClusterRef = &v1alpha1.ZookeeperClusterReference{Name: "zookeeper"}
+			Ω(fields(ValidateClusterUpdate(p, old))).Should(Equal([]string{"spec.zookeeper.clusterRef"}))
+		})
+
+		It("should reject the removal of the ZookeeperCluster reference", func() {
+			old.Spec.Zookeeper.ClusterRef = &v1alpha1.ZookeeperClusterReference{Name: "zookeeper"}
+			Ω(fields(ValidateClusterUpdate(p, old))).Should(Equal([]string{"spec.zookeeper.clusterRef"}))
+		})
+
+		It("should reject a change of the ZookeeperCluster reference", func() {
+			p.Spec.Zookeeper.ClusterRef = &v1alpha1.ZookeeperClusterReference{Name: "zookeeper"}
+			old = p.DeepCopy()
+			p.Spec.Zookeeper.ClusterRef.Name = "other"
+			Ω(fields(ValidateClusterUpdate(p, old))).Should(Equal([]string{"spec.zookeeper.clusterRef"}))
+
+			p.Spec.Zookeeper.ClusterRef = &v1alpha1.ZookeeperClusterReference{Name: "zookeeper", Namespace: "other"}
+			Ω(fields(ValidateClusterUpdate(p, old))).Should(Equal([]string{"spec.zookeeper.clusterRef"}))
+		})
+
+		It("should accept the namespace of the ZookeeperCluster reference set by default", func() {
+			p.Spec.Zookeeper.ClusterRef = &v1alpha1.ZookeeperClusterReference{Name: "zookeeper"}
+			old = p.DeepCopy()
+			p.Spec.Zookeeper.ClusterRef.Namespace = p.Namespace
+			Ω(ValidateClusterUpdate(p, old)).Should(BeEmpty())
+		})
+
+		It("should accept forced changes", func() {
+			p.Annotations = map[string]string{v1alpha1.AllowImmutableChangesAnnotation: "true"}
+			p.Spec.ZookeeperUri = "zookeeper:2181"
+			Ω(ValidateClusterUpdate(p, old)).Should(BeEmpty())
+			Ω(ValidateImmutableFields(p, old)).Should(HaveLen(1))
+		})
+	})
+})
@@ -13,10 +13,12 @@ package webhook
 import (
 	"context"
 	"fmt"
-	"log"
+	"path"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	pravegav1beta1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	controllerconfig "github.com/pravega/pravega-operator/pkg/controller/config"
 	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -28,13 +30,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/builder"
+
+	log "github.com/sirupsen/logrus"
 )
 
 const (
-	CertDir           = "/tmp"
-	WebhookConfigName = "pravega-webhook-config"
-	WebhookName       = "pravegawebhook.pravega.io"
-	WebhookSvcName    = "pravega-webhook-svc"
+	CertDir                     = "/tmp"
+	WebhookConfigName           = "pravega-webhook-config"
+	WebhookName                 = "pravegawebhook.pravega.io"
+	ValidatingWebhookConfigName = "pravega-validating-webhook-config"
+	ValidatingWebhookName       = "pravegavalidatingwebhook.pravega.io"
+	WebhookSvcName              = "pravega-webhook-svc"
 )
 
 // AddToManagerFuncs is a list of functions to add all Webhooks to the Manager
@@ -57,40 +63,117 @@ func AddToManager(m manager.Manager) error {
 
 // Create webhook server and register webhook to it
 func Add(mgr manager.Manager) error {
-	log.Printf("Initializing webhook")
+	log.Info("initializing webhook")
+
+	var err error
+	handler := &pravegaWebhookHandler{}
+	if len(controllerconfig.WatchNamespaces) > 1 {
+		// The cache of the manager only holds the objects of one namespace,
+		// the clusters of the other namespaces are read from the API server
+		handler.reader, err = client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			log.Errorf("failed to create webhook client: %v", err)
+			return err
+		}
+	}
 
-	svr, err := newWebhookServer(mgr)
+	wh, err := newMutatingWebhook(mgr, handler)
 	if err != nil {
-		log.Printf("Failed to create webhook server: %v", err)
+		log.Errorf("failed to create mutating webhook: %v", err)
 		return err
 	}
 
-	wh, err := newMutatingWebhook(mgr)
+	vwh, err := newValidatingWebhook(mgr, &validatingWebhookHandler{})
 	if err != nil {
-		log.Printf("Failed to create validating webhook: %v", err)
+		log.Errorf("failed to create validating webhook: %v", err)
 		return err
 	}
 
-	svr.Register(wh)
+	if controllerconfig.WebhookCertDir != "" {
+		svr, err := newCertServer(mgr, controllerconfig.WebhookCertDir, wh, vwh)
+		if err != nil {
+			log.Errorf("failed to create webhook server: %v", err)
+			return err
+		}
+		svr.mux.Handle(ConversionPath, &conversionHandler{})
+		if err := mgr.Add(svr); err != nil {
+			log.Errorf("failed to register webhooks: %v", err)
+			return err
+		}
+		return nil
+	}
+
+	svr, err := newWebhookServer(mgr)
+	if err != nil {
+		log.Errorf("failed to create webhook server: %v", err)
+		return err
+	}
+
+	if err := svr.Register(wh, vwh); err != nil {
+		log.Errorf("failed to register webhooks: %v", err)
+		return err
+	}
+	svr.Handle(ConversionPath, &conversionHandler{})
+
+	// The CA certificate generated by the webhook server is set on the
+	// conversion webhook of the custom resource definition
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		log.Errorf("failed to create webhook client: %v", err)
+		return err
+	}
+	err = mgr.Add(&conversionCABundleInstaller{client: c, caFile: path.Join(CertDir, "ca-cert.pem")})
+	if err != nil {
+		log.Errorf("failed to register conversion webhook: %v", err)
+		return err
+	}
 
 	err = addOwnerReferenceToWebhookK8sService(mgr)
 	if err != nil {
-		log.Printf("Failed to update webhook svc: %v", err)
+		log.Warnf("failed to update webhook svc: %v", err)
 	}
 	return nil
 }
 
-func newMutatingWebhook(mgr manager.Manager) (*admission.Webhook, error) {
+func newMutatingWebhook(mgr manager.Manager, handler *pravegaWebhookHandler) (*admission.Webhook, error) {
 	return builder.NewWebhookBuilder().
 		Name(WebhookName).
 		Mutating().
-		Operations(admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update).
-		ForType(&pravegav1alpha1.PravegaCluster{}).
-		Handlers(&pravegaWebhookHandler{}).
+		Rules(pravegaClusterRules()).
+		Handlers(handler).
+		WithManager(mgr).
+		Build()
+}
+
+func newValidatingWebhook(mgr manager.Manager, handler *validatingWebhookHandler) (*admission.Webhook, error) {
+	return builder.NewWebhookBuilder().
+		Name(ValidatingWebhookName).
+		Validating().
+		Rules(pravegaClusterRules()).
+		Handlers(handler).
 		WithManager(mgr).
 		Build()
 }
 
+// pravegaClusterRules returns the rules of the webhooks, which match the
+// creation and the update of the Pravega clusters of all the served versions
+func pravegaClusterRules() admissionregistrationv1beta1.RuleWithOperations {
+	return admissionregistrationv1beta1.RuleWithOperations{
+		Operations: []admissionregistrationv1beta1.OperationType{
+			admissionregistrationv1beta1.Create,
+			admissionregistrationv1beta1.Update,
+		},
+		Rule: admissionregistrationv1beta1.Rule{
+			APIGroups: []string{pravegav1alpha1.SchemeGroupVersion.Group},
+			APIVersions: []string{
+				pravegav1alpha1.SchemeGroupVersion.Version,
+				pravegav1beta1.SchemeGroupVersion.Version,
+			},
+			Resources: []string{"pravegaclusters"},
+		},
+	}
+}
+
 func newWebhookServer(mgr manager.Manager) (*webhook.Server, error) {
 	namespace, err := k8sutil.GetOperatorNamespace()
 	if err != nil {
@@ -99,18 +182,23 @@ func newWebhookServer(mgr manager.Manager) (*webhook.Server, error) {
 	return webhook.NewServer(WebhookSvcName, mgr, webhook.ServerOptions{
 		CertDir: CertDir,
 		BootstrapOptions: &webhook.BootstrapOptions{
-			MutatingWebhookConfigName: WebhookConfigName,
-			Service: &webhook.Service{
-				Namespace: namespace,
-				Name:      WebhookSvcName,
-				Selectors: map[string]string{
-					"component": "pravega-operator",
-				},
-			},
+			MutatingWebhookConfigName:   WebhookConfigName,
+			ValidatingWebhookConfigName: ValidatingWebhookConfigName,
+			Service:                     webhookService(namespace),
 		},
 	})
 }
 
+func webhookService(namespace string) *webhook.Service {
+	return &webhook.Service{
+		Namespace: namespace,
+		Name:      WebhookSvcName,
+		Selectors: map[string]string{
+			"component": "pravega-operator",
+		},
+	}
+}
+
 func addOwnerReferenceToWebhookK8sService(mgr manager.Manager) error {
 	// Use non-default kube client to talk to apiserver directly since the default kube client uses cache and
 	// that cache is not updated quickly enough for this method to use to get the operator deployment.
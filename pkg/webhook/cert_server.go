@@ -0,0 +1,362 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	controllerconfig "github.com/pravega/pravega-operator/pkg/controller/config"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+	webhooktypes "sigs.k8s.io/controller-runtime/pkg/webhook/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WebhookPort is the port the webhook server listens on
+const WebhookPort = 443
+
+// Reasons of the events recorded on the operator deployment
+const (
+	WebhookCertificateExpiringReason = "WebhookCertificateExpiring"
+	WebhookCertificateExpiredReason  = "WebhookCertificateExpired"
+)
+
+// expiryEventInterval is the interval at which the expiry event is recorded
+// again while the certificate is not renewed, so that it outlives the time to
+// live of the events in the API server
+const expiryEventInterval = time.Hour
+
+// certServer serves the admission webhooks with a certificate loaded from a
+// directory, e.g. a secret issued by cert-manager, instead of the certificate
+// the controller-runtime webhook server generates in CertDir. It reloads the
+// certificate when it changes, and keeps the CA bundle of the webhook
+// configurations in sync with it.
+type certServer struct {
+	// client talks to the API server directly, the webhook configurations
+	// are not in the cache of the manager
+	client   client.Client
+	certs    *certWatcher
+	service  *webhook.Service
+	webhooks []*admission.Webhook
+	mux      *http.ServeMux
+
+	// caBundle is the CA bundle set in the webhook configurations
+	caBundle []byte
+	synced   bool
+
+	// recorder records the expiry of the certificate on the operator
+	// deployment, whose name is empty if it is not known
+	recorder record.EventRecorder
+	operator string
+
+	// expiryError is the last expiry error reported, at expiryTime
+	expiryError string
+	expiryTime  time.Time
+}
+
+var _ manager.Runnable = &certServer{}
+
+func newCertServer(mgr manager.Manager, dir string, webhooks ...*admission.Webhook) (*certServer, error) {
+	namespace, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		return nil, err
+	}
+	// Use non-default kube client to talk to apiserver directly
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return nil, err
+	}
+	s, err := newCertServerWithClient(c, dir, webhookService(namespace), webhooks...)
+	if err != nil {
+		return nil, err
+	}
+	s.recorder = mgr.GetRecorder("pravega-operator")
+	if s.operator, err = k8sutil.GetOperatorName(); err != nil {
+		log.Warnf("the expiry of the webhook certificate will not be recorded on the operator deployment: %v", err)
+	}
+	return s, nil
+}
+
+func newCertServerWithClient(c client.Client, dir string, service *webhook.Service, webhooks ...*admission.Webhook) (*certServer, error) {
+	s := &certServer{
+		client:   c,
+		certs:    newCertWatcher(dir),
+		service:  service,
+		webhooks: webhooks,
+		mux:      http.NewServeMux(),
+	}
+	for _, wh := range webhooks {
+		if err := wh.Validate(); err != nil {
+			return nil, err
+		}
+		s.mux.Handle(wh.GetPath(), wh.Handler())
+	}
+	return s, nil
+}
+
+// Start installs the webhook configurations and serves the webhooks until
+// stop is closed
+func (s *certServer) Start(stop <-chan struct{}) error {
+	if _, err := s.certs.load(); err != nil {
+		return err
+	}
+	if err := s.installWebhookManifests(); err != nil {
+		return err
+	}
+	s.reportExpiry(time.Now())
+
+	srv := &http.Server{
+		Addr:      fmt.Sprintf(":%d", WebhookPort),
+		Handler:   s.mux,
+		TLSConfig: &tls.Config{GetCertificate: s.certs.GetCertificate},
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServeTLS("", "")
+	}()
+
+	ticker := time.NewTicker(controllerconfig.WebhookCertPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return srv.Shutdown(context.Background())
+		case err := <-errCh:
+			return err
+		case <-ticker.C:
+			s.syncCert()
+		}
+	}
+}
+
+// syncCert reloads the certificate if it changed, and updates the CA bundle
+// of the webhook configurations. The update is retried until it succeeds.
+func (s *certServer) syncCert() {
+	if _, err := s.certs.load(); err != nil {
+		log.Errorf("%v, still using the previous webhook certificate", err)
+	}
+	if !s.synced || !bytes.Equal(s.caBundle, s.certs.CABundle()) {
+		if err := s.installWebhookManifests(); err != nil {
+			log.Errorf("failed to update the CA bundle of the webhook configurations: %v", err)
+		}
+	}
+	s.reportExpiry(time.Now())
+}
+
+// reportExpiry logs an error and records a warning event on the operator
+// deployment when the certificate is about to expire, once for each
+// certificate and state. The event is recorded again every
+// expiryEventInterval until the certificate is renewed.
+func (s *certServer) reportExpiry(now time.Time) {
+	expired, err := s.certs.checkExpiry(now, controllerconfig.WebhookCertExpiryWarning)
+	if err == nil {
+		s.expiryError = ""
+		return
+	}
+	if err.Error() == s.expiryError && now.Sub(s.expiryTime) < expiryEventInterval {
+		return
+	}
+	if err.Error() != s.expiryError {
+		log.Error(err)
+	}
+	s.expiryError = err.Error()
+	s.expiryTime = now
+
+	reason := WebhookCertificateExpiringReason
+	if expired {
+		reason = WebhookCertificateExpiredReason
+	}
+	if err := s.recordOperatorEvent(corev1.EventTypeWarning, reason, err.Error()); err != nil {
+		log.Errorf("failed to record the expiry of the webhook certificate: %v", err)
+	}
+}
+
+// recordOperatorEvent records an event on the operator deployment, if it is
+// known
+func (s *certServer) recordOperatorEvent(eventType, reason, message string) error {
+	if s.recorder == nil || s.operator == "" {
+		return nil
+	}
+	deployment := &appsv1.Deployment{}
+	nn := types.NamespacedName{Namespace: s.service.Namespace, Name: s.operator}
+	if err := s.client.Get(context.TODO(), nn, deployment); err != nil {
+		return fmt.Errorf("failed to get operator deployment (%s): %v", s.operator, err)
+	}
+	s.recorder.Event(deployment, eventType, reason, message)
+	return nil
+}
+
+// installWebhookManifests creates the webhook service if it does not exist,
+// and creates or updates the webhook configurations and the conversion
+// webhook of the custom resource definition with the CA bundle of the
+// certificate
+func (s *certServer) installWebhookManifests() error {
+	s.synced = false
+	caBundle := s.certs.CABundle()
+
+	if err := s.ensureService(); err != nil {
+		return err
+	}
+
+	mutating := &admissionregistrationv1beta1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: WebhookConfigName},
+		Webhooks:   s.webhookConfigs(webhooktypes.WebhookTypeMutating, caBundle),
+	}
+	existingMutating := &admissionregistrationv1beta1.MutatingWebhookConfiguration{}
+	err := s.client.Get(context.TODO(), types.NamespacedName{Name: mutating.Name}, existingMutating)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get webhook configuration (%s): %v", mutating.Name, err)
+	}
+	if errors.IsNotFound(err) {
+		err = s.client.Create(context.TODO(), mutating)
+	} else {
+		existingMutating.Webhooks = mutating.Webhooks
+		err = s.client.Update(context.TODO(), existingMutating)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to install webhook configuration (%s): %v", mutating.Name, err)
+	}
+
+	validating := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ValidatingWebhookConfigName},
+		Webhooks:   s.webhookConfigs(webhooktypes.WebhookTypeValidating, caBundle),
+	}
+	existingValidating := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{}
+	err = s.client.Get(context.TODO(), types.NamespacedName{Name: validating.Name}, existingValidating)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get webhook configuration (%s): %v", validating.Name, err)
+	}
+	if errors.IsNotFound(err) {
+		err = s.client.Create(context.TODO(), validating)
+	} else {
+		existingValidating.Webhooks = validating.Webhooks
+		err = s.client.Update(context.TODO(), existingValidating)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to install webhook configuration (%s): %v", validating.Name, err)
+	}
+
+	if err = installConversionCABundle(s.client, caBundle); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(s.caBundle, caBundle) {
+		log.Info("updated the CA bundle of the webhook configurations")
+	}
+	s.caBundle = caBundle
+	s.synced = true
+	return nil
+}
+
+// webhookConfigs returns the configurations of the webhooks of a type,
+// calling the webhook service
+func (s *certServer) webhookConfigs(webhookType webhooktypes.WebhookType, caBundle []byte) []admissionregistrationv1beta1.Webhook {
+	var configs []admissionregistrationv1beta1.Webhook
+	for _, wh := range s.webhooks {
+		if wh.GetType() != webhookType {
+			continue
+		}
+		path := wh.GetPath()
+		configs = append(configs, admissionregistrationv1beta1.Webhook{
+			Name:              wh.GetName(),
+			Rules:             wh.Rules,
+			FailurePolicy:     wh.FailurePolicy,
+			NamespaceSelector: wh.NamespaceSelector,
+			ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{
+				Service: &admissionregistrationv1beta1.ServiceReference{
+					Namespace: s.service.Namespace,
+					Name:      s.service.Name,
+					Path:      &path,
+				},
+				CABundle: caBundle,
+			},
+		})
+	}
+	return configs
+}
+
+// ensureService creates the service of the webhook if it does not exist
+func (s *certServer) ensureService() error {
+	svc := &corev1.Service{}
+	nn := types.NamespacedName{Namespace: s.service.Namespace, Name: s.service.Name}
+	err := s.client.Get(context.TODO(), nn, svc)
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get webhook service: %v", err)
+	}
+
+	svc = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.service.Name,
+			Namespace: s.service.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: s.service.Selectors,
+			Ports: []corev1.ServicePort{
+				{
+					Port:       443,
+					TargetPort: intstr.FromInt(WebhookPort),
+				},
+			},
+		},
+	}
+	if err = s.client.Create(context.TODO(), svc); err != nil {
+		return fmt.Errorf("failed to create webhook service: %v", err)
+	}
+	return nil
+}
+
+// certServer implements inject.Client.
+var _ inject.Client = &certServer{}
+
+// InjectClient injects the client of the manager into the webhooks
+func (s *certServer) InjectClient(c client.Client) error {
+	for _, wh := range s.webhooks {
+		if _, err := inject.ClientInto(c, wh.Handler()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// certServer implements inject.Decoder.
+var _ inject.Decoder = &certServer{}
+
+// InjectDecoder injects the decoder into the webhooks
+func (s *certServer) InjectDecoder(d admissiontypes.Decoder) error {
+	for _, wh := range s.webhooks {
+		if _, err := inject.DecoderInto(d, wh.Handler()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pravega/pravega-operator/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	webhooktypes "sigs.k8s.io/controller-runtime/pkg/webhook/types"
+)
+
+// writeCert writes a self-signed certificate valid until notAfter to dir
+func writeCert(dir string, notAfter time.Time) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Ω(err).Should(BeNil())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "pravega-webhook-svc.default.svc"},
+		DNSNames:     []string{"pravega-webhook-svc.default.svc"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Ω(err).Should(BeNil())
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	Ω(ioutil.WriteFile(filepath.Join(dir, WebhookCertFile), certPEM, 0644)).Should(Succeed())
+	Ω(ioutil.WriteFile(filepath.Join(dir, WebhookKeyFile), keyPEM, 0600)).Should(Succeed())
+	Ω(ioutil.WriteFile(filepath.Join(dir, WebhookCAFile), certPEM, 0644)).Should(Succeed())
+	return certPEM
+}
+
+var _ = Describe("Webhook certificate", func() {
+	var (
+		dir string
+		err error
+	)
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "webhook-cert")
+		Ω(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("Certificate watcher", func() {
+		var (
+			w        *certWatcher
+			notAfter time.Time
+			caBundle []byte
+		)
+
+		BeforeEach(func() {
+			w = newCertWatcher(dir)
+			notAfter = time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second)
+			caBundle = writeCert(dir, notAfter)
+		})
+
+		It("should fail without a certificate", func() {
+			_, err = newCertWatcher(filepath.Join(dir, "missing")).load()
+			Ω(err).ShouldNot(BeNil())
+			_, err = w.GetCertificate(nil)
+			Ω(err).ShouldNot(BeNil())
+		})
+
+		It("should load the certificate", func() {
+			changed, err := w.load()
+			Ω(err).Should(BeNil())
+			Ω(changed).Should(BeTrue())
+			cert, err := w.GetCertificate(nil)
+			Ω(err).Should(BeNil())
+			Ω(cert.Leaf.NotAfter.Equal(notAfter)).Should(BeTrue())
+			Ω(w.CABundle()).Should(Equal(caBundle))
+			Ω(testutil.ToFloat64(metrics.WebhookCertificateExpiry)).Should(Equal(float64(notAfter.Unix())))
+		})
+
+		It("should only reload a changed certificate", func() {
+			w.load()
+			changed, err := w.load()
+			Ω(err).Should(BeNil())
+			Ω(changed).Should(BeFalse())
+
+			rotated := writeCert(dir, notAfter.Add(30*24*time.Hour))
+			changed, err = w.load()
+			Ω(err).Should(BeNil())
+			Ω(changed).Should(BeTrue())
+			Ω(w.CABundle()).Should(Equal(rotated))
+		})
+
+		It("should keep the previous certificate if the new one is invalid", func() {
+			w.load()
+			Ω(ioutil.WriteFile(filepath.Join(dir, WebhookKeyFile), []byte("invalid"), 0600)).Should(Succeed())
+			_, err = w.load()
+			Ω(err).ShouldNot(BeNil())
+			cert, err := w.GetCertificate(nil)
+			Ω(err).Should(BeNil())
+			Ω(cert.Leaf.NotAfter.Equal(notAfter)).Should(BeTrue())
+			Ω(w.CABundle()).Should(Equal(caBundle))
+		})
+
+		It("should accept a certificate without CA", func() {
+			Ω(os.Remove(filepath.Join(dir, WebhookCAFile))).Should(Succeed())
+			_, err = w.load()
+			Ω(err).Should(BeNil())
+			Ω(w.CABundle()).Should(BeEmpty())
+		})
+
+		It("should report the expiry of the certificate", func() {
+			w.load()
+			warning := 7 * 24 * time.Hour
+			expired, err := w.checkExpiry(notAfter.Add(-30*24*time.Hour), warning)
+			Ω(err).Should(BeNil())
+			Ω(expired).Should(BeFalse())
+			expired, err = w.checkExpiry(notAfter.Add(-24*time.Hour), warning)
+			Ω(err).Should(MatchError(HaveSuffix("renew it before then")))
+			Ω(expired).Should(BeFalse())
+			expired, err = w.checkExpiry(notAfter, warning)
+			Ω(err).Should(MatchError(ContainSubstring("expired at")))
+			Ω(expired).Should(BeTrue())
+		})
+	})
+
+	Context("Webhook configurations", func() {
+		var (
+			client   client.Client
+			svr      *certServer
+			caBundle []byte
+		)
+
+		webhook := func(name string, webhookType webhooktypes.WebhookType) *admission.Webhook {
+			return &admission.Webhook{
+				Name: name,
+				Type: webhookType,
+				Rules: []admissionregistrationv1beta1.RuleWithOperations{
+					{
+						Operations: []admissionregistrationv1beta1.OperationType{admissionregistrationv1beta1.Create},
+						Rule: admissionregistrationv1beta1.Rule{
+							APIGroups:   []string{"pravega.pravega.io"},
+							APIVersions: []string{"v1alpha1"},
+							Resources:   []string{"pravegaclusters"},
+						},
+					},
+				},
+				Handlers: []admission.Handler{&validatingWebhookHandler{}},
+			}
+		}
+
+		mutatingConfig := func() *admissionregistrationv1beta1.MutatingWebhookConfiguration {
+			config := &admissionregistrationv1beta1.MutatingWebhookConfiguration{}
+			Ω(client.Get(context.TODO(), types.NamespacedName{Name: WebhookConfigName}, config)).Should(Succeed())
+			return config
+		}
+
+		validatingConfig := func() *admissionregistrationv1beta1.ValidatingWebhookConfiguration {
+			config := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{}
+			Ω(client.Get(context.TODO(), types.NamespacedName{Name: ValidatingWebhookConfigName}, config)).Should(Succeed())
+			return config
+		}
+
+		BeforeEach(func() {
+			client = fake.NewFakeClientWithScheme(scheme.Scheme)
+			svr, err = newCertServerWithClient(client, dir, webhookService("default"),
+				webhook(WebhookName, webhooktypes.WebhookTypeMutating),
+				webhook(ValidatingWebhookName, webhooktypes.WebhookTypeValidating))
+			Ω(err).Should(BeNil())
+			caBundle = writeCert(dir, time.Now().Add(90*24*time.Hour))
+			_, err = svr.certs.load()
+			Ω(err).Should(BeNil())
+			Ω(svr.installWebhookManifests()).Should(Succeed())
+		})
+
+		It("should create the webhook service", func() {
+			svc := &corev1.Service{}
+			Ω(client.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: WebhookSvcName}, svc)).Should(Succeed())
+			Ω(svc.Spec.Selector).Should(HaveKeyWithValue("component", "pravega-operator"))
+			Ω(svc.Spec.Ports[0].Port).Should(BeEquivalentTo(443))
+		})
+
+		It("should create the webhook configurations with the CA bundle", func() {
+			webhooks := mutatingConfig().Webhooks
+			Ω(webhooks).Should(HaveLen(1))
+			Ω(webhooks[0].Name).Should(Equal(WebhookName))
+			Ω(*webhooks[0].ClientConfig.Service.Path).Should(Equal("/mutate-pravegaclusters"))
+			Ω(webhooks[0].ClientConfig.CABundle).Should(Equal(caBundle))
+
+			webhooks = validatingConfig().Webhooks
+			Ω(webhooks).Should(HaveLen(1))
+			Ω(webhooks[0].Name).Should(Equal(ValidatingWebhookName))
+			Ω(*webhooks[0].ClientConfig.Service.Path).Should(Equal("/validate-pravegaclusters"))
+			Ω(webhooks[0].ClientConfig.CABundle).Should(Equal(caBundle))
+		})
+
+		It("should update the CA bundle when the certificate is rotated", func() {
+			rotated := writeCert(dir, time.Now().Add(180*24*time.Hour))
+			svr.syncCert()
+			Ω(mutatingConfig().Webhooks[0].ClientConfig.CABundle).Should(Equal(rotated))
+			Ω(validatingConfig().Webhooks[0].ClientConfig.CABundle).Should(Equal(rotated))
+		})
+
+		It("should report the expiry once", func() {
+			writeCert(dir, time.Now().Add(24*time.Hour))
+			svr.syncCert()
+			Ω(svr.expiryError).Should(HaveSuffix("renew it before then"))
+			writeCert(dir, time.Now().Add(90*24*time.Hour))
+			svr.syncCert()
+			Ω(svr.expiryError).Should(BeEmpty())
+		})
+
+		Context("Expiry events", func() {
+			var recorder *record.FakeRecorder
+
+			BeforeEach(func() {
+				recorder = record.NewFakeRecorder(10)
+				svr.recorder = recorder
+				svr.operator = "pravega-operator"
+				deployment := &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "pravega-operator", Namespace: "default"},
+				}
+				Ω(client.Create(context.TODO(), deployment)).Should(Succeed())
+			})
+
+			It("should record an event on the operator deployment when the certificate expires soon", func() {
+				writeCert(dir, time.Now().Add(time.Hour))
+				svr.syncCert()
+				Ω(recorder.Events).Should(Receive(And(
+					HavePrefix("Warning "+WebhookCertificateExpiringReason),
+					HaveSuffix("renew it before then"))))
+
+				svr.syncCert()
+				Ω(recorder.Events).ShouldNot(Receive())
+			})
+
+			It("should record the event again while the certificate is not renewed", func() {
+				writeCert(dir, time.Now().Add(2*expiryEventInterval))
+				svr.syncCert()
+				Ω(recorder.Events).Should(Receive())
+
+				svr.reportExpiry(time.Now().Add(expiryEventInterval / 2))
+				Ω(recorder.Events).ShouldNot(Receive())
+				svr.reportExpiry(time.Now().Add(expiryEventInterval))
+				Ω(recorder.Events).Should(Receive(HavePrefix("Warning " + WebhookCertificateExpiringReason)))
+			})
+
+			It("should record an event when the certificate has expired", func() {
+				writeCert(dir, time.Now().Add(time.Minute))
+				svr.syncCert()
+				Ω(recorder.Events).Should(Receive(HavePrefix("Warning " + WebhookCertificateExpiringReason)))
+
+				svr.reportExpiry(time.Now().Add(2 * time.Minute))
+				Ω(recorder.Events).Should(Receive(And(
+					HavePrefix("Warning "+WebhookCertificateExpiredReason),
+					ContainSubstring("the API server cannot reach the webhook"))))
+			})
+
+			It("should not record events for a valid certificate", func() {
+				svr.syncCert()
+				Ω(recorder.Events).ShouldNot(Receive())
+			})
+		})
+	})
+})
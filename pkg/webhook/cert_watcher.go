@@ -0,0 +1,136 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pravega/pravega-operator/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Files of the webhook certificate, as in the secrets of type
+	// kubernetes.io/tls issued by cert-manager. The CA certificate is
+	// optional, the CA bundle of the webhook configurations is left empty
+	// without it.
+	WebhookCertFile = "tls.crt"
+	WebhookKeyFile  = "tls.key"
+	WebhookCAFile   = "ca.crt"
+)
+
+// certWatcher holds the certificate of the webhook loaded from a directory,
+// e.g. a mounted secret, so that the certificate can be rotated without
+// restarting the operator
+type certWatcher struct {
+	dir string
+
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	certPEM  []byte
+	keyPEM   []byte
+	caBundle []byte
+}
+
+func newCertWatcher(dir string) *certWatcher {
+	return &certWatcher{dir: dir}
+}
+
+// load loads the certificate from the directory, unless it has not changed
+// since it was last loaded. The certificate in use is left unchanged if the
+// files cannot be loaded, e.g. while the secret is being updated.
+func (w *certWatcher) load() (changed bool, err error) {
+	certPEM, err := ioutil.ReadFile(filepath.Join(w.dir, WebhookCertFile))
+	if err != nil {
+		return false, fmt.Errorf("failed to read webhook certificate (%s): %v", w.dir, err)
+	}
+	keyPEM, err := ioutil.ReadFile(filepath.Join(w.dir, WebhookKeyFile))
+	if err != nil {
+		return false, fmt.Errorf("failed to read webhook certificate (%s): %v", w.dir, err)
+	}
+	caBundle, err := ioutil.ReadFile(filepath.Join(w.dir, WebhookCAFile))
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read webhook certificate (%s): %v", w.dir, err)
+	}
+
+	w.mutex.RLock()
+	unchanged := bytes.Equal(certPEM, w.certPEM) && bytes.Equal(keyPEM, w.keyPEM) && bytes.Equal(caBundle, w.caBundle)
+	w.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse webhook certificate (%s): %v", w.dir, err)
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false, fmt.Errorf("failed to parse webhook certificate (%s): %v", w.dir, err)
+	}
+
+	w.mutex.Lock()
+	w.cert = &cert
+	w.certPEM = certPEM
+	w.keyPEM = keyPEM
+	w.caBundle = caBundle
+	w.mutex.Unlock()
+	metrics.WebhookCertificateExpiry.Set(float64(cert.Leaf.NotAfter.Unix()))
+	log.Infof("loaded webhook certificate from %s, valid until %s", w.dir, cert.Leaf.NotAfter.Format(time.RFC3339))
+	return true, nil
+}
+
+// GetCertificate returns the certificate in use, for the TLS configuration of
+// the webhook server
+func (w *certWatcher) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	if w.cert == nil {
+		return nil, fmt.Errorf("webhook certificate not loaded (%s)", w.dir)
+	}
+	return w.cert, nil
+}
+
+// CABundle returns the CA certificate of the certificate in use
+func (w *certWatcher) CABundle() []byte {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.caBundle
+}
+
+// checkExpiry returns an error if the certificate in use has expired, or
+// expires within the warning period, and whether it has expired
+func (w *certWatcher) checkExpiry(now time.Time, warning time.Duration) (expired bool, err error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	if w.cert == nil {
+		return false, nil
+	}
+
+	notAfter := w.cert.Leaf.NotAfter
+	if !now.Before(notAfter) {
+		return true, fmt.Errorf("webhook certificate from %s expired at %s, the API server cannot reach the webhook until it is renewed",
+			w.dir, notAfter.Format(time.RFC3339))
+	}
+	if now.Add(warning).After(notAfter) {
+		return false, fmt.Errorf("webhook certificate from %s expires at %s, renew it before then",
+			w.dir, notAfter.Format(time.RFC3339))
+	}
+	return false, nil
+}
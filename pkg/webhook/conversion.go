@@ -0,0 +1,281 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	pravegav1beta1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+	controllerconfig "github.com/pravega/pravega-operator/pkg/controller/config"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ConversionPath is the path of the conversion webhook on the webhook
+	// server
+	ConversionPath = "/convert"
+
+	// CRDName is the name of the PravegaCluster custom resource definition,
+	// whose conversion webhook is configured with the CA bundle of the webhook
+	// certificate
+	CRDName = "pravegaclusters.pravega.pravega.io"
+)
+
+// The ConversionReview types of the apiextensions.k8s.io/v1beta1 API, which
+// are not available in the Kubernetes libraries used by the operator
+
+type conversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *conversionRequest  `json:"request,omitempty"`
+	Response        *conversionResponse `json:"response,omitempty"`
+}
+
+type conversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type conversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// conversionHandler converts Pravega clusters between v1alpha1 and v1beta1
+// for the API server
+type conversionHandler struct{}
+
+var _ http.Handler = &conversionHandler{}
+
+func (h *conversionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &conversionReview{}
+	body, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, review)
+	}
+	if err == nil && review.Request == nil {
+		err = fmt.Errorf("conversion review has no request")
+	}
+	if err != nil {
+		log.Errorf("failed to read conversion review: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger := log.WithFields(log.Fields{
+		"requestID":     review.Request.UID,
+		"targetVersion": review.Request.DesiredAPIVersion,
+	})
+	logger.Debugf("converting %d objects", len(review.Request.Objects))
+	review.Response = convert(review.Request)
+	if review.Response.Result.Status == metav1.StatusFailure {
+		logger.Errorf("failed to convert objects: %s", review.Response.Result.Message)
+	}
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		logger.Errorf("failed to write conversion review: %v", err)
+	}
+}
+
+// convert converts the objects of a conversion request to the desired
+// version. The request fails if any of the objects cannot be converted.
+func convert(req *conversionRequest) *conversionResponse {
+	res := &conversionResponse{UID: req.UID}
+	for _, obj := range req.Objects {
+		converted, err := convertCluster(obj.Raw, req.DesiredAPIVersion)
+		if err != nil {
+			res.Result = metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			return res
+		}
+		res.ConvertedObjects = append(res.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+	res.Result = metav1.Status{Status: metav1.StatusSuccess}
+	return res
+}
+
+// convertCluster converts a serialized Pravega cluster to an API version
+func convertCluster(raw []byte, apiVersion string) ([]byte, error) {
+	typeMeta := &metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, typeMeta); err != nil {
+		return nil, fmt.Errorf("failed to decode object: %v", err)
+	}
+	if typeMeta.Kind != "PravegaCluster" {
+		return nil, fmt.Errorf("unsupported kind %s", typeMeta.Kind)
+	}
+	if typeMeta.APIVersion == apiVersion {
+		return raw, nil
+	}
+
+	alphaVersion := pravegav1alpha1.SchemeGroupVersion.String()
+	betaVersion := pravegav1beta1.SchemeGroupVersion.String()
+	var converted interface{}
+	switch {
+	case typeMeta.APIVersion == alphaVersion && apiVersion == betaVersion:
+		src := &pravegav1alpha1.PravegaCluster{}
+		if err := json.Unmarshal(raw, src); err != nil {
+			return nil, fmt.Errorf("failed to decode object: %v", err)
+		}
+		dst := &pravegav1beta1.PravegaCluster{}
+		if err := src.ConvertTo(dst); err != nil {
+			return nil, fmt.Errorf("failed to convert cluster (%s) to %s: %v", src.Name, apiVersion, err)
+		}
+		converted = dst
+	case typeMeta.APIVersion == betaVersion && apiVersion == alphaVersion:
+		src := &pravegav1beta1.PravegaCluster{}
+		if err := json.Unmarshal(raw, src); err != nil {
+			return nil, fmt.Errorf("failed to decode object: %v", err)
+		}
+		dst := &pravegav1alpha1.PravegaCluster{}
+		if err := dst.ConvertFrom(src); err != nil {
+			return nil, fmt.Errorf("failed to convert cluster (%s) to %s: %v", src.Name, apiVersion, err)
+		}
+		converted = dst
+	default:
+		return nil, fmt.Errorf("unsupported conversion from %s to %s", typeMeta.APIVersion, apiVersion)
+	}
+	return json.Marshal(converted)
+}
+
+// isV1beta1Request returns whether an admission request holds a v1beta1
+// Pravega cluster
+func isV1beta1Request(req admissiontypes.Request) bool {
+	return req.AdmissionRequest.Kind.Version == pravegav1beta1.SchemeGroupVersion.Version
+}
+
+// decodeCluster decodes the Pravega cluster of an admission request into its
+// v1alpha1 representation, which is used by the checks of the webhooks. The
+// object of the request, in the version of the request, is also returned.
+func decodeCluster(d admissiontypes.Decoder, req admissiontypes.Request) (*pravegav1alpha1.PravegaCluster, runtime.Object, error) {
+	p := &pravegav1alpha1.PravegaCluster{}
+	if !isV1beta1Request(req) {
+		if err := d.Decode(req, p); err != nil {
+			return nil, nil, err
+		}
+		return p, p, nil
+	}
+
+	obj := &pravegav1beta1.PravegaCluster{}
+	if err := d.Decode(req, obj); err != nil {
+		return nil, nil, err
+	}
+	if err := p.ConvertFrom(obj); err != nil {
+		return nil, nil, err
+	}
+	return p, obj, nil
+}
+
+// convertToRequestVersion converts a v1alpha1 Pravega cluster to the version
+// of the object of an admission request
+func convertToRequestVersion(p *pravegav1alpha1.PravegaCluster, obj runtime.Object) (runtime.Object, error) {
+	if _, ok := obj.(*pravegav1beta1.PravegaCluster); !ok {
+		return p, nil
+	}
+	dst := &pravegav1beta1.PravegaCluster{}
+	if err := p.ConvertTo(dst); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// installConversionCABundle sets the CA bundle of the conversion webhook of
+// the PravegaCluster custom resource definition, unless the definition does
+// not use the conversion webhook. The definition is updated as an
+// unstructured object, as the conversion settings are not part of the
+// Kubernetes libraries used by the operator.
+func installConversionCABundle(c client.Client, caBundle []byte) error {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "apiextensions.k8s.io",
+		Version: "v1beta1",
+		Kind:    "CustomResourceDefinition",
+	})
+	err := c.Get(context.TODO(), types.NamespacedName{Name: CRDName}, crd)
+	if errors.IsNotFound(err) {
+		// There is nothing to configure until the definition is installed
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get custom resource definition (%s): %v", CRDName, err)
+	}
+
+	strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+	if strategy != "Webhook" {
+		return nil
+	}
+	encoded := base64.StdEncoding.EncodeToString(caBundle)
+	current, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhookClientConfig", "caBundle")
+	if current == encoded {
+		return nil
+	}
+	err = unstructured.SetNestedField(crd.Object, encoded, "spec", "conversion", "webhookClientConfig", "caBundle")
+	if err != nil {
+		return fmt.Errorf("failed to set CA bundle of custom resource definition (%s): %v", CRDName, err)
+	}
+	if err = c.Update(context.TODO(), crd); err != nil {
+		return fmt.Errorf("failed to update custom resource definition (%s): %v", CRDName, err)
+	}
+	log.Infof("updated the CA bundle of the conversion webhook of %s", CRDName)
+	return nil
+}
+
+// conversionCABundleInstaller sets the CA bundle of the conversion webhook
+// when the certificate is generated by the webhook server, which writes the
+// CA certificate in a directory
+type conversionCABundleInstaller struct {
+	client client.Client
+	caFile string
+}
+
+var _ manager.Runnable = &conversionCABundleInstaller{}
+
+// Start sets the CA bundle whenever the CA certificate changes, until stop is
+// closed
+func (i *conversionCABundleInstaller) Start(stop <-chan struct{}) error {
+	var installed []byte
+	ticker := time.NewTicker(controllerconfig.WebhookCertPollInterval)
+	defer ticker.Stop()
+	for {
+		caBundle, err := ioutil.ReadFile(i.caFile)
+		if err == nil && len(caBundle) > 0 && !bytes.Equal(caBundle, installed) {
+			if err := installConversionCABundle(i.client, caBundle); err != nil {
+				log.Errorf("failed to update the CA bundle of the conversion webhook: %v", err)
+			} else {
+				installed = caBundle
+			}
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
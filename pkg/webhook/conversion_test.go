@@ -0,0 +1,260 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1beta1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+var _ = Describe("Conversion webhook", func() {
+	const (
+		Name      = "example"
+		Namespace = "default"
+	)
+
+	var (
+		s = scheme.Scheme
+		p *v1alpha1.PravegaCluster
+	)
+
+	toRaw := func(obj interface{}) runtime.RawExtension {
+		data, err := json.Marshal(obj)
+		Ω(err).Should(BeNil())
+		return runtime.RawExtension{Raw: data}
+	}
+
+	toBeta := func(p *v1alpha1.PravegaCluster) *v1beta1.PravegaCluster {
+		b := &v1beta1.PravegaCluster{}
+		Ω(p.ConvertTo(b)).Should(Succeed())
+		return b
+	}
+
+	BeforeEach(func() {
+		s.AddKnownTypes(v1alpha1.SchemeGroupVersion, &v1alpha1.PravegaCluster{})
+		s.AddKnownTypes(v1beta1.SchemeGroupVersion, &v1beta1.PravegaCluster{})
+		p = &v1alpha1.PravegaCluster{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.SchemeGroupVersion.String(),
+				Kind:       "PravegaCluster",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name,
+				Namespace: Namespace,
+			},
+			Spec: v1alpha1.ClusterSpec{
+				Version:        "0.5.0",
+				ExternalAccess: &v1alpha1.ExternalAccess{Enabled: true, Type: corev1.ServiceTypeNodePort},
+				Pravega: &v1alpha1.PravegaSpec{
+					Options: map[string]string{"pravegaservice.containerCount": "8"},
+				},
+			},
+		}
+	})
+
+	Context("Conversion review", func() {
+		serve := func(body []byte) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, ConversionPath, bytes.NewReader(body))
+			(&conversionHandler{}).ServeHTTP(rec, req)
+			return rec
+		}
+
+		review := func(apiVersion string, objects ...interface{}) *conversionResponse {
+			req := &conversionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1beta1", Kind: "ConversionReview"},
+				Request:  &conversionRequest{UID: "uid", DesiredAPIVersion: apiVersion},
+			}
+			for _, obj := range objects {
+				req.Request.Objects = append(req.Request.Objects, toRaw(obj))
+			}
+			body, err := json.Marshal(req)
+			Ω(err).Should(BeNil())
+
+			rec := serve(body)
+			Ω(rec.Code).Should(Equal(http.StatusOK))
+			res := &conversionReview{}
+			Ω(json.Unmarshal(rec.Body.Bytes(), res)).Should(Succeed())
+			Ω(res.Kind).Should(Equal("ConversionReview"))
+			Ω(res.Response).ShouldNot(BeNil())
+			Ω(res.Response.UID).Should(BeEquivalentTo("uid"))
+			return res.Response
+		}
+
+		It("should convert v1alpha1 clusters to v1beta1", func() {
+			res := review(v1beta1.SchemeGroupVersion.String(), p)
+			Ω(res.Result.Status).Should(Equal(metav1.StatusSuccess))
+			Ω(res.ConvertedObjects).Should(HaveLen(1))
+			Ω(string(res.ConvertedObjects[0].Raw)).Should(MatchJSON(toRaw(toBeta(p)).Raw))
+		})
+
+		It("should convert v1beta1 clusters to v1alpha1", func() {
+			b := toBeta(p)
+			b.Spec.Pravega.ControllerOptions = map[string]string{"controller.retention": "10"}
+			res := review(v1alpha1.SchemeGroupVersion.String(), b)
+			Ω(res.Result.Status).Should(Equal(metav1.StatusSuccess))
+
+			alpha := &v1alpha1.PravegaCluster{}
+			Ω(json.Unmarshal(res.ConvertedObjects[0].Raw, alpha)).Should(Succeed())
+			Ω(alpha.APIVersion).Should(Equal(v1alpha1.SchemeGroupVersion.String()))
+			Ω(alpha.ControllerOptions()).Should(Equal(b.Spec.Pravega.ControllerOptions))
+			Ω(string(toRaw(toBeta(alpha)).Raw)).Should(MatchJSON(toRaw(b).Raw))
+		})
+
+		It("should keep the clusters in the desired version", func() {
+			res := review(v1alpha1.SchemeGroupVersion.String(), p)
+			Ω(res.Result.Status).Should(Equal(metav1.StatusSuccess))
+			Ω(string(res.ConvertedObjects[0].Raw)).Should(MatchJSON(toRaw(p).Raw))
+		})
+
+		It("should fail on unsupported versions", func() {
+			res := review("pravega.pravega.io/v2", p)
+			Ω(res.Result.Status).Should(Equal(metav1.StatusFailure))
+			Ω(res.Result.Message).Should(ContainSubstring("unsupported conversion"))
+			Ω(res.ConvertedObjects).Should(BeEmpty())
+		})
+
+		It("should reject invalid reviews", func() {
+			Ω(serve([]byte("{")).Code).Should(Equal(http.StatusBadRequest))
+			Ω(serve([]byte("{}")).Code).Should(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("Admission of v1beta1 clusters", func() {
+		var decoder admissiontypes.Decoder
+
+		request := func(operation admissionv1beta1.Operation, obj, old *v1beta1.PravegaCluster) admissiontypes.Request {
+			req := &admissionv1beta1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Group: "pravega.pravega.io", Version: "v1beta1", Kind: "PravegaCluster"},
+				Name:      Name,
+				Namespace: Namespace,
+				Operation: operation,
+				Object:    toRaw(obj),
+			}
+			if old != nil {
+				req.OldObject = toRaw(old)
+			}
+			return admissiontypes.Request{AdmissionRequest: req}
+		}
+
+		BeforeEach(func() {
+			var err error
+			decoder, err = admission.NewDecoder(s)
+			Ω(err).Should(BeNil())
+		})
+
+		It("should not use the image tag as version", func() {
+			b := toBeta(p)
+			b.Spec.Version = ""
+			b.Spec.Pravega.Image = &v1beta1.ImageSpec{Tag: "0.5.0-patched"}
+			pwh := &pravegaWebhookHandler{client: fake.NewFakeClient(), decoder: decoder}
+			res := pwh.Handle(context.TODO(), request(admissionv1beta1.Create, b, nil))
+			Ω(res.Response.Allowed).Should(BeTrue())
+			versions := map[string]interface{}{}
+			for _, patch := range res.Patches {
+				if patch.Path == "/spec/version" || patch.Path == "/spec/pravega/image/tag" {
+					versions[patch.Path] = patch.Value
+				}
+			}
+			Ω(versions).Should(Equal(map[string]interface{}{"/spec/version": v1alpha1.DefaultPravegaVersion}))
+		})
+
+		It("should not patch clusters with defaults", func() {
+			p.WithDefaults()
+			b := toBeta(p)
+			pwh := &pravegaWebhookHandler{client: fake.NewFakeClient(), decoder: decoder}
+			res := pwh.Handle(context.TODO(), request(admissionv1beta1.Create, b, nil))
+			Ω(res.Response.Allowed).Should(BeTrue())
+			Ω(res.Patches).Should(BeEmpty())
+		})
+
+		It("should validate the v1beta1 fields", func() {
+			b := toBeta(p)
+			b.Spec.Pravega.ControllerExternalAccess = &v1beta1.ExternalAccess{Enabled: true, Type: corev1.ServiceTypeClusterIP}
+			vwh := &validatingWebhookHandler{decoder: decoder}
+			res := vwh.Handle(context.TODO(), request(admissionv1beta1.Create, b, nil))
+			Ω(res.Response.Allowed).Should(BeFalse())
+			Ω(res.Response.Result.Message).Should(ContainSubstring("spec.pravega.controllerExternalAccess.type: Unsupported value"))
+		})
+
+		It("should deny the update of an immutable field", func() {
+			old := toBeta(p)
+			b := old.DeepCopy()
+			b.Spec.ZookeeperUri = "zookeeper:2181"
+			vwh := &validatingWebhookHandler{decoder: decoder}
+			res := vwh.Handle(context.TODO(), request(admissionv1beta1.Update, b, old))
+			Ω(res.Response.Allowed).Should(BeFalse())
+			Ω(res.Response.Result.Message).Should(ContainSubstring("spec.zookeeperUri: Forbidden: field is immutable"))
+		})
+	})
+
+	Context("CA bundle", func() {
+		var c client.Client
+
+		crd := func(strategy string) *unstructured.Unstructured {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "apiextensions.k8s.io/v1beta1",
+				"kind":       "CustomResourceDefinition",
+				"metadata":   map[string]interface{}{"name": CRDName},
+				"spec": map[string]interface{}{
+					"conversion": map[string]interface{}{"strategy": strategy},
+				},
+			}}
+			return obj
+		}
+
+		caBundle := func() string {
+			obj := crd("")
+			Ω(c.Get(context.TODO(), types.NamespacedName{Name: CRDName}, obj)).Should(Succeed())
+			value, _, err := unstructured.NestedString(obj.Object, "spec", "conversion", "webhookClientConfig", "caBundle")
+			Ω(err).Should(BeNil())
+			return value
+		}
+
+		It("should set the CA bundle of the conversion webhook", func() {
+			c = fake.NewFakeClientWithScheme(s, crd("Webhook"))
+			Ω(installConversionCABundle(c, []byte("ca"))).Should(Succeed())
+			Ω(caBundle()).Should(Equal(base64.StdEncoding.EncodeToString([]byte("ca"))))
+		})
+
+		It("should not configure definitions without conversion webhook", func() {
+			c = fake.NewFakeClientWithScheme(s, crd("None"))
+			Ω(installConversionCABundle(c, []byte("ca"))).Should(Succeed())
+			Ω(caBundle()).Should(BeEmpty())
+		})
+
+		It("should ignore a missing definition", func() {
+			c = fake.NewFakeClientWithScheme(s)
+			Ω(installConversionCABundle(c, []byte("ca"))).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,95 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// annotateDefaultedFields lists the fields of the spec set by the mutating
+// webhook in the DefaultedFieldsAnnotation of the mutated object. The paths
+// are those of the version of the request. The annotation is recomputed on
+// every admission from the fields left empty in the original object, so that
+// the fields set explicitly since a previous admission are no longer listed.
+// It is removed if no field was defaulted.
+func annotateDefaultedFields(original, mutated runtime.Object) error {
+	before, err := specOf(original)
+	if err != nil {
+		return err
+	}
+	after, err := specOf(mutated)
+	if err != nil {
+		return err
+	}
+
+	accessor, err := meta.Accessor(mutated)
+	if err != nil {
+		return err
+	}
+	annotations := accessor.GetAnnotations()
+	fields := addedFields("spec", before, after)
+	if len(fields) == 0 {
+		if _, ok := annotations[pravegav1alpha1.DefaultedFieldsAnnotation]; ok {
+			delete(annotations, pravegav1alpha1.DefaultedFieldsAnnotation)
+			accessor.SetAnnotations(annotations)
+		}
+		return nil
+	}
+
+	sort.Strings(fields)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[pravegav1alpha1.DefaultedFieldsAnnotation] = strings.Join(fields, ",")
+	accessor.SetAnnotations(annotations)
+	return nil
+}
+
+// specOf returns the spec of a Pravega cluster as generic JSON values
+func specOf(obj runtime.Object) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cluster: %v", err)
+	}
+	cluster := map[string]interface{}{}
+	if err = json.Unmarshal(data, &cluster); err != nil {
+		return nil, fmt.Errorf("failed to decode cluster: %v", err)
+	}
+	return cluster["spec"], nil
+}
+
+// addedFields returns the paths of the fields set in after that are not set,
+// or set to another value, in before. A field added with its subfields is
+// returned as a single path.
+func addedFields(path string, before, after interface{}) []string {
+	afterFields, ok := after.(map[string]interface{})
+	beforeFields, _ := before.(map[string]interface{})
+	if !ok || beforeFields == nil {
+		if reflect.DeepEqual(before, after) {
+			return nil
+		}
+		return []string{path}
+	}
+
+	var fields []string
+	for name, value := range afterFields {
+		fields = append(fields, addedFields(path+"."+name, beforeFields[name], value)...)
+	}
+	return fields
+}
@@ -0,0 +1,123 @@
+/**
+ * Copyright (c) 2019 Dell Inc., or its subsidiaries. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ */
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+
+	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/validation"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// validatingWebhookHandler rejects the Pravega clusters whose spec is not
+// valid, and the updates that change immutable fields. It runs after the
+// mutating webhook, so the versions are already checked. Only the denials
+// are counted in the admission metrics, the allowed requests are counted by
+// the mutating webhook.
+type validatingWebhookHandler struct {
+	decoder admissiontypes.Decoder
+}
+
+var _ admission.Handler = &validatingWebhookHandler{}
+
+// Webhook server will call this func when request comes in
+func (vwh *validatingWebhookHandler) Handle(ctx context.Context, req admissiontypes.Request) admissiontypes.Response {
+	logger := log.WithFields(log.Fields{
+		"namespace": req.AdmissionRequest.Namespace,
+		"cluster":   req.AdmissionRequest.Name,
+		"operation": req.AdmissionRequest.Operation,
+		"requestID": req.AdmissionRequest.UID,
+	})
+	logger.Debug("validating webhook is handling incoming request")
+	pravega, _, err := decodeCluster(vwh.decoder, req)
+	if err != nil {
+		return deny(logger, http.StatusBadRequest, &admissionDenial{reason: "InvalidObject", err: err})
+	}
+
+	if pravega.DeletionTimestamp != nil {
+		// The finalizer of a cluster being deleted must be removable, even if
+		// its spec does not pass the checks added since its creation
+		logger.Debug("allowing the update of a cluster being deleted")
+		return admission.ValidationResponse(true, "")
+	}
+
+	if req.AdmissionRequest.Operation == admissionv1beta1.Update {
+		old, err := vwh.decodeOldObject(req)
+		if err != nil {
+			return deny(logger, http.StatusBadRequest, &admissionDenial{reason: "InvalidObject", err: err})
+		}
+		if err := validateClusterUpdate(logger, pravega, old); err != nil {
+			return deny(logger, http.StatusUnprocessableEntity, err)
+		}
+	} else if err := validateCluster(pravega); err != nil {
+		return deny(logger, http.StatusUnprocessableEntity, err)
+	}
+
+	logger.Debug("admission request validated")
+	return admission.ValidationResponse(true, "")
+}
+
+// validateCluster validates the spec of a Pravega cluster
+func validateCluster(p *pravegav1alpha1.PravegaCluster) error {
+	if errs := validation.ValidateCluster(p); len(errs) > 0 {
+		return &admissionDenial{reason: "InvalidSpec", err: validation.InvalidError(p, errs)}
+	}
+	return nil
+}
+
+// validateClusterUpdate validates the update of a Pravega cluster. The spec
+// is only validated if it changes, so that the metadata of the clusters
+// created before a check was added can still be updated.
+func validateClusterUpdate(logger *log.Entry, p, old *pravegav1alpha1.PravegaCluster) error {
+	var errs field.ErrorList
+	if !equality.Semantic.DeepEqual(p.Spec, old.Spec) {
+		errs = validation.ValidateClusterUpdate(p, old)
+	} else if !p.AllowsImmutableChanges() {
+		errs = validation.ValidateImmutableFields(p, old)
+	}
+	if len(errs) > 0 {
+		return &admissionDenial{reason: "InvalidSpec", err: validation.InvalidError(p, errs)}
+	}
+	if p.AllowsImmutableChanges() {
+		if errs := validation.ValidateImmutableFields(p, old); len(errs) > 0 {
+			logger.Warnf("allowing changes to immutable fields, the cluster has the annotation %s: %v",
+				pravegav1alpha1.AllowImmutableChangesAnnotation, errs.ToAggregate())
+		}
+	}
+	return nil
+}
+
+// decodeOldObject decodes the object stored before an update
+func (vwh *validatingWebhookHandler) decodeOldObject(req admissiontypes.Request) (*pravegav1alpha1.PravegaCluster, error) {
+	oldReq := *req.AdmissionRequest
+	oldReq.Object = oldReq.OldObject
+	old, _, err := decodeCluster(vwh.decoder, admissiontypes.Request{AdmissionRequest: &oldReq})
+	return old, err
+}
+
+// validatingWebhookHandler implements inject.Decoder.
+var _ inject.Decoder = &validatingWebhookHandler{}
+
+// InjectDecoder injects the decoder into the validatingWebhookHandler
+func (vwh *validatingWebhookHandler) InjectDecoder(d admissiontypes.Decoder) error {
+	vwh.decoder = d
+	return nil
+}
@@ -14,10 +14,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 
 	pravegav1alpha1 "github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/metrics"
 	"github.com/pravega/pravega-operator/pkg/util"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -31,47 +34,96 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	// The key is the supported versions, the value is a list of versions that can upgrade to.
-	supportedVersions = map[string][]string{
-		"0.1.0": []string{"0.1.0"},
-		"0.2.0": []string{"0.2.0"},
-		"0.3.0": []string{"0.3.0", "0.3.1", "0.3.2"},
-		"0.3.1": []string{"0.3.1", "0.3.2"},
-		"0.3.2": []string{"0.3.2"},
-		"0.4.0": []string{"0.4.0"},
-		"0.5.0": []string{"0.5.0", "0.6.0"},
-		"0.6.0": []string{"0.6.0"},
-	}
-)
-
 type pravegaWebhookHandler struct {
 	client  client.Client
 	scheme  *runtime.Scheme
 	decoder admissiontypes.Decoder
+
+	// reader reads the Pravega clusters instead of the client if set, e.g.
+	// when the cache of the client does not hold all the watched namespaces
+	reader client.Reader
 }
 
 var _ admission.Handler = &pravegaWebhookHandler{}
 
 // Webhook server will call this func when request comes in
 func (pwh *pravegaWebhookHandler) Handle(ctx context.Context, req admissiontypes.Request) admissiontypes.Response {
-	log.Printf("Webhook is handling incoming requests")
-	pravega := &pravegav1alpha1.PravegaCluster{}
-
-	if err := pwh.decoder.Decode(req, pravega); err != nil {
-		return admission.ErrorResponse(http.StatusBadRequest, err)
+	logger := log.WithFields(log.Fields{
+		"namespace": req.AdmissionRequest.Namespace,
+		"cluster":   req.AdmissionRequest.Name,
+		"operation": req.AdmissionRequest.Operation,
+		"requestID": req.AdmissionRequest.UID,
+	})
+	logger.Debug("webhook is handling incoming request")
+	pravega, obj, err := decodeCluster(pwh.decoder, req)
+	if err != nil {
+		return deny(logger, http.StatusBadRequest, &admissionDenial{reason: "InvalidObject", err: err})
+	}
+	if pravega.DeletionTimestamp != nil {
+		// The finalizer of a cluster being deleted must be removable, even if
+		// its version is no longer supported or the cluster is upgrading
+		logger.Debug("allowing the update of a cluster being deleted")
+		metrics.WebhookAdmissions.WithLabelValues(metrics.AdmissionAllowed, "").Inc()
+		return admission.ValidationResponse(true, "")
 	}
 	copy := pravega.DeepCopy()
+	if copy.Spec.Version == "" && isV1beta1Request(req) {
+		// Unlike in v1alpha1, the image tag is never used as cluster version
+		copy.Spec.Version = pravegav1alpha1.DefaultPravegaVersion
+	}
+	if pravega.Spec.Version != "" {
+		logger = logger.WithField("targetVersion", pravega.Spec.Version)
+	}
 
 	if err := pwh.clusterIsAvailable(ctx, copy); err != nil {
-		return admission.ErrorResponse(http.StatusServiceUnavailable, err)
+		return deny(logger, http.StatusServiceUnavailable, err)
 	}
 
 	if err := pwh.mutatePravegaManifest(ctx, copy); err != nil {
-		return admission.ErrorResponse(http.StatusBadRequest, err)
+		return deny(logger, http.StatusBadRequest, err)
+	}
+
+	// The defaults are stored on admission, so that the reconciler does not
+	// update the spec of the clusters
+	copy.WithDefaults()
+
+	mutated, err := convertToRequestVersion(copy, obj)
+	if err != nil {
+		return deny(logger, http.StatusInternalServerError, err)
+	}
+	if err := annotateDefaultedFields(obj, mutated); err != nil {
+		return deny(logger, http.StatusInternalServerError, err)
 	}
 
-	return admission.PatchResponse(pravega, copy)
+	logger.Debug("admission request allowed")
+	metrics.WebhookAdmissions.WithLabelValues(metrics.AdmissionAllowed, "").Inc()
+	return admission.PatchResponse(obj, mutated)
+}
+
+// admissionDenial is an error that rejects an admission request for a known
+// reason. The reason is used to label the admission metrics.
+type admissionDenial struct {
+	reason string
+	err    error
+}
+
+func (d *admissionDenial) Error() string {
+	return d.err.Error()
+}
+
+func denial(reason string, format string, args ...interface{}) error {
+	return &admissionDenial{reason: reason, err: fmt.Errorf(format, args...)}
+}
+
+// deny records the denial of an admission request and returns the response
+func deny(logger *log.Entry, code int32, err error) admissiontypes.Response {
+	reason := "InternalError"
+	if d, ok := err.(*admissionDenial); ok {
+		reason = d.reason
+	}
+	logger.WithField("reason", reason).Infof("denying admission request: %v", err)
+	metrics.WebhookAdmissions.WithLabelValues(metrics.AdmissionDenied, reason).Inc()
+	return admission.ErrorResponse(code, err)
 }
 
 func (pwh *pravegaWebhookHandler) mutatePravegaManifest(ctx context.Context, p *pravegav1alpha1.PravegaCluster) error {
@@ -88,27 +140,43 @@ func (pwh *pravegaWebhookHandler) mutatePravegaVersion(ctx context.Context, p *p
 	// Mutate the version if it is empty
 	if p.Spec.Version == "" {
 		if p.Spec.Pravega != nil && p.Spec.Pravega.Image != nil && p.Spec.Pravega.Image.Tag != "" {
+			// The tag of the Pravega image used to set the cluster version
 			p.Spec.Version = p.Spec.Pravega.Image.Tag
+			p.Spec.Pravega.Image.Tag = ""
 		} else {
 			p.Spec.Version = pravegav1alpha1.DefaultPravegaVersion
 		}
 	}
-	// Set Pravega and Bookkeeper tag to empty
-	if p.Spec.Pravega != nil && p.Spec.Pravega.Image != nil && p.Spec.Pravega.Image.Tag != "" {
-		p.Spec.Pravega.Image.Tag = ""
+
+	if p.Spec.Pravega != nil && p.Spec.Pravega.Image != nil {
+		if err := validateImage("Pravega", &p.Spec.Pravega.Image.ImageSpec); err != nil {
+			return err
+		}
 	}
-	if p.Spec.Bookkeeper != nil && p.Spec.Bookkeeper.Image != nil && p.Spec.Bookkeeper.Image.Tag != "" {
-		p.Spec.Bookkeeper.Image.Tag = ""
+	if p.Spec.Bookkeeper != nil && p.Spec.Bookkeeper.Image != nil {
+		if err := validateImage("bookkeeper", &p.Spec.Bookkeeper.Image.ImageSpec); err != nil {
+			return err
+		}
 	}
 
 	requestVersion := p.Spec.Version
 	// Check if the request has a valid Pravega version
 	normRequestVersion, err := util.NormalizeVersion(requestVersion)
 	if err != nil {
-		return fmt.Errorf("request version is not in valid format: %v", err)
+		return denial("InvalidVersion", "request version is not in valid format: %v", err)
 	}
+	supportedVersions, source := util.SupportedVersions()
 	if _, ok := supportedVersions[normRequestVersion]; !ok {
-		return fmt.Errorf("unsupported Pravega cluster version %s", requestVersion)
+		return denial("UnsupportedVersion", "unsupported Pravega cluster version %s (supported versions from %s)", requestVersion, source)
+	}
+
+	requestBookkeeperVersion := p.BookkeeperVersion()
+	normRequestBookkeeperVersion, err := util.NormalizeVersion(requestBookkeeperVersion)
+	if err != nil {
+		return denial("InvalidVersion", "request bookkeeper version is not in valid format: %v", err)
+	}
+	if _, ok := supportedVersions[normRequestBookkeeperVersion]; !ok {
+		return denial("UnsupportedVersion", "unsupported bookkeeper version %s (supported versions from %s)", requestBookkeeperVersion, source)
 	}
 
 	// Check if the request is an upgrade
@@ -117,14 +185,40 @@ func (pwh *pravegaWebhookHandler) mutatePravegaVersion(ctx context.Context, p *p
 		Namespace: p.Namespace,
 		Name:      p.Name,
 	}
-	err = pwh.client.Get(context.TODO(), nn, found)
+	err = pwh.getCluster(nn, found)
 	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("failed to obtain PravegaCluster resource: %v", err)
 	}
+	if errors.IsNotFound(err) {
+		// This is not an upgrade if "found" is empty
+		return nil
+	}
+
+	err = checkUpgradePath("", found.Spec.Version, found.Status.CurrentVersion, requestVersion, supportedVersions, source)
+	if err != nil {
+		return err
+	}
+
+	// BookKeeper can be upgraded on its own, along the same upgrade paths
+	currentBookkeeperVersion := found.Status.CurrentBookkeeperVersion
+	if currentBookkeeperVersion == "" {
+		currentBookkeeperVersion = found.Status.CurrentVersion
+	}
+	return checkUpgradePath("bookkeeper ", found.BookkeeperVersion(), currentBookkeeperVersion, requestBookkeeperVersion,
+		supportedVersions, source)
+}
+
+// checkUpgradePath checks that the requested version of a component is in the
+// upgrade paths of the version in the spec
+func checkUpgradePath(component, foundVersion, currentVersion, requestVersion string, supportedVersions util.VersionMatrix, source string) error {
+	// This is not an upgrade if the requested version is equal to the running version
+	if foundVersion == requestVersion {
+		return nil
+	}
 
-	foundVersion := found.Spec.Version
-	// This is not an upgrade if "found" is empty or the requested version is equal to the running version
-	if errors.IsNotFound(err) || foundVersion == requestVersion {
+	// Reverting an upgrade to the version the cluster is running, e.g. to
+	// roll back a failed upgrade, is not an upgrade either
+	if requestVersion == currentVersion {
 		return nil
 	}
 
@@ -132,17 +226,54 @@ func (pwh *pravegaWebhookHandler) mutatePravegaVersion(ctx context.Context, p *p
 	normFoundVersion, err := util.NormalizeVersion(foundVersion)
 	if err != nil {
 		// It should never happen
-		return fmt.Errorf("found version is not in valid format, something bad happens: %v", err)
+		return fmt.Errorf("found %sversion is not in valid format, something bad happens: %v", component, err)
+	}
+	normRequestVersion, err := util.NormalizeVersion(requestVersion)
+	if err != nil {
+		return denial("InvalidVersion", "request %sversion is not in valid format: %v", component, err)
 	}
 	upgradeList, ok := supportedVersions[normFoundVersion]
 	if !ok {
-		// It should never happen
-		return fmt.Errorf("failed to find current cluster version in the supported versions")
+		// It may happen if the version was removed from the version matrix
+		return denial("UnsupportedUpgrade", "unsupported %supgrade from version %s, it is not in the supported versions from %s",
+			component, foundVersion, source)
 	}
 	if !util.ContainsVersion(upgradeList, normRequestVersion) {
-		return fmt.Errorf("unsupported upgrade from version %s to %s", foundVersion, requestVersion)
+		return denial("UnsupportedUpgrade", "unsupported %supgrade from version %s to %s (upgrade paths from %s)",
+			component, foundVersion, requestVersion, source)
 	}
+	return nil
+}
+
+var (
+	// imageTagRegexp matches the tags accepted by Docker
+	imageTagRegexp = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
 
+	// imageDigestRegexp matches the digests accepted by Docker, e.g.
+	// "sha256:" followed by 64 hexadecimal digits
+	imageDigestRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}$`)
+)
+
+// validateImage checks the tag and digest of the image of a component. The
+// repository may also be pinned to a digest, e.g. "pravega/pravega@sha256:...",
+// in which case the tag and digest must not be set.
+func validateImage(component string, image *pravegav1alpha1.ImageSpec) error {
+	if util.IsDigestReference(image.Repository) {
+		parts := strings.SplitN(image.Repository, "@", 2)
+		if parts[0] == "" || !imageDigestRegexp.MatchString(parts[1]) {
+			return denial("InvalidImage", "invalid %s image repository %q", component, image.Repository)
+		}
+		if image.Tag != "" || image.Digest != "" {
+			return denial("InvalidImage", "%s image repository %q is pinned to a digest, its tag and digest must not be set",
+				component, image.Repository)
+		}
+	}
+	if image.Tag != "" && !imageTagRegexp.MatchString(image.Tag) {
+		return denial("InvalidImage", "invalid %s image tag %q", component, image.Tag)
+	}
+	if image.Digest != "" && !imageDigestRegexp.MatchString(image.Digest) {
+		return denial("InvalidImage", "invalid %s image digest %q", component, image.Digest)
+	}
 	return nil
 }
 
@@ -152,7 +283,7 @@ func (pwh *pravegaWebhookHandler) clusterIsAvailable(ctx context.Context, p *pra
 		Namespace: p.Namespace,
 		Name:      p.Name,
 	}
-	err := pwh.client.Get(context.TODO(), nn, found)
+	err := pwh.getCluster(nn, found)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return nil
@@ -164,7 +295,10 @@ func (pwh *pravegaWebhookHandler) clusterIsAvailable(ctx context.Context, p *pra
 	if upgrade != nil && upgrade.Status == corev1.ConditionTrue {
 		// Reject the request if the requested version is new.
 		if p.Spec.Version != found.Spec.Version && p.Spec.Version != found.Status.CurrentVersion {
-			return fmt.Errorf("failed to process the request, cluster is upgrading")
+			return denial("ClusterUpgrading", "failed to process the request, cluster is upgrading")
+		}
+		if p.BookkeeperVersion() != found.BookkeeperVersion() && p.BookkeeperVersion() != found.Status.CurrentBookkeeperVersion {
+			return denial("ClusterUpgrading", "failed to process the request, cluster is upgrading")
 		}
 	}
 
@@ -172,6 +306,13 @@ func (pwh *pravegaWebhookHandler) clusterIsAvailable(ctx context.Context, p *pra
 	return nil
 }
 
+func (pwh *pravegaWebhookHandler) getCluster(nn types.NamespacedName, p *pravegav1alpha1.PravegaCluster) error {
+	if pwh.reader != nil {
+		return pwh.reader.Get(context.TODO(), nn, p)
+	}
+	return pwh.client.Get(context.TODO(), nn, p)
+}
+
 // pravegaWebhookHandler implements inject.Client.
 var _ inject.Client = &pravegaWebhookHandler{}
 
@@ -12,16 +12,32 @@ package webhook
 
 import (
 	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pravega/pravega-operator/pkg/apis/pravega/v1alpha1"
+	"github.com/pravega/pravega-operator/pkg/metrics"
+	"github.com/pravega/pravega-operator/pkg/util"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	log "github.com/sirupsen/logrus"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
 )
 
 func TestWebhook(t *testing.T) {
@@ -127,7 +143,10 @@ var _ = Describe("Admission webhook", func() {
 
 				It("Version on .spec.Version should prevail", func() {
 					Ω(p.Spec.Version).Should(Equal("0.1.0"))
-					Ω(p.Spec.Pravega.Image.Tag).Should(Equal(""))
+				})
+
+				It("should keep the image tag", func() {
+					Ω(p.Spec.Pravega.Image.Tag).Should(Equal("0.3.2-rc3"))
 				})
 			})
 		})
@@ -209,7 +228,7 @@ var _ = Describe("Admission webhook", func() {
 					}
 					err = pwh.mutatePravegaManifest(context.TODO(), p)
 					Ω(err).ShouldNot(BeNil())
-					Ω(err.Error()).To(Equal("unsupported Pravega cluster version 99.0.0"))
+					Ω(err.Error()).To(Equal("unsupported Pravega cluster version 99.0.0 (supported versions from compiled-in table)"))
 				})
 			})
 
@@ -256,7 +275,7 @@ var _ = Describe("Admission webhook", func() {
 					}
 					err = pwh.mutatePravegaManifest(context.TODO(), p)
 					Ω(err).ShouldNot(BeNil())
-					Ω(err.Error()).To(Equal("unsupported Pravega cluster version 99.0.0-001"))
+					Ω(err.Error()).To(Equal("unsupported Pravega cluster version 99.0.0-001 (supported versions from compiled-in table)"))
 				})
 			})
 
@@ -267,10 +286,340 @@ var _ = Describe("Admission webhook", func() {
 					}
 					err = pwh.mutatePravegaManifest(context.TODO(), p)
 					Ω(err).ShouldNot(BeNil())
-					Ω(err.Error()).To(Equal("unsupported upgrade from version 0.5.0-001 to 0.4.0-001"))
+					Ω(err.Error()).To(Equal("unsupported upgrade from version 0.5.0-001 to 0.4.0-001 (upgrade paths from compiled-in table)"))
+				})
+
+				It("should count the denial by reason", func() {
+					p.Spec = v1alpha1.ClusterSpec{
+						Version: "0.4.0-001",
+					}
+					counter := metrics.WebhookAdmissions.WithLabelValues(metrics.AdmissionDenied, "UnsupportedUpgrade")
+					before := testutil.ToFloat64(counter)
+					err = pwh.mutatePravegaManifest(context.TODO(), p)
+					deny(log.WithField("cluster", Name), http.StatusBadRequest, err)
+					Ω(testutil.ToFloat64(counter)).Should(Equal(before + 1))
+				})
+			})
+
+			Context("Revert to the current version", func() {
+				BeforeEach(func() {
+					p.Spec = v1alpha1.ClusterSpec{
+						Version: "0.6.0",
+					}
+					p.Status.CurrentVersion = "0.5.0"
+					client = fake.NewFakeClient(p)
+					pwh = &pravegaWebhookHandler{client: client}
+				})
+
+				It("should pass", func() {
+					p.Spec = v1alpha1.ClusterSpec{
+						Version: "0.5.0",
+					}
+					err = pwh.mutatePravegaManifest(context.TODO(), p)
+					Ω(err).Should(BeNil())
 				})
 			})
 		})
+		Context("Bookkeeper version", func() {
+			var (
+				client client.Client
+				err    error
+			)
+
+			BeforeEach(func() {
+				p.Spec = v1alpha1.ClusterSpec{
+					Version: "0.5.0",
+				}
+				client = fake.NewFakeClient(p)
+				pwh = &pravegaWebhookHandler{client: client}
+			})
+
+			It("should allow upgrading only bookkeeper", func() {
+				p.Spec.Bookkeeper = &v1alpha1.BookkeeperSpec{Version: "0.6.0"}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).Should(BeNil())
+			})
+
+			It("should not pass if the version is not supported", func() {
+				p.Spec.Bookkeeper = &v1alpha1.BookkeeperSpec{Version: "99.0.0"}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).To(Equal("unsupported bookkeeper version 99.0.0 (supported versions from compiled-in table)"))
+			})
+
+			It("should not pass if the version is not in the upgrade path", func() {
+				p.Spec.Bookkeeper = &v1alpha1.BookkeeperSpec{Version: "0.4.0"}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).To(Equal("unsupported bookkeeper upgrade from version 0.5.0 to 0.4.0 (upgrade paths from compiled-in table)"))
+			})
+		})
+
+		Context("Image tag and digest", func() {
+			var (
+				err error
+			)
+
+			BeforeEach(func() {
+				pwh = &pravegaWebhookHandler{client: fake.NewFakeClient()}
+				p.Spec = v1alpha1.ClusterSpec{
+					Version: "0.5.0",
+				}
+			})
+
+			It("should accept a tag and a digest", func() {
+				p.Spec.Pravega = &v1alpha1.PravegaSpec{
+					Image: &v1alpha1.PravegaImageSpec{
+						ImageSpec: v1alpha1.ImageSpec{Tag: "0.5.0-cve-fix"},
+					},
+				}
+				p.Spec.Bookkeeper = &v1alpha1.BookkeeperSpec{
+					Image: &v1alpha1.BookkeeperImageSpec{
+						ImageSpec: v1alpha1.ImageSpec{Digest: "sha256:4b1c8bd86e3d3cb8c34c05c8bbd1c9a1e1bbbdc1a3d1ba1a3e6bd8b6f6e2c0d1"},
+					},
+				}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).Should(BeNil())
+			})
+
+			It("should accept a repository pinned to a digest", func() {
+				p.Spec.Pravega = &v1alpha1.PravegaSpec{
+					Image: &v1alpha1.PravegaImageSpec{
+						ImageSpec: v1alpha1.ImageSpec{
+							Repository: "registry.example.com/pravega@sha256:4b1c8bd86e3d3cb8c34c05c8bbd1c9a1e1bbbdc1a3d1ba1a3e6bd8b6f6e2c0d1",
+						},
+					},
+				}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).Should(BeNil())
+			})
+
+			It("should not pass a repository pinned to a digest with a tag", func() {
+				p.Spec.Pravega = &v1alpha1.PravegaSpec{
+					Image: &v1alpha1.PravegaImageSpec{
+						ImageSpec: v1alpha1.ImageSpec{
+							Repository: "pravega/pravega@sha256:4b1c8bd86e3d3cb8c34c05c8bbd1c9a1e1bbbdc1a3d1ba1a3e6bd8b6f6e2c0d1",
+							Tag:        "0.5.0",
+						},
+					},
+				}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).To(ContainSubstring("is pinned to a digest"))
+			})
+
+			It("should not pass an invalid digest", func() {
+				p.Spec.Bookkeeper = &v1alpha1.BookkeeperSpec{
+					Image: &v1alpha1.BookkeeperImageSpec{
+						ImageSpec: v1alpha1.ImageSpec{Digest: "latest"},
+					},
+				}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).To(Equal(`invalid bookkeeper image digest "latest"`))
+			})
+		})
+
+		Context("Version matrix file", func() {
+			var (
+				client client.Client
+				dir    string
+				file   string
+				err    error
+			)
+
+			writeMatrix := func(data string) {
+				Ω(ioutil.WriteFile(file, []byte(data), 0644)).Should(Succeed())
+				// make sure the change is detected on file systems with a
+				// coarse modification time
+				modTime := time.Now().Add(time.Duration(len(data)) * time.Second)
+				Ω(os.Chtimes(file, modTime, modTime)).Should(Succeed())
+			}
+
+			BeforeEach(func() {
+				dir, err = ioutil.TempDir("", "version-matrix")
+				Ω(err).Should(BeNil())
+				file = filepath.Join(dir, "matrix.yaml")
+				writeMatrix("0.5.0: [0.7.0]\n0.7.0: []\n")
+				Ω(util.LoadVersionMatrix(file)).Should(Succeed())
+
+				p.Spec = v1alpha1.ClusterSpec{
+					Version: "0.5.0",
+				}
+				client = fake.NewFakeClient(p)
+				pwh = &pravegaWebhookHandler{client: client}
+			})
+
+			AfterEach(func() {
+				util.ResetVersionMatrix()
+				os.RemoveAll(dir)
+			})
+
+			It("should allow the upgrade paths of the file", func() {
+				p.Spec = v1alpha1.ClusterSpec{
+					Version: "0.7.0",
+				}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).Should(BeNil())
+			})
+
+			It("should name the file in the denials", func() {
+				p.Spec = v1alpha1.ClusterSpec{
+					Version: "0.6.0",
+				}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).To(Equal("unsupported Pravega cluster version 0.6.0 (supported versions from " + file + ")"))
+			})
+
+			It("should reload the file when it changes", func() {
+				writeMatrix("0.5.0: [0.6.0]\n0.6.0: []\n0.7.0: []\n")
+				Ω(util.LoadVersionMatrix(file)).Should(Succeed())
+				p.Spec = v1alpha1.ClusterSpec{
+					Version: "0.7.0",
+				}
+				err = pwh.mutatePravegaManifest(context.TODO(), p)
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).To(Equal("unsupported upgrade from version 0.5.0 to 0.7.0 (upgrade paths from " + file + ")"))
+			})
+
+			It("should keep the previous matrix if the file is invalid", func() {
+				writeMatrix("0.5.0: invalid: [")
+				Ω(util.LoadVersionMatrix(file)).ShouldNot(Succeed())
+				matrix, source := util.SupportedVersions()
+				Ω(source).Should(Equal(file))
+				Ω(matrix).Should(HaveKeyWithValue("0.5.0", []string{"0.5.0", "0.7.0"}))
+			})
+
+			It("should go back to the compiled-in table", func() {
+				util.ResetVersionMatrix()
+				_, source := util.SupportedVersions()
+				Ω(source).Should(Equal(util.VersionMatrixCompiledIn))
+			})
+		})
+
+		Context("Spec validation", func() {
+			BeforeEach(func() {
+				p.Spec = v1alpha1.ClusterSpec{
+					Version: "0.5.0",
+					Pravega: &v1alpha1.PravegaSpec{
+						Tier2: &v1alpha1.Tier2Spec{
+							FileSystem: &v1alpha1.FileSystemSpec{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "pravega-tier2"},
+							},
+						},
+					},
+				}
+			})
+
+			It("should pass a valid spec", func() {
+				Ω(validateCluster(p)).Should(BeNil())
+			})
+
+			It("should not pass an invalid spec", func() {
+				p.Spec.Pravega.Tier2.Ecs = &v1alpha1.ECSSpec{Uri: "https://ecs:9021", Bucket: "pravega", Credentials: "ecs"}
+				err := validateCluster(p)
+				Ω(err).ShouldNot(BeNil())
+				Ω(err.Error()).To(Equal(`PravegaCluster.pravega.pravega.io "example" is invalid: ` +
+					`spec.pravega.tier2.ecs: Forbidden: may not specify more than 1 tier 2 storage type`))
+			})
+
+			It("should count the denial by reason", func() {
+				p.Spec.Pravega.SegmentStoreReplicas = -1
+				counter := metrics.WebhookAdmissions.WithLabelValues(metrics.AdmissionDenied, "InvalidSpec")
+				before := testutil.ToFloat64(counter)
+				deny(log.WithField("cluster", Name), http.StatusUnprocessableEntity, validateCluster(p))
+				Ω(testutil.ToFloat64(counter)).Should(Equal(before + 1))
+			})
+		})
+
+		Context("Immutable fields", func() {
+			var (
+				vwh *validatingWebhookHandler
+				old *v1alpha1.PravegaCluster
+			)
+
+			request := func(operation admissionv1beta1.Operation) admissiontypes.Request {
+				raw := func(p *v1alpha1.PravegaCluster) runtime.RawExtension {
+					p.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "PravegaCluster"}
+					data, err := json.Marshal(p)
+					Ω(err).Should(BeNil())
+					return runtime.RawExtension{Raw: data}
+				}
+				return admissiontypes.Request{AdmissionRequest: &admissionv1beta1.AdmissionRequest{
+					Name:      Name,
+					Namespace: Namespace,
+					Operation: operation,
+					Object:    raw(p),
+					OldObject: raw(old),
+				}}
+			}
+
+			BeforeEach(func() {
+				decoder, err := admission.NewDecoder(s)
+				Ω(err).Should(BeNil())
+				vwh = &validatingWebhookHandler{decoder: decoder}
+				p.Spec = v1alpha1.ClusterSpec{Version: "0.5.0"}
+				p.WithDefaults()
+				old = p.DeepCopy()
+				p.Spec.ZookeeperUri = "zookeeper:2181"
+			})
+
+			It("should deny the update of an immutable field", func() {
+				res := vwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+				Ω(res.Response.Allowed).Should(BeFalse())
+				Ω(res.Response.Result.Code).Should(Equal(int32(http.StatusUnprocessableEntity)))
+				Ω(res.Response.Result.Message).Should(ContainSubstring("spec.zookeeperUri: Forbidden: field is immutable"))
+			})
+
+			It("should allow the creation", func() {
+				res := vwh.Handle(context.TODO(), request(admissionv1beta1.Create))
+				Ω(res.Response.Allowed).Should(BeTrue())
+			})
+
+			It("should allow a forced update", func() {
+				p.Annotations = map[string]string{v1alpha1.AllowImmutableChangesAnnotation: "true"}
+				res := vwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+				Ω(res.Response.Allowed).Should(BeTrue())
+			})
+
+			Context("Cluster with a spec that is no longer valid", func() {
+				BeforeEach(func() {
+					old.Spec.Pravega.SegmentStoreReplicas = -1
+					old.Finalizers = []string{util.ZkFinalizer}
+					p = old.DeepCopy()
+				})
+
+				It("should allow the update of the metadata", func() {
+					p.Annotations = map[string]string{v1alpha1.PausedAnnotation: "true"}
+					res := vwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+					Ω(res.Response.Allowed).Should(BeTrue())
+				})
+
+				It("should deny the update of the spec", func() {
+					p.Spec.Pravega.ControllerReplicas = 2
+					res := vwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+					Ω(res.Response.Allowed).Should(BeFalse())
+					Ω(res.Response.Result.Message).Should(ContainSubstring("spec.pravega.segmentStoreReplicas"))
+				})
+
+				It("should allow the removal of the finalizer once deleted", func() {
+					now := metav1.Now()
+					old.DeletionTimestamp = &now
+					p = old.DeepCopy()
+					p.Finalizers = nil
+					res := vwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+					Ω(res.Response.Allowed).Should(BeTrue())
+
+					pwh := &pravegaWebhookHandler{client: fake.NewFakeClient(), decoder: vwh.decoder}
+					p.Spec.Version = "0.1.0"
+					res = pwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+					Ω(res.Response.Allowed).Should(BeTrue())
+					Ω(res.Patches).Should(BeEmpty())
+				})
+			})
+		})
+
 		Context("Reject request when upgrading", func() {
 			var (
 				client client.Client
@@ -298,4 +647,99 @@ var _ = Describe("Admission webhook", func() {
 
 		})
 	})
+
+	Context("Defaults", func() {
+		var (
+			p   *v1alpha1.PravegaCluster
+			pwh *pravegaWebhookHandler
+		)
+
+		request := func(operation admissionv1beta1.Operation) admissiontypes.Request {
+			p.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "PravegaCluster"}
+			data, err := json.Marshal(p)
+			Ω(err).Should(BeNil())
+			return admissiontypes.Request{AdmissionRequest: &admissionv1beta1.AdmissionRequest{
+				Name:      Name,
+				Namespace: Namespace,
+				Operation: operation,
+				Object:    runtime.RawExtension{Raw: data},
+			}}
+		}
+
+		patched := func(res admissiontypes.Response) map[string]interface{} {
+			patches := map[string]interface{}{}
+			for _, patch := range res.Patches {
+				patches[patch.Path] = patch.Value
+			}
+			return patches
+		}
+
+		BeforeEach(func() {
+			p = &v1alpha1.PravegaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      Name,
+					Namespace: Namespace,
+				},
+				Spec: v1alpha1.ClusterSpec{
+					Version: "0.5.0",
+					Pravega: &v1alpha1.PravegaSpec{
+						ControllerReplicas: 2,
+					},
+				},
+			}
+			s.AddKnownTypes(v1alpha1.SchemeGroupVersion, p)
+			decoder, err := admission.NewDecoder(s)
+			Ω(err).Should(BeNil())
+			pwh = &pravegaWebhookHandler{client: fake.NewFakeClient(), decoder: decoder}
+		})
+
+		It("should set the defaults on creation", func() {
+			res := pwh.Handle(context.TODO(), request(admissionv1beta1.Create))
+			Ω(res.Response.Allowed).Should(BeTrue())
+			patches := patched(res)
+			Ω(patches).Should(HaveKeyWithValue("/spec/zookeeperUri", v1alpha1.DefaultZookeeperUri))
+			Ω(patches).Should(HaveKey("/spec/bookkeeper"))
+			Ω(patches).Should(HaveKey("/spec/pravega/segmentStoreReplicas"))
+			Ω(patches).ShouldNot(HaveKey("/spec/pravega/controllerReplicas"))
+		})
+
+		It("should list the defaulted fields in an annotation", func() {
+			res := pwh.Handle(context.TODO(), request(admissionv1beta1.Create))
+			annotations, ok := patched(res)["/metadata/annotations"].(map[string]interface{})
+			Ω(ok).Should(BeTrue())
+			fields := strings.Split(annotations[v1alpha1.DefaultedFieldsAnnotation].(string), ",")
+			Ω(fields).Should(ContainElement("spec.zookeeperUri"))
+			Ω(fields).Should(ContainElement("spec.bookkeeper"))
+			Ω(fields).Should(ContainElement("spec.pravega.segmentStoreReplicas"))
+			Ω(fields).ShouldNot(ContainElement("spec.pravega.controllerReplicas"))
+			Ω(fields).ShouldNot(ContainElement("spec.version"))
+		})
+
+		It("should only list the fields defaulted by the last admission", func() {
+			p.WithDefaults()
+			p.Annotations = map[string]string{v1alpha1.DefaultedFieldsAnnotation: "spec.bookkeeper,spec.zookeeperUri"}
+			p.Spec.Pravega.Tier2 = nil
+			res := pwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+			Ω(res.Response.Allowed).Should(BeTrue())
+			Ω(patched(res)).Should(HaveKeyWithValue("/metadata/annotations/"+strings.Replace(v1alpha1.DefaultedFieldsAnnotation, "/", "~1", -1),
+				"spec.pravega.tier2"))
+		})
+
+		It("should remove the annotation once all the fields are set", func() {
+			p.WithDefaults()
+			p.Annotations = map[string]string{v1alpha1.DefaultedFieldsAnnotation: "spec.bookkeeper,spec.zookeeperUri"}
+			res := pwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+			Ω(res.Response.Allowed).Should(BeTrue())
+			Ω(res.Patches).Should(HaveLen(1))
+			Ω(res.Patches[0].Operation).Should(Equal("remove"))
+			Ω(res.Patches[0].Path).Should(Equal("/metadata/annotations"))
+		})
+
+		It("should not patch clusters with defaults", func() {
+			p.WithDefaults()
+			res := pwh.Handle(context.TODO(), request(admissionv1beta1.Update))
+			Ω(res.Response.Allowed).Should(BeTrue())
+			Ω(res.Patches).Should(BeEmpty())
+		})
+	})
 })